@@ -13,8 +13,15 @@ const (
 	ParamQuery       = "q"
 	ParamID          = "id"
 	ParamRemote      = "remote"
+	ParamIndex       = "index"
+	ParamDryRun      = "dryRun"
+	ParamSort        = "sort"
+	ParamFacets      = "facets"
+	ParamMinSize     = "minSize"
+	ParamVerify      = "verify"
 
 	// Headers
-	HeaderContentType = "Content-Type"
-	MimeJSON          = "application/json"
+	HeaderContentType   = "Content-Type"
+	HeaderSharePassword = "X-Share-Password"
+	MimeJSON            = "application/json"
 )
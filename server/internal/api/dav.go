@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"gravity/internal/engine"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DAVPrefix is where the WebDAV server is mounted
+const DAVPrefix = "/dav"
+
+// davMethods are the WebDAV verbs chi has to route besides the standard ones
+var davMethods = []string{"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK"}
+
+// NewDAVHandler serves the VFS over WebDAV under DAVPrefix, so all remotes can
+// be mounted in Finder, Explorer, Kodi or rclone through one endpoint. It
+// must be created before it is mounted.
+func NewDAVHandler(s engine.WebDAVServer) http.Handler {
+	for _, m := range davMethods {
+		chi.RegisterMethod(m)
+	}
+	return s.WebDAVHandler(DAVPrefix)
+}
@@ -14,10 +14,11 @@ import (
 
 type DownloadHandler struct {
 	service *service.DownloadService
+	uploads *service.UploadService
 }
 
-func NewDownloadHandler(s *service.DownloadService) *DownloadHandler {
-	return &DownloadHandler{service: s}
+func NewDownloadHandler(s *service.DownloadService, us *service.UploadService) *DownloadHandler {
+	return &DownloadHandler{service: s, uploads: us}
 }
 
 func (h *DownloadHandler) Routes() chi.Router {
@@ -25,12 +26,14 @@ func (h *DownloadHandler) Routes() chi.Router {
 	r.Get("/", h.List)
 	r.Post("/", h.Create)
 	r.Post("/batch", h.Batch)
+	r.Post("/preview-path", h.PreviewPath)
 	r.Get("/{id}", h.Get)
 	r.Delete("/{id}", h.Delete)
 	r.Patch("/{id}", h.Update)
 	r.Post("/{id}/pause", h.Pause)
 	r.Post("/{id}/resume", h.Resume)
 	r.Post("/{id}/retry", h.Retry)
+	r.Post("/{id}/uploads/{index}/retry", h.RetryUpload)
 	r.Patch("/{id}/priority", h.UpdatePriority)
 	return r
 }
@@ -119,6 +122,39 @@ func (h *DownloadHandler) List(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// PreviewPath godoc
+// @Summary Preview path template
+// @Description Show how a download directory or destination template such as "gdrive:/Media/{category}/{year}" expands for an existing or sample download
+// @Tags downloads
+// @Accept json
+// @Produce json
+// @Param request body PreviewPathRequest true "Template and sample download"
+// @Success 200 {object} PathPreviewResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /downloads/preview-path [post]
+func (h *DownloadHandler) PreviewPath(w http.ResponseWriter, r *http.Request) {
+	var req PreviewPathRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	sample := &model.Download{
+		ID:         req.DownloadID,
+		URL:        req.URL,
+		Filename:   req.Filename,
+		Provider:   req.Provider,
+		MagnetHash: req.Hash,
+	}
+	preview, err := h.service.PreviewPath(r.Context(), req.Template, sample)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	sendJSON(w, PathPreviewResponse{Data: *preview})
+}
+
 // Update godoc
 // @Summary Update download
 // @Description Update download properties
@@ -138,7 +174,7 @@ func (h *DownloadHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Update(r.Context(), id, req.Filename, req.Destination, req.Priority, req.MaxRetries); err != nil {
+	if err := h.service.Update(r.Context(), id, req.Filename, req.Destination, req.Destinations, req.Priority, req.MaxRetries); err != nil {
 		sendAppError(w, err)
 		return
 	}
@@ -170,6 +206,7 @@ func (h *DownloadHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Filename:      req.Filename,
 		Dir:           req.Dir,
 		Destination:   req.Destination,
+		Destinations:  req.Destinations,
 		Split:         req.Split,
 		RemoveLocal:   req.RemoveLocal,
 		Headers:       req.Headers,
@@ -181,6 +218,9 @@ func (h *DownloadHandler) Create(w http.ResponseWriter, r *http.Request) {
 		// Overrides
 		MaxDownloadSpeed: req.MaxDownloadSpeed,
 		ConnectTimeout:   req.ConnectTimeout,
+
+		StreamToDestination: req.StreamToDestination,
+		UploadQuorum:        req.UploadQuorum,
 	}
 
 	if req.Priority != nil {
@@ -299,3 +339,28 @@ func (h *DownloadHandler) Retry(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// RetryUpload godoc
+// @Summary Retry upload to one destination
+// @Description Re-run the failed upload of a download to a single destination
+// @Tags downloads
+// @Param id path string true "Download ID"
+// @Param index path int true "Index of the destination in uploads"
+// @Success 200 "OK"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /downloads/{id}/uploads/{index}/retry [post]
+func (h *DownloadHandler) RetryUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, ParamID)
+	index, err := strconv.Atoi(chi.URLParam(r, ParamIndex))
+	if err != nil {
+		sendError(w, "invalid upload index", http.StatusBadRequest)
+		return
+	}
+	if err := h.uploads.RetryUpload(r.Context(), id, index); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
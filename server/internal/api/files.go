@@ -1,10 +1,18 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"path"
 	"path/filepath"
+	"strconv"
 
 	"gravity/internal/engine"
+	"gravity/internal/model"
+	"gravity/internal/service"
 	"gravity/internal/utils"
 
 	"github.com/go-chi/chi/v5"
@@ -13,12 +21,20 @@ import (
 type FileHandler struct {
 	storage engine.StorageEngine
 	upload  engine.UploadEngine
+	files   *service.FileUploadService
+	archive *service.ArchiveService
+	trash   *service.TrashService
+	dups    *service.DuplicateService
 }
 
-func NewFileHandler(s engine.StorageEngine, u engine.UploadEngine) *FileHandler {
+func NewFileHandler(s engine.StorageEngine, u engine.UploadEngine, f *service.FileUploadService, a *service.ArchiveService, t *service.TrashService, d *service.DuplicateService) *FileHandler {
 	return &FileHandler{
 		storage: s,
 		upload:  u,
+		files:   f,
+		archive: a,
+		trash:   t,
+		dups:    d,
 	}
 }
 
@@ -28,8 +44,15 @@ func (h *FileHandler) Routes() chi.Router {
 	r.Get("/cat", h.Cat)
 	r.Post("/mkdir", h.Mkdir)
 	r.Post("/delete", h.Delete)
+	r.Post("/duplicates/resolve", h.ResolveDuplicates)
 	r.Post("/operate", h.Operate)
 	r.Post("/restart", h.Restart)
+	r.Post("/archive", h.Archive)
+	r.Post("/upload", h.Upload)
+	r.Post("/upload/sessions", h.CreateUploadSession)
+	r.Get("/upload/sessions/{id}", h.GetUploadSession)
+	r.Put("/upload/sessions/{id}", h.WriteUploadChunk)
+	r.Delete("/upload/sessions/{id}", h.AbortUploadSession)
 	return r
 }
 
@@ -147,7 +170,7 @@ func (h *FileHandler) Mkdir(w http.ResponseWriter, r *http.Request) {
 
 // Delete godoc
 // @Summary Delete file/directory
-// @Description Delete a file or directory
+// @Description Move a file or directory to the trash of its remote, or delete it if the trash is off or bypassed for the remote
 // @Tags files
 // @Accept json
 // @Param request body DeleteFileRequest true "Path to delete"
@@ -167,13 +190,50 @@ func (h *FileHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.storage.Delete(r.Context(), cleanPath); err != nil {
+	if err := h.trash.Delete(r.Context(), cleanPath); err != nil {
 		sendAppError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ResolveDuplicates godoc
+// @Summary Remove duplicate files
+// @Description Keep one file of each duplicate group found by /search/duplicates and delete the others as /files/delete does. A file is only removed if the search index still has it in the group of the file to keep, by name, size and, for a verified group, hash, and if its size still matches on the backend. Each removal reports its own error.
+// @Tags files
+// @Accept json
+// @Produce json
+// @Param request body ResolveDuplicatesRequest true "Files to keep and remove"
+// @Success 200 {object} DuplicateRemovalListResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /files/duplicates/resolve [post]
+func (h *FileHandler) ResolveDuplicates(w http.ResponseWriter, r *http.Request) {
+	var req ResolveDuplicatesRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	for i := range req.Groups {
+		g := &req.Groups[i]
+		cleanKeep, err := utils.SanitizePath(g.Keep, "/")
+		if err != nil {
+			sendError(w, "invalid keep path: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		g.Keep = cleanKeep
+		for j, p := range g.Remove {
+			cleanPath, err := utils.SanitizePath(p, "/")
+			if err != nil {
+				sendError(w, "invalid remove path: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			g.Remove[j] = cleanPath
+		}
+	}
+
+	sendJSON(w, DuplicateRemovalListResponse{Data: h.dups.Resolve(r.Context(), req.Groups, h.trash.Delete)})
+}
+
 func (h *FileHandler) Operate(w http.ResponseWriter, r *http.Request) {
 	var req FileOperationRequest
 	if !decodeAndValidate(w, r, &req) {
@@ -217,3 +277,242 @@ func (h *FileHandler) Operate(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// Archive godoc
+// @Summary Download as archive
+// @Description Stream files and folders as one zip (stored, not compressed) or tar, built on the fly. Folders are included recursively and modification times are kept. Selections larger than the archive limit (the archiveMaxSize setting, or maxSize if smaller) are rejected before anything is sent.
+// @Tags files
+// @Accept json
+// @Produce application/zip,application/x-tar
+// @Param request body ArchiveRequest true "Paths to include"
+// @Success 200 {file} file "Archive"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /files/archive [post]
+func (h *FileHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	var req ArchiveRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	paths := make([]string, len(req.Paths))
+	for i, p := range req.Paths {
+		cleanPath, err := utils.SanitizePath(p, "/")
+		if err != nil {
+			sendError(w, "invalid path: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		paths[i] = cleanPath
+	}
+
+	a, err := h.archive.Prepare(r.Context(), paths, req.MaxSize)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	format := req.Format
+	if format == "" {
+		format = "zip"
+	}
+	name := req.Name
+	if name == "" {
+		name = "archive"
+		if len(paths) == 1 && path.Base(paths[0]) != "/" {
+			name = path.Base(paths[0])
+		}
+	}
+
+	contentType := "application/zip"
+	if format == "tar" {
+		contentType = "application/x-tar"
+	}
+	w.Header().Set(HeaderContentType, contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+
+	if err := h.archive.Write(r.Context(), w, a, format); err != nil {
+		// Headers are sent; drop the connection so the client does not keep
+		// a truncated archive that looks complete
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// Upload godoc
+// @Summary Upload files
+// @Description Stream the files of a multipart form into the directory at path. Each part is written as it arrives; use upload sessions for large files that need to resume. Progress is published as file.progress events.
+// @Tags files
+// @Accept multipart/form-data
+// @Produce json
+// @Param path query string true "Virtual directory to upload into"
+// @Param conflict query string false "What to do when a file exists (default fail)" Enums(fail, overwrite, rename)
+// @Param file formData file true "Files to upload"
+// @Success 201 {object} FileInfoListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /files/upload [post]
+func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	dir, err := utils.SanitizePath(r.URL.Query().Get("path"), "/")
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	conflict, ok := parseConflict(w, r.URL.Query().Get("conflict"))
+	if !ok {
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		sendError(w, "expected a multipart form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var files FileInfoList
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			sendError(w, "invalid multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		name := path.Base(filepath.ToSlash(part.FileName()))
+		if part.FileName() == "" || name == "." || name == "/" || name == ".." {
+			part.Close()
+			continue
+		}
+
+		info, err := h.files.Put(r.Context(), path.Join(dir, name), part, -1, conflict)
+		part.Close()
+		if err != nil {
+			sendAppError(w, err)
+			return
+		}
+		files = append(files, *info)
+	}
+
+	if len(files) == 0 {
+		sendError(w, "no files in request", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(FileInfoListResponse{Data: files})
+}
+
+// CreateUploadSession godoc
+// @Summary Start resumable upload
+// @Description Start a chunked upload of one file. Send its data in order with PUT /files/upload/sessions/{id}; the file is written to path once all of it has arrived.
+// @Tags files
+// @Accept json
+// @Produce json
+// @Param request body CreateUploadSessionRequest true "Target file"
+// @Success 201 {object} FileUploadSessionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /files/upload/sessions [post]
+func (h *FileHandler) CreateUploadSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateUploadSessionRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	cleanPath, err := utils.SanitizePath(req.Path, "/")
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	conflict, ok := parseConflict(w, string(req.Conflict))
+	if !ok {
+		return
+	}
+
+	sess, err := h.files.CreateSession(r.Context(), cleanPath, req.Size, conflict)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(FileUploadSessionResponse{Data: sess})
+}
+
+// GetUploadSession godoc
+// @Summary Get resumable upload
+// @Description Get the offset to resume a chunked upload from
+// @Tags files
+// @Produce json
+// @Param id path string true "Upload session ID"
+// @Success 200 {object} FileUploadSessionResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /files/upload/sessions/{id} [get]
+func (h *FileHandler) GetUploadSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := h.files.GetSession(chi.URLParam(r, ParamID))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, FileUploadSessionResponse{Data: sess})
+}
+
+// WriteUploadChunk godoc
+// @Summary Upload chunk
+// @Description Append the request body to a chunked upload. offset must equal the session's offset; after a dropped connection, get the session to find where to resume. The chunk that completes the file writes it to its path.
+// @Tags files
+// @Accept application/octet-stream
+// @Produce json
+// @Param id path string true "Upload session ID"
+// @Param offset query int true "Position of this chunk in the file"
+// @Success 200 {object} FileUploadSessionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /files/upload/sessions/{id} [put]
+func (h *FileHandler) WriteUploadChunk(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get(ParamOffset), 10, 64)
+	if err != nil {
+		sendError(w, "offset is required", http.StatusBadRequest)
+		return
+	}
+
+	sess, err := h.files.WriteChunk(r.Context(), chi.URLParam(r, ParamID), offset, r.Body)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, FileUploadSessionResponse{Data: sess})
+}
+
+// AbortUploadSession godoc
+// @Summary Abort resumable upload
+// @Description Discard a chunked upload and the data received for it
+// @Tags files
+// @Param id path string true "Upload session ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /files/upload/sessions/{id} [delete]
+func (h *FileHandler) AbortUploadSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.files.AbortSession(chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseConflict reads a conflict policy, defaulting to fail
+func parseConflict(w http.ResponseWriter, v string) (model.ConflictPolicy, bool) {
+	if v == "" {
+		return model.ConflictFail, true
+	}
+	p := model.ConflictPolicy(v)
+	if !p.Valid() {
+		sendError(w, "conflict must be fail, overwrite or rename", http.StatusBadRequest)
+		return "", false
+	}
+	return p, true
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"gravity/internal/model"
+	"gravity/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxCookieFileSize = 1 << 20
+
+type SiteProfileHandler struct {
+	service *service.SiteProfileService
+}
+
+func NewSiteProfileHandler(s *service.SiteProfileService) *SiteProfileHandler {
+	return &SiteProfileHandler{service: s}
+}
+
+func (h *SiteProfileHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.List)
+	r.Post("/", h.Create)
+	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+	r.Post("/{id}/cookies", h.ImportCookies)
+	r.Delete("/{id}/cookies", h.ClearCookies)
+	return r
+}
+
+// List godoc
+// @Summary List site profiles
+// @Description Get all site profiles. Secrets are masked.
+// @Tags profiles
+// @Produce json
+// @Success 200 {object} SiteProfileListResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profiles [get]
+func (h *SiteProfileHandler) List(w http.ResponseWriter, r *http.Request) {
+	profiles, err := h.service.List(r.Context())
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	out := make(SiteProfileList, len(profiles))
+	for i, p := range profiles {
+		out[i] = p.Redacted()
+	}
+	sendJSON(w, SiteProfileListResponse{Data: out})
+}
+
+// Create godoc
+// @Summary Create site profile
+// @Description Add credentials, cookies and headers for a set of hosts
+// @Tags profiles
+// @Accept json
+// @Produce json
+// @Param request body model.SiteProfile true "Site profile"
+// @Success 201 {object} SiteProfileResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /profiles [post]
+func (h *SiteProfileHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req model.SiteProfile
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	p, err := h.service.Create(r.Context(), &req)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(SiteProfileResponse{Data: p.Redacted()})
+}
+
+// Get godoc
+// @Summary Get site profile
+// @Tags profiles
+// @Produce json
+// @Param id path string true "Profile ID"
+// @Success 200 {object} SiteProfileResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /profiles/{id} [get]
+func (h *SiteProfileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	p, err := h.service.Get(r.Context(), chi.URLParam(r, ParamID))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SiteProfileResponse{Data: p.Redacted()})
+}
+
+// Update godoc
+// @Summary Update site profile
+// @Description Replace a site profile. Secrets sent empty or as "********" keep their stored values.
+// @Tags profiles
+// @Accept json
+// @Produce json
+// @Param id path string true "Profile ID"
+// @Param request body model.SiteProfile true "Site profile"
+// @Success 200 {object} SiteProfileResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /profiles/{id} [put]
+func (h *SiteProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req model.SiteProfile
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	p, err := h.service.Update(r.Context(), chi.URLParam(r, ParamID), &req)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SiteProfileResponse{Data: p.Redacted()})
+}
+
+// Delete godoc
+// @Summary Delete site profile
+// @Tags profiles
+// @Param id path string true "Profile ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Router /profiles/{id} [delete]
+func (h *SiteProfileHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportCookies godoc
+// @Summary Import cookies
+// @Description Merge a Netscape cookies.txt export (as written by browser extensions, curl or yt-dlp) into the profile
+// @Tags profiles
+// @Accept plain
+// @Produce json
+// @Param id path string true "Profile ID"
+// @Param request body string true "cookies.txt contents"
+// @Success 200 {object} SiteProfileResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /profiles/{id}/cookies [post]
+func (h *SiteProfileHandler) ImportCookies(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCookieFileSize+1))
+	if err != nil {
+		sendError(w, "failed to read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxCookieFileSize {
+		sendError(w, "cookies file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	p, err := h.service.ImportCookies(r.Context(), chi.URLParam(r, ParamID), string(body))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SiteProfileResponse{Data: p.Redacted()})
+}
+
+// ClearCookies godoc
+// @Summary Clear cookies
+// @Description Remove all cookies from the profile
+// @Tags profiles
+// @Produce json
+// @Param id path string true "Profile ID"
+// @Success 200 {object} SiteProfileResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /profiles/{id}/cookies [delete]
+func (h *SiteProfileHandler) ClearCookies(w http.ResponseWriter, r *http.Request) {
+	p, err := h.service.ClearCookies(r.Context(), chi.URLParam(r, ParamID))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SiteProfileResponse{Data: p.Redacted()})
+}
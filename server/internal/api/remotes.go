@@ -4,16 +4,18 @@ import (
 	"net/http"
 
 	"gravity/internal/engine"
+	"gravity/internal/service"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type RemoteHandler struct {
 	engine engine.UploadEngine
+	quota  *service.QuotaService
 }
 
-func NewRemoteHandler(e engine.UploadEngine) *RemoteHandler {
-	return &RemoteHandler{engine: e}
+func NewRemoteHandler(e engine.UploadEngine, quota *service.QuotaService) *RemoteHandler {
+	return &RemoteHandler{engine: e, quota: quota}
 }
 
 func (h *RemoteHandler) Routes() chi.Router {
@@ -27,7 +29,7 @@ func (h *RemoteHandler) Routes() chi.Router {
 
 // List godoc
 // @Summary List cloud remotes
-// @Description Get a list of all configured rclone remotes
+// @Description Get a list of all configured rclone remotes. Remotes whose backend reports a quota include their last known total, used, free and trashed space and object count, refreshed in the background every few minutes.
 // @Tags remotes
 // @Produce json
 // @Success 200 {object} RemoteListResponse
@@ -39,6 +41,9 @@ func (h *RemoteHandler) List(w http.ResponseWriter, r *http.Request) {
 		sendAppError(w, err)
 		return
 	}
+	for i := range remotes {
+		remotes[i].About = h.quota.Cached(remotes[i].Name)
+	}
 	sendJSON(w, RemoteListResponse{Data: remotes})
 }
 
@@ -54,6 +54,30 @@ func (rt *Router) Auth(next http.Handler) http.Handler {
 	})
 }
 
+// BasicAuth is Auth for clients that only speak basic auth, such as WebDAV
+// mounts. The API key is taken as the password with any user name.
+func (rt *Router) BasicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rt.apiKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			_, key, _ = r.BasicAuth()
+		}
+
+		if key != rt.apiKey {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Gravity", charset="UTF-8"`)
+			sendError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (rt *Router) Handler() http.Handler {
 	return rt.chi
 }
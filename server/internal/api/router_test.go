@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	serve := func(apiKey string, prepare func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PROPFIND", "/dav/", nil)
+		if prepare != nil {
+			prepare(r)
+		}
+		w := httptest.NewRecorder()
+		NewRouter(apiKey).BasicAuth(ok).ServeHTTP(w, r)
+		return w
+	}
+
+	if w := serve("", nil); w.Code != http.StatusNoContent {
+		t.Errorf("no key configured: %d", w.Code)
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(r *http.Request)
+		want    int
+	}{
+		{"no credentials", nil, http.StatusUnauthorized},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("me", "nope") }, http.StatusUnauthorized},
+		{"wrong header", func(r *http.Request) { r.Header.Set("X-API-Key", "nope") }, http.StatusUnauthorized},
+		{"password", func(r *http.Request) { r.SetBasicAuth("anyone", "secret") }, http.StatusNoContent},
+		{"header", func(r *http.Request) { r.Header.Set("X-API-Key", "secret") }, http.StatusNoContent},
+		// The header wins over a wrong password
+		{"header and password", func(r *http.Request) {
+			r.SetBasicAuth("me", "nope")
+			r.Header.Set("X-API-Key", "secret")
+		}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		w := serve("secret", tt.prepare)
+		if w.Code != tt.want {
+			t.Errorf("%s: %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: no basic auth challenge", tt.name)
+		}
+	}
+}
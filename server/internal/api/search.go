@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"gravity/internal/model"
 	"gravity/internal/service"
@@ -11,13 +13,18 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+// Files below this size are left out of duplicate results unless asked for
+const defaultDuplicateMinSize = 1 << 20
+
 type SearchHandler struct {
-	service *service.SearchService
-	appCtx  context.Context
+	service    *service.SearchService
+	duplicates *service.DuplicateService
+	saved      *service.SavedSearchService
+	appCtx     context.Context
 }
 
-func NewSearchHandler(ctx context.Context, s *service.SearchService) *SearchHandler {
-	return &SearchHandler{service: s, appCtx: ctx}
+func NewSearchHandler(ctx context.Context, s *service.SearchService, duplicates *service.DuplicateService, saved *service.SavedSearchService) *SearchHandler {
+	return &SearchHandler{service: s, duplicates: duplicates, saved: saved, appCtx: ctx}
 }
 
 func (h *SearchHandler) Routes() chi.Router {
@@ -27,6 +34,12 @@ func (h *SearchHandler) Routes() chi.Router {
 	r.Post("/config", h.BatchUpdateConfig)
 	r.Post("/config/{remote}", h.UpdateConfig)
 	r.Post("/index/{remote}", h.IndexRemote)
+	r.Get("/duplicates", h.Duplicates)
+	r.Get("/saved", h.ListSaved)
+	r.Post("/saved", h.CreateSaved)
+	r.Get("/saved/{id}", h.GetSaved)
+	r.Put("/saved/{id}", h.UpdateSaved)
+	r.Delete("/saved/{id}", h.DeleteSaved)
 	return r
 }
 
@@ -66,13 +79,15 @@ func (h *SearchHandler) BatchUpdateConfig(w http.ResponseWriter, r *http.Request
 
 // Search godoc
 // @Summary Search indexed files
-// @Description Global search across all indexed cloud remotes
+// @Description Global search across indexed cloud remotes, the local download dir and the download history; each result has a source of remote, local or download. Names are matched on words split at dots, underscores and brackets. The query mixes words, "quoted phrases" and -excluded words with filters: source:local, remote:gdrive, ext:mkv,mp4, size:>1G or size:1G..4G, after:2024-01-01 or after:7d, before:, path:/gdrive/TV, is:dir or is:file, sort:size-desc and fuzzy:on. When nothing matches exactly, typo tolerant matches are returned with fuzzy set.
 // @Tags search
 // @Produce json
-// @Param query query string true "Search query string"
+// @Param q query string true "Search query"
+// @Param sort query string false "relevance, name, size, modified or path, optionally with -asc or -desc"
+// @Param facets query bool false "Count matches by source, remote and extension"
 // @Param limit query int false "Max number of results"
 // @Param offset query int false "Offset for pagination"
-// @Success 200 {object} IndexedFileListResponse
+// @Success 200 {object} SearchResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /search [get]
@@ -82,10 +97,22 @@ func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
 		q = r.URL.Query().Get("query")
 	}
 
-	if q == "" {
+	query, err := model.ParseSearchQuery(q, time.Now())
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	if sort := r.URL.Query().Get(ParamSort); sort != "" {
+		if err := query.SetSort(sort); err != nil {
+			sendAppError(w, err)
+			return
+		}
+	}
+	if query.Empty() {
 		sendError(w, "missing query", http.StatusBadRequest)
 		return
 	}
+	facets, _ := strconv.ParseBool(r.URL.Query().Get(ParamFacets))
 
 	limit, _ := strconv.Atoi(r.URL.Query().Get(ParamLimit))
 	if limit <= 0 {
@@ -96,23 +123,21 @@ func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
 		offset = DefaultOffset
 	}
 
-	results, total, err := h.service.Search(r.Context(), q, limit, offset)
+	result, err := h.service.Search(r.Context(), query, limit, offset, facets)
 	if err != nil {
 		sendAppError(w, err)
 		return
 	}
 
-	if results == nil {
-		results = make([]model.IndexedFile, 0)
-	}
-
-	sendJSON(w, IndexedFileListResponse{
-		Data: results,
+	sendJSON(w, SearchResponse{
+		Data: result.Files,
 		Meta: &Meta{
-			Total:  total,
+			Total:  result.Total,
 			Limit:  limit,
 			Offset: offset,
 		},
+		Facets: result.Facets,
+		Fuzzy:  result.Fuzzy,
 	})
 }
 
@@ -170,3 +195,157 @@ func (h *SearchHandler) IndexRemote(w http.ResponseWriter, r *http.Request) {
 	go h.service.IndexRemote(h.appCtx, remote)
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// Duplicates godoc
+// @Summary Find duplicate files
+// @Description Find indexed files stored more than once across remotes and local roots, the most space wasted first. Files with the same name and size are grouped; with verify, each group is confirmed with a hash all its backends support and files whose hash differs are dropped. Hashes missing from the index are computed in the background and cached there; until then their groups are returned unverified with hashing set, so the report can be reloaded later. Remove duplicates with /files/duplicates/resolve.
+// @Tags search
+// @Produce json
+// @Param minSize query int false "Smallest file size in bytes (default 1 MiB)"
+// @Param verify query bool false "Confirm groups by hash (default true)"
+// @Param limit query int false "Max number of candidate groups"
+// @Param offset query int false "Offset for pagination"
+// @Success 200 {object} DuplicateGroupListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /search/duplicates [get]
+func (h *SearchHandler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	minSize := int64(defaultDuplicateMinSize)
+	if v := r.URL.Query().Get(ParamMinSize); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			sendError(w, "invalid minSize", http.StatusBadRequest)
+			return
+		}
+		minSize = n
+	}
+	verify := true
+	if v := r.URL.Query().Get(ParamVerify); v != "" {
+		verify, _ = strconv.ParseBool(v)
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get(ParamLimit))
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get(ParamOffset))
+	if offset < 0 {
+		offset = DefaultOffset
+	}
+
+	groups, total, err := h.duplicates.Find(r.Context(), minSize, verify, limit, offset)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	sendJSON(w, DuplicateGroupListResponse{
+		Data: groups,
+		Meta: &Meta{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	})
+}
+
+// ListSaved godoc
+// @Summary List saved searches
+// @Tags search
+// @Produce json
+// @Success 200 {object} SavedSearchListResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /search/saved [get]
+func (h *SearchHandler) ListSaved(w http.ResponseWriter, r *http.Request) {
+	searches, err := h.saved.List(r.Context())
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SavedSearchListResponse{Data: searches})
+}
+
+// CreateSaved godoc
+// @Summary Create saved search
+// @Description Save a search in the query language of GET /search. While enabled, every index run checks it against the files the run added (the first index of a remote is skipped) and publishes a search.matched event for new matches. With an action, each new matching file is also copied: copy to the remote folder in destination, download into destination inside the download dir.
+// @Tags search
+// @Accept json
+// @Produce json
+// @Param request body model.SavedSearch true "Saved search"
+// @Success 201 {object} SavedSearchResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /search/saved [post]
+func (h *SearchHandler) CreateSaved(w http.ResponseWriter, r *http.Request) {
+	var req model.SavedSearch
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	ss, err := h.saved.Create(r.Context(), &req)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(SavedSearchResponse{Data: ss})
+}
+
+// GetSaved godoc
+// @Summary Get saved search
+// @Tags search
+// @Produce json
+// @Param id path string true "Saved search ID"
+// @Success 200 {object} SavedSearchResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /search/saved/{id} [get]
+func (h *SearchHandler) GetSaved(w http.ResponseWriter, r *http.Request) {
+	ss, err := h.saved.Get(r.Context(), chi.URLParam(r, ParamID))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SavedSearchResponse{Data: ss})
+}
+
+// UpdateSaved godoc
+// @Summary Update saved search
+// @Description Replace a saved search's definition. Its match count is kept.
+// @Tags search
+// @Accept json
+// @Produce json
+// @Param id path string true "Saved search ID"
+// @Param request body model.SavedSearch true "Saved search"
+// @Success 200 {object} SavedSearchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /search/saved/{id} [put]
+func (h *SearchHandler) UpdateSaved(w http.ResponseWriter, r *http.Request) {
+	var req model.SavedSearch
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	ss, err := h.saved.Update(r.Context(), chi.URLParam(r, ParamID), &req)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SavedSearchResponse{Data: ss})
+}
+
+// DeleteSaved godoc
+// @Summary Delete saved search
+// @Tags search
+// @Param id path string true "Saved search ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Router /search/saved/{id} [delete]
+func (h *SearchHandler) DeleteSaved(w http.ResponseWriter, r *http.Request) {
+	if err := h.saved.Delete(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
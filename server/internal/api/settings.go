@@ -8,6 +8,7 @@ import (
 	"gravity/internal/engine"
 	"gravity/internal/event"
 	"gravity/internal/model"
+	"gravity/internal/network"
 	"gravity/internal/store"
 
 	"github.com/go-chi/chi/v5"
@@ -102,6 +103,7 @@ func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Apply settings to engines (Sync)
+	network.Configure(&newSettings)
 	h.engine.Configure(ctx, &newSettings)
 	h.uploadEngine.Configure(ctx, &newSettings)
 
@@ -188,7 +190,9 @@ func (h *SettingsHandler) Import(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Apply
+	network.Configure(&settings)
 	h.engine.Configure(r.Context(), &settings)
+	h.uploadEngine.Configure(r.Context(), &settings)
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/service"
+	"gravity/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ShareHandler struct {
+	service *service.ShareService
+	storage engine.StorageEngine
+}
+
+func NewShareHandler(s *service.ShareService, storage engine.StorageEngine) *ShareHandler {
+	return &ShareHandler{service: s, storage: storage}
+}
+
+func (h *ShareHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.List)
+	r.Post("/", h.Create)
+	r.Delete("/{id}", h.Revoke)
+	return r
+}
+
+// PublicRoutes serves shared files. They are mounted outside the API and
+// need no API key.
+func (h *ShareHandler) PublicRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/{token}", h.Serve)
+	r.Head("/{token}", h.Serve)
+	r.Post("/{token}", h.Serve)
+	return r
+}
+
+// List godoc
+// @Summary List share links
+// @Description Get all share links, including expired ones that have not been cleaned up yet
+// @Tags shares
+// @Produce json
+// @Success 200 {object} ShareLinkListResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /shares [get]
+func (h *ShareHandler) List(w http.ResponseWriter, r *http.Request) {
+	links, err := h.service.List(r.Context())
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, ShareLinkListResponse{Data: links})
+}
+
+// Create godoc
+// @Summary Create share link
+// @Description Share a file with people who have no API key. The link is served at /s/{token} until it expires, is revoked or reaches its download limit. With native set, the remote's own sharing is used and /s/{token} redirects to it; native links cannot have a password or download limit.
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param request body CreateShareRequest true "File to share"
+// @Success 201 {object} ShareLinkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /shares [post]
+func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateShareRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	cleanPath, err := utils.SanitizePath(req.Path, "/")
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.service.Create(r.Context(), service.ShareOptions{
+		Path:         cleanPath,
+		ExpiresIn:    req.ExpiresIn,
+		Password:     req.Password,
+		MaxDownloads: req.MaxDownloads,
+		Native:       req.Native,
+	})
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ShareLinkResponse{Data: link})
+}
+
+// Revoke godoc
+// @Summary Revoke share link
+// @Tags shares
+// @Param id path string true "Share link ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Router /shares/{id} [delete]
+func (h *ShareHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Revoke(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Serve streams a shared file with Range support. The password is taken
+// from basic auth, so browsers prompt for it, the X-Share-Password header,
+// or the password field of a POSTed form; never from the URL, where it would
+// end up in logs and browser history. See ShareService.Open for which
+// requests count as downloads.
+func (h *ShareHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	_, password, _ := r.BasicAuth()
+	if p := r.Header.Get(HeaderSharePassword); p != "" {
+		password = p
+	}
+	if r.Method == http.MethodPost {
+		if p := r.PostFormValue("password"); p != "" {
+			password = p
+		}
+	}
+
+	req := service.ShareRequest{
+		Client:     shareClient(r),
+		Download:   r.Method != http.MethodHead,
+		RangeStart: -1,
+	}
+	if rng := r.Header.Get("Range"); rng != "" {
+		req.Ranged = true
+		req.RangeStart = rangeStart(rng)
+	}
+
+	link, err := h.service.Open(r.Context(), chi.URLParam(r, "token"), password, req)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.Code == apperrors.CodeUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Gravity share", charset="UTF-8"`)
+		}
+		sendAppError(w, err)
+		return
+	}
+
+	if link.NativeURL != "" {
+		http.Redirect(w, r, link.NativeURL, http.StatusFound)
+		return
+	}
+
+	info, err := h.storage.Stat(r.Context(), link.Path)
+	if err != nil {
+		sendError(w, "shared file no longer exists", http.StatusNotFound)
+		return
+	}
+	rc, err := h.storage.Open(r.Context(), link.Path)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	cw := &countingWriter{ResponseWriter: w}
+	defer func() { h.service.Served(link.ID, req.Client, cw.n, info.Size) }()
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+	http.ServeContent(cw, r, info.Name, info.ModTime, rc)
+}
+
+// shareClient identifies the client of r for continuing downloads
+func shareClient(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host + " " + r.UserAgent()
+}
+
+// rangeStart returns the first byte of the first range of a Range header,
+// or -1 if it is a suffix range or cannot be parsed
+func rangeStart(header string) int64 {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return -1
+	}
+	first, _, _ := strings.Cut(spec, ",")
+	start, _, _ := strings.Cut(strings.TrimSpace(first), "-")
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// countingWriter counts the body bytes written through it
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
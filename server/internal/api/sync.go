@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"gravity/internal/model"
+	"gravity/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SyncHandler struct {
+	service *service.SyncService
+}
+
+func NewSyncHandler(s *service.SyncService) *SyncHandler {
+	return &SyncHandler{service: s}
+}
+
+func (h *SyncHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.List)
+	r.Post("/", h.Create)
+	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+	r.Post("/{id}/run", h.Run)
+	r.Post("/{id}/cancel", h.Cancel)
+	r.Get("/{id}/runs", h.Runs)
+	return r
+}
+
+// List godoc
+// @Summary List sync jobs
+// @Description Get all scheduled copy, move and sync jobs
+// @Tags sync
+// @Produce json
+// @Success 200 {object} SyncJobListResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sync [get]
+func (h *SyncHandler) List(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.service.List(r.Context())
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SyncJobListResponse{Data: jobs})
+}
+
+// Create godoc
+// @Summary Create sync job
+// @Description Add a named copy, move or sync between local folders and remotes. Jobs run on a cron schedule, every intervalMin minutes, or only by hand.
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Param request body model.SyncJob true "Sync job"
+// @Success 201 {object} SyncJobResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sync [post]
+func (h *SyncHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req model.SyncJob
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	j, err := h.service.Create(r.Context(), &req)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(SyncJobResponse{Data: j})
+}
+
+// Get godoc
+// @Summary Get sync job
+// @Tags sync
+// @Produce json
+// @Param id path string true "Sync job ID"
+// @Success 200 {object} SyncJobResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sync/{id} [get]
+func (h *SyncHandler) Get(w http.ResponseWriter, r *http.Request) {
+	j, err := h.service.Get(r.Context(), chi.URLParam(r, ParamID))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SyncJobResponse{Data: j})
+}
+
+// Update godoc
+// @Summary Update sync job
+// @Description Replace a sync job's definition. Its run history is kept.
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Param id path string true "Sync job ID"
+// @Param request body model.SyncJob true "Sync job"
+// @Success 200 {object} SyncJobResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sync/{id} [put]
+func (h *SyncHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req model.SyncJob
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	j, err := h.service.Update(r.Context(), chi.URLParam(r, ParamID), &req)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, SyncJobResponse{Data: j})
+}
+
+// Delete godoc
+// @Summary Delete sync job
+// @Description Delete a sync job and its run history, stopping a run in progress
+// @Tags sync
+// @Param id path string true "Sync job ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Router /sync/{id} [delete]
+func (h *SyncHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Delete(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Run godoc
+// @Summary Run sync job
+// @Description Start a sync job now. Progress is published as sync.progress events.
+// @Tags sync
+// @Produce json
+// @Param id path string true "Sync job ID"
+// @Param dryRun query bool false "Only report what would change"
+// @Success 202 {object} SyncRunResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sync/{id}/run [post]
+func (h *SyncHandler) Run(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get(ParamDryRun))
+	run, err := h.service.Run(r.Context(), chi.URLParam(r, ParamID), dryRun)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(SyncRunResponse{Data: run})
+}
+
+// Cancel godoc
+// @Summary Cancel sync run
+// @Description Stop the running run of a sync job
+// @Tags sync
+// @Param id path string true "Sync job ID"
+// @Success 200 "OK"
+// @Failure 409 {object} ErrorResponse
+// @Router /sync/{id}/cancel [post]
+func (h *SyncHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Cancel(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Runs godoc
+// @Summary List sync runs
+// @Description Get the run history of a sync job with per-run stats and errors, newest first
+// @Tags sync
+// @Produce json
+// @Param id path string true "Sync job ID"
+// @Param limit query int false "Max number of items to return"
+// @Param offset query int false "Offset for pagination"
+// @Success 200 {object} SyncRunListResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sync/{id}/runs [get]
+func (h *SyncHandler) Runs(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get(ParamLimit))
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get(ParamOffset))
+
+	runs, total, err := h.service.Runs(r.Context(), chi.URLParam(r, ParamID), limit, offset)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	sendJSON(w, SyncRunListResponse{
+		Data: runs,
+		Meta: &Meta{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	})
+}
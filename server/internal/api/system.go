@@ -8,6 +8,7 @@ import (
 
 	"gravity/internal/engine"
 	"gravity/internal/engine/hybrid"
+	"gravity/internal/network"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -25,6 +26,8 @@ func NewSystemHandler(ctx context.Context, de engine.DownloadEngine, ue engine.U
 func (h *SystemHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 	r.Get("/version", h.Version)
+	r.Get("/engines", h.Engines)
+	r.Get("/proxies", h.Proxies)
 	r.Post("/restart/aria2", h.RestartAria2)
 	r.Post("/restart/rclone", h.RestartRclone)
 	r.Post("/restart/server", h.RestartServer)
@@ -60,6 +63,36 @@ func (h *SystemHandler) Version(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Engines godoc
+// @Summary Get engine capabilities
+// @Description List the protocols, per-download options and live settings each download engine honors
+// @Tags system
+// @Produce json
+// @Success 200 {object} EngineCapabilitiesListResponse
+// @Router /system/engines [get]
+func (h *SystemHandler) Engines(w http.ResponseWriter, r *http.Request) {
+	var caps []engine.Capabilities
+
+	if router, ok := h.downloadEngine.(*hybrid.HybridRouter); ok {
+		caps = router.GetCapabilities()
+	} else {
+		caps = []engine.Capabilities{h.downloadEngine.Capabilities()}
+	}
+
+	sendJSON(w, EngineCapabilitiesListResponse{Data: caps})
+}
+
+// Proxies godoc
+// @Summary Get proxy pool health
+// @Description List configured proxies with the result of their last health check
+// @Tags system
+// @Produce json
+// @Success 200 {object} ProxyStatusListResponse
+// @Router /system/proxies [get]
+func (h *SystemHandler) Proxies(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, ProxyStatusListResponse{Data: network.Default().Status()})
+}
+
 // RestartAria2 godoc
 // @Summary Restart Aria2 engine
 // @Description Stop and restart the underlying Aria2 download engine
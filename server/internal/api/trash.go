@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"gravity/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TrashHandler struct {
+	service *service.TrashService
+}
+
+func NewTrashHandler(s *service.TrashService) *TrashHandler {
+	return &TrashHandler{service: s}
+}
+
+func (h *TrashHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.List)
+	r.Delete("/", h.Empty)
+	r.Post("/{id}/restore", h.Restore)
+	r.Delete("/{id}", h.Purge)
+	return r
+}
+
+// List godoc
+// @Summary List trash
+// @Description Get deleted files and folders that can still be restored, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param limit query int false "Max number of items to return"
+// @Param offset query int false "Offset for pagination"
+// @Success 200 {object} TrashItemListResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /trash [get]
+func (h *TrashHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get(ParamLimit))
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get(ParamOffset))
+
+	items, total, err := h.service.List(r.Context(), limit, offset)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	sendJSON(w, TrashItemListResponse{
+		Data: items,
+		Meta: &Meta{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	})
+}
+
+// Restore godoc
+// @Summary Restore from trash
+// @Description Move a deleted item back to where it was deleted from. Fails if something else now exists there.
+// @Tags trash
+// @Produce json
+// @Param id path string true "Trash item ID"
+// @Success 200 {object} TrashItemResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /trash/{id}/restore [post]
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	item, err := h.service.Restore(r.Context(), chi.URLParam(r, ParamID))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, TrashItemResponse{Data: item})
+}
+
+// Purge godoc
+// @Summary Purge trash item
+// @Description Delete an item in the trash permanently
+// @Tags trash
+// @Param id path string true "Trash item ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse
+// @Router /trash/{id} [delete]
+func (h *TrashHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Purge(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Empty godoc
+// @Summary Empty trash
+// @Description Delete everything in the trash permanently
+// @Tags trash
+// @Produce json
+// @Success 200 {object} EmptyTrashResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /trash [delete]
+func (h *TrashHandler) Empty(w http.ResponseWriter, r *http.Request) {
+	n, err := h.service.Empty(r.Context())
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, EmptyTrashResponse{Data: EmptyTrashResult{Purged: n}})
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"gravity/internal/engine"
 	"gravity/internal/model"
+	"gravity/internal/network"
 	"gravity/internal/provider"
 	"time"
 )
@@ -14,6 +15,17 @@ type RemoteList []engine.Remote
 type FileInfoList []engine.FileInfo
 type IndexedFileList []model.IndexedFile
 type RemoteIndexConfigList []model.RemoteIndexConfig
+type EngineCapabilitiesList []engine.Capabilities
+type ProxyStatusList []network.ProxyStatus
+type SiteProfileList []*model.SiteProfile
+type UploadJobList []*model.UploadJob
+type SyncJobList []*model.SyncJob
+type SyncRunList []*model.SyncRun
+type ShareLinkList []*model.ShareLink
+type TrashItemList []*model.TrashItem
+type DuplicateGroupList []*model.DuplicateGroup
+type DuplicateRemovalList []model.DuplicateRemoval
+type SavedSearchList []*model.SavedSearch
 
 // Concrete response wrappers for Swagger (Flattened to avoid generated names)
 // Only include fields that are actually used in the response.
@@ -27,6 +39,57 @@ type DownloadResponse struct {
 	Data *model.Download `json:"data" binding:"required"`
 }
 
+type UploadJobListResponse struct {
+	Data UploadJobList `json:"data" binding:"required"`
+	Meta *Meta         `json:"meta,omitempty"`
+}
+
+type UploadJobResponse struct {
+	Data *model.UploadJob `json:"data" binding:"required"`
+}
+
+type SyncJobListResponse struct {
+	Data SyncJobList `json:"data" binding:"required"`
+}
+
+type SyncJobResponse struct {
+	Data *model.SyncJob `json:"data" binding:"required"`
+}
+
+type SyncRunListResponse struct {
+	Data SyncRunList `json:"data" binding:"required"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+type SyncRunResponse struct {
+	Data *model.SyncRun `json:"data" binding:"required"`
+}
+
+type ShareLinkListResponse struct {
+	Data ShareLinkList `json:"data" binding:"required"`
+}
+
+type ShareLinkResponse struct {
+	Data *model.ShareLink `json:"data" binding:"required"`
+}
+
+type TrashItemListResponse struct {
+	Data TrashItemList `json:"data" binding:"required"`
+	Meta *Meta         `json:"meta,omitempty"`
+}
+
+type TrashItemResponse struct {
+	Data *model.TrashItem `json:"data" binding:"required"`
+}
+
+type EmptyTrashResponse struct {
+	Data EmptyTrashResult `json:"data" binding:"required"`
+}
+
+type EmptyTrashResult struct {
+	Purged int `json:"purged" example:"12"`
+}
+
 type ProviderListResponse struct {
 	Data ProviderList `json:"data" binding:"required"`
 }
@@ -59,11 +122,39 @@ type FileOperationResponse struct {
 	Data FileOperation `json:"data" binding:"required"`
 }
 
+type FileUploadSessionResponse struct {
+	Data *model.FileUploadSession `json:"data" binding:"required"`
+}
+
 type IndexedFileListResponse struct {
 	Data IndexedFileList `json:"data" binding:"required"`
 	Meta *Meta           `json:"meta,omitempty"`
 }
 
+type SearchResponse struct {
+	Data   IndexedFileList     `json:"data" binding:"required"`
+	Meta   *Meta               `json:"meta,omitempty"`
+	Facets *model.SearchFacets `json:"facets,omitempty"`
+	Fuzzy  bool                `json:"fuzzy"` // No exact match; these are typo tolerant matches
+}
+
+type DuplicateGroupListResponse struct {
+	Data DuplicateGroupList `json:"data" binding:"required"`
+	Meta *Meta              `json:"meta,omitempty"`
+}
+
+type DuplicateRemovalListResponse struct {
+	Data DuplicateRemovalList `json:"data" binding:"required"`
+}
+
+type SavedSearchListResponse struct {
+	Data SavedSearchList `json:"data" binding:"required"`
+}
+
+type SavedSearchResponse struct {
+	Data *model.SavedSearch `json:"data" binding:"required"`
+}
+
 type RemoteIndexConfigListResponse struct {
 	Data RemoteIndexConfigList `json:"data" binding:"required"`
 }
@@ -72,6 +163,26 @@ type SystemVersionResponse struct {
 	Data SystemVersion `json:"data" binding:"required"`
 }
 
+type EngineCapabilitiesListResponse struct {
+	Data EngineCapabilitiesList `json:"data" binding:"required"`
+}
+
+type ProxyStatusListResponse struct {
+	Data ProxyStatusList `json:"data" binding:"required"`
+}
+
+type SiteProfileListResponse struct {
+	Data SiteProfileList `json:"data" binding:"required"`
+}
+
+type SiteProfileResponse struct {
+	Data *model.SiteProfile `json:"data" binding:"required"`
+}
+
+type PathPreviewResponse struct {
+	Data engine.TemplatePreview `json:"data" binding:"required"`
+}
+
 type SettingsResponse struct {
 	Data *model.Settings `json:"data" binding:"required"`
 }
@@ -149,6 +260,17 @@ type CreateDownloadRequest struct {
 	RemoveLocal *bool             `json:"removeLocal"`
 	Headers     map[string]string `json:"headers"`
 
+	// Write straight into the destination instead of staging locally.
+	// Defaults to settings.upload.streamToDestination.
+	StreamToDestination *bool `json:"streamToDestination"`
+
+	// Replicate to several remotes, each uploaded as its own job. Merged
+	// with destination, which stays the primary.
+	Destinations []string `json:"destinations" example:"gdrive:movies,s3:backup"`
+	// Destinations that must succeed for the download to complete (0 = all).
+	// Defaults to settings.upload.quorum.
+	UploadQuorum *int `json:"uploadQuorum" validate:"omitempty,min=0"`
+
 	// Optional Overrides
 	Priority         *int    `json:"priority" validate:"omitempty,min=1,max=10"`
 	MaxRetries       *int    `json:"maxRetries" validate:"omitempty,min=0"`
@@ -168,10 +290,23 @@ type BatchActionRequest struct {
 }
 
 type UpdateDownloadRequest struct {
-	Filename    *string `json:"filename"`
-	Destination *string `json:"destination"`
-	Priority    *int    `json:"priority" validate:"omitempty,min=1,max=10"`
-	MaxRetries  *int    `json:"maxRetries" validate:"omitempty,min=0"`
+	Filename     *string  `json:"filename"`
+	Destination  *string  `json:"destination"`
+	Destinations []string `json:"destinations"`
+	Priority     *int     `json:"priority" validate:"omitempty,min=1,max=10"`
+	MaxRetries   *int     `json:"maxRetries" validate:"omitempty,min=0"`
+}
+
+type PreviewPathRequest struct {
+	Template string `json:"template" validate:"required" binding:"required" example:"gdrive:/Media/{category}/{year}/{month}/{filename}"`
+	// Expand for an existing download instead of a sample
+	DownloadID string `json:"downloadId" example:"d_a1b2c3d4"`
+
+	// Sample download; blank fields get example values
+	URL      string `json:"url" example:"https://example.com/files/movie.mkv"`
+	Filename string `json:"filename" example:"movie.mkv"`
+	Provider string `json:"provider" example:"direct"`
+	Hash     string `json:"hash"`
 }
 
 // Search
@@ -186,6 +321,10 @@ type BatchUpdateConfigRequest struct {
 	Configs map[string]UpdateConfigRequest `json:"configs" binding:"required"`
 }
 
+type ResolveDuplicatesRequest struct {
+	Groups []model.DuplicateResolution `json:"groups" validate:"required,min=1,dive" binding:"required"`
+}
+
 // Files
 type MkdirRequest struct {
 	Path string `json:"path" validate:"required" binding:"required" example:"/movies"`
@@ -201,6 +340,32 @@ type FileOperationRequest struct {
 	Dst string `json:"dst" validate:"required" example:"/movies/file.txt"`
 }
 
+type ArchiveRequest struct {
+	Paths  []string `json:"paths" validate:"required,min=1" binding:"required" example:"/gdrive/photos,/gdrive/notes.txt"`
+	Format string   `json:"format" validate:"omitempty,oneof=zip tar" enums:"zip,tar" example:"zip"`
+	// File name of the download, without extension. Defaults to the name of
+	// the single selected path, or "archive".
+	Name string `json:"name" example:"photos"`
+	// Refuse selections larger than this many bytes. It can only lower the
+	// archiveMaxSize setting.
+	MaxSize int64 `json:"maxSize,omitempty" validate:"omitempty,min=1" example:"10737418240"`
+}
+
+type CreateShareRequest struct {
+	Path         string `json:"path" validate:"required" binding:"required" example:"/gdrive/movies/file.mkv"`
+	ExpiresIn    string `json:"expiresIn" example:"7d"` // Defaults to 7d
+	Password     string `json:"password,omitempty"`
+	MaxDownloads int    `json:"maxDownloads" validate:"min=0" example:"10"` // 0 = unlimited
+	// Create the link with the remote's own sharing, where it has one
+	Native bool `json:"native"`
+}
+
+type CreateUploadSessionRequest struct {
+	Path     string               `json:"path" validate:"required" binding:"required" example:"/gdrive/movies/file.mkv"`
+	Size     int64                `json:"size" validate:"min=0" example:"1073741824"`
+	Conflict model.ConflictPolicy `json:"conflict" enums:"fail,overwrite,rename"`
+}
+
 type FileOperation struct {
 	JobID string `json:"jobId" binding:"required"`
 }
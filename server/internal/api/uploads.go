@@ -0,0 +1,156 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gravity/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type UploadHandler struct {
+	service *service.UploadService
+}
+
+func NewUploadHandler(s *service.UploadService) *UploadHandler {
+	return &UploadHandler{service: s}
+}
+
+func (h *UploadHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Post("/{id}/pause", h.Pause)
+	r.Post("/{id}/resume", h.Resume)
+	r.Post("/{id}/cancel", h.Cancel)
+	r.Post("/{id}/retry", h.Retry)
+	return r
+}
+
+// List godoc
+// @Summary List uploads
+// @Description Get the upload queue: one job per download destination, newest first
+// @Tags uploads
+// @Produce json
+// @Param status query string false "Comma-separated statuses to filter by (queued, running, paused, complete, error, cancelled)"
+// @Param limit query int false "Max number of items to return"
+// @Param offset query int false "Offset for pagination"
+// @Success 200 {object} UploadJobListResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /uploads [get]
+func (h *UploadHandler) List(w http.ResponseWriter, r *http.Request) {
+	statusStr := r.URL.Query().Get(ParamStatus)
+	var status []string
+	if statusStr != "" {
+		status = strings.Split(statusStr, ",")
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get(ParamLimit))
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get(ParamOffset))
+
+	jobs, total, err := h.service.List(r.Context(), status, limit, offset)
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+
+	sendJSON(w, UploadJobListResponse{
+		Data: jobs,
+		Meta: &Meta{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	})
+}
+
+// Get godoc
+// @Summary Get upload
+// @Description Get an upload job by ID
+// @Tags uploads
+// @Produce json
+// @Param id path string true "Upload job ID"
+// @Success 200 {object} UploadJobResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /uploads/{id} [get]
+func (h *UploadHandler) Get(w http.ResponseWriter, r *http.Request) {
+	j, err := h.service.Get(r.Context(), chi.URLParam(r, ParamID))
+	if err != nil {
+		sendAppError(w, err)
+		return
+	}
+	sendJSON(w, UploadJobResponse{Data: j})
+}
+
+// Pause godoc
+// @Summary Pause upload
+// @Description Pause a queued or running upload. A running upload starts over when resumed.
+// @Tags uploads
+// @Param id path string true "Upload job ID"
+// @Success 200 "OK"
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /uploads/{id}/pause [post]
+func (h *UploadHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Pause(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Resume godoc
+// @Summary Resume upload
+// @Description Put a paused upload back in the queue
+// @Tags uploads
+// @Param id path string true "Upload job ID"
+// @Success 200 "OK"
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /uploads/{id}/resume [post]
+func (h *UploadHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Resume(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Cancel godoc
+// @Summary Cancel upload
+// @Description Stop an upload for good; its destination counts as failed
+// @Tags uploads
+// @Param id path string true "Upload job ID"
+// @Success 200 "OK"
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /uploads/{id}/cancel [post]
+func (h *UploadHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Cancel(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Retry godoc
+// @Summary Retry upload
+// @Description Queue a failed, mismatched or cancelled upload again. Uploads that failed verification are sent in full.
+// @Tags uploads
+// @Param id path string true "Upload job ID"
+// @Success 200 "OK"
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /uploads/{id}/retry [post]
+func (h *UploadHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Retry(r.Context(), chi.URLParam(r, ParamID)); err != nil {
+		sendAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
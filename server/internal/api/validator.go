@@ -44,6 +44,10 @@ func sendAppError(w http.ResponseWriter, err error) {
 			code = http.StatusBadRequest
 		case apperrors.CodeInvalidTransition, apperrors.CodeInvalidOperation:
 			code = http.StatusConflict
+		case apperrors.CodeUnauthorized:
+			code = http.StatusUnauthorized
+		case apperrors.CodeGone:
+			code = http.StatusGone
 		default:
 			code = http.StatusInternalServerError
 		}
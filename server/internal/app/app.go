@@ -20,6 +20,7 @@ import (
 	"gravity/internal/event"
 	"gravity/internal/logger"
 	"gravity/internal/model"
+	"gravity/internal/network"
 	"gravity/internal/provider"
 	"gravity/internal/provider/alldebrid"
 	"gravity/internal/provider/debridlink"
@@ -27,6 +28,7 @@ import (
 	"gravity/internal/provider/megadebrid"
 	"gravity/internal/provider/premiumize"
 	"gravity/internal/provider/realdebrid"
+	"gravity/internal/provider/remote"
 	"gravity/internal/provider/torbox"
 	"gravity/internal/provider/ytdlp"
 	"gravity/internal/service"
@@ -50,8 +52,15 @@ type App struct {
 	downloadService *service.DownloadService
 	uploadService   *service.UploadService
 	providerService *service.ProviderService
+	profileService  *service.SiteProfileService
 	statsService    *service.StatsService
 	searchService   *service.SearchService
+	dupService      *service.DuplicateService
+	syncService     *service.SyncService
+	fileService     *service.FileUploadService
+	shareService    *service.ShareService
+	trashService    *service.TrashService
+	quotaService    *service.QuotaService
 
 	httpServer *http.Server
 	Router     *api.Router
@@ -100,10 +109,21 @@ func New(ctx context.Context, de engine.DownloadEngine, ue engine.UploadEngine)
 
 	// Repos
 	dr := store.NewDownloadRepo(s.GetDB())
+	ujr := store.NewUploadJobRepo(s.GetDB())
+	sjr := store.NewSyncJobRepo(s.GetDB())
+	shr := store.NewShareLinkRepo(s.GetDB())
+	tr := store.NewTrashRepo(s.GetDB())
 	pr := store.NewProviderRepo(s.GetDB())
 	sr := store.NewStatsRepo(s.GetDB())
 	setr := store.NewSettingsRepo(s.GetDB())
 	searchRepo := store.NewSearchRepo(s.GetDB())
+	ssr := store.NewSavedSearchRepo(s.GetDB())
+
+	secrets, err := store.NewSecretBox(cfg)
+	if err != nil {
+		return nil, err
+	}
+	spr := store.NewSiteProfileRepo(s.GetDB(), secrets)
 
 	// Engines (Initialize both for Hybrid support)
 	if de == nil {
@@ -119,6 +139,7 @@ func New(ctx context.Context, de engine.DownloadEngine, ue engine.UploadEngine)
 	// Providers
 	registry := provider.NewRegistry()
 	registry.Register(direct.New())
+	registry.Register(remote.New())
 	registry.Register(ytdlp.New(binMgr.GetYtDlpPath()))
 	ad := alldebrid.New()
 	registry.Register(ad)
@@ -129,24 +150,40 @@ func New(ctx context.Context, de engine.DownloadEngine, ue engine.UploadEngine)
 	registry.Register(megadebrid.New())
 
 	// Services
+	sps := service.NewSiteProfileService(spr)
+	provider.SetCredentials(sps)
+	rclone.SetSourceCredentials(sps)
 	ps := service.NewProviderService(pr, registry, de)
-	ds := service.NewDownloadService(dr, setr, de, ue, bus, ps)
-	us := service.NewUploadService(dr, setr, ue, bus)
-	ss := service.NewStatsService(sr, setr, dr, de, ue, bus)
-	searchService := service.NewSearchService(searchRepo, setr, ue)
+	ts := service.NewTrashService(tr, ue, setr)
+	ds := service.NewDownloadService(dr, setr, de, ue, bus, ps, sps, ts)
+	qs := service.NewQuotaService(ue)
+	us := service.NewUploadService(dr, ujr, setr, ue, bus, qs)
+	ss := service.NewStatsService(sr, setr, dr, de, ue, bus, qs)
+	sss := service.NewSavedSearchService(ssr, searchRepo, ue, bus)
+	searchService := service.NewSearchService(searchRepo, setr, ue, bus, dr, sss)
+	dups := service.NewDuplicateService(searchRepo, ue)
+	syncService := service.NewSyncService(sjr, ue, bus)
+	fus := service.NewFileUploadService(ue, bus, cfg.DataDir)
+	as := service.NewArchiveService(ue, setr)
+	shs := service.NewShareService(shr, ue)
 
 	// API
 	router := api.NewRouter(cfg.APIKey)
 
-	dh := api.NewDownloadHandler(ds)
+	dh := api.NewDownloadHandler(ds, us)
 	ph := api.NewProviderHandler(ps)
-	rh := api.NewRemoteHandler(ue)
+	rh := api.NewRemoteHandler(ue, qs)
 	sh := api.NewStatsHandler(ss)
 	seth := api.NewSettingsHandler(setr, pr, de, ue, bus)
 	sysh := api.NewSystemHandler(ctx, de, ue)
-	fh := api.NewFileHandler(ue, ue)
-	searchHandler := api.NewSearchHandler(ctx, searchService)
+	fh := api.NewFileHandler(ue, ue, fus, as, ts, dups)
+	searchHandler := api.NewSearchHandler(ctx, searchService, dups, sss)
 	eh := api.NewEventHandler(bus, de, ss)
+	sph := api.NewSiteProfileHandler(sps)
+	uh := api.NewUploadHandler(us)
+	synch := api.NewSyncHandler(syncService)
+	shh := api.NewShareHandler(shs, ue)
+	th := api.NewTrashHandler(ts)
 
 	// V1 Router
 	v1 := chi.NewRouter()
@@ -161,9 +198,18 @@ func New(ctx context.Context, de engine.DownloadEngine, ue engine.UploadEngine)
 	v1.Mount("/files", fh.Routes())
 	v1.Mount("/search", searchHandler.Routes())
 	v1.Mount("/events", eh.Routes())
+	v1.Mount("/profiles", sph.Routes())
+	v1.Mount("/uploads", uh.Routes())
+	v1.Mount("/sync", synch.Routes())
+	v1.Mount("/shares", shh.Routes())
+	v1.Mount("/trash", th.Routes())
 
 	// Mount V1 to root
 	router.Mount("/api/v1", v1)
+	router.Mount("/s", shh.PublicRoutes())
+	if dav, ok := ue.(engine.WebDAVServer); ok {
+		router.Mount(api.DAVPrefix, router.BasicAuth(api.NewDAVHandler(dav)))
+	}
 	router.Handle("/*", AssetsHandler())
 
 	srv := &http.Server{
@@ -182,8 +228,15 @@ func New(ctx context.Context, de engine.DownloadEngine, ue engine.UploadEngine)
 		downloadService: ds,
 		uploadService:   us,
 		providerService: ps,
+		profileService:  sps,
 		statsService:    ss,
 		searchService:   searchService,
+		dupService:      dups,
+		syncService:     syncService,
+		fileService:     fus,
+		shareService:    shs,
+		trashService:    ts,
+		quotaService:    qs,
 		httpServer:      srv,
 		Router:          router,
 	}, nil
@@ -229,6 +282,13 @@ func (a *App) StartEngines(ctx context.Context) error {
 		}
 	}
 
+	// Proxy pool and dialer are shared by engines and provider clients, so
+	// they must be ready before either starts
+	if settings != nil {
+		network.Configure(settings)
+	}
+	go network.Default().Run(ctx)
+
 	// Start engines
 	if err := a.DownloadEngine.Start(ctx); err != nil {
 		return err
@@ -247,6 +307,9 @@ func (a *App) StartEngines(ctx context.Context) error {
 
 	// Init provider configs
 	a.providerService.Init(ctx)
+	if err := a.profileService.Load(ctx); err != nil {
+		a.logger.Warn("failed to load site profiles", zap.Error(err))
+	}
 
 	// Sync engine state
 	if err := a.downloadService.Sync(ctx); err != nil {
@@ -258,6 +321,12 @@ func (a *App) StartEngines(ctx context.Context) error {
 	a.uploadService.Start(ctx)
 	a.statsService.Start(ctx)
 	a.searchService.Start(ctx)
+	a.dupService.Start(ctx)
+	a.syncService.Start(ctx)
+	a.fileService.Start(ctx)
+	a.shareService.Start(ctx)
+	a.trashService.Start(ctx)
+	a.quotaService.Start(ctx)
 
 	return nil
 }
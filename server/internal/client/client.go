@@ -2,8 +2,15 @@ package client
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"gravity/internal/model"
+	"gravity/internal/network"
+
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/lib/rest"
@@ -27,7 +34,36 @@ func New(ctx context.Context, baseURL string, opts ...Option) *Client {
 		opt(ci)
 	}
 
-	baseClient := fshttp.NewClient(ctx)
+	// API clients without an explicit proxy use the provider proxies, picked
+	// for each request so pool changes and health checks apply right away
+	pooled := ci.Proxy == "" && baseURL != ""
+
+	// rclone's own dialer (used for SOCKS proxies) only needs the source address
+	dialer := network.DefaultDialer()
+	if ip, err := dialer.BindIP(false); err == nil && ip != nil {
+		ci.BindAddr = ip
+	}
+
+	baseClient := fshttp.NewClientCustom(ctx, func(t *http.Transport) {
+		if pooled {
+			t.Proxy = poolProxy
+		}
+		// rclone dials explicit SOCKS proxies itself; pooled ones go through
+		// the transport and so through the dialer below
+		if !dialer.Active() || (!pooled && strings.HasPrefix(ci.Proxy, "socks5")) {
+			return
+		}
+		// Resolve through DoH and bind to the configured interface
+		timeout := time.Duration(ci.ConnectTimeout)
+		t.DialContext = func(ctx context.Context, netw, addr string) (net.Conn, error) {
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			return dialer.DialContext(ctx, netw, addr)
+		}
+	})
 
 	// Create the rest client
 	rc := rest.NewClient(baseClient)
@@ -38,6 +74,15 @@ func New(ctx context.Context, baseURL string, opts ...Option) *Client {
 	return rc
 }
 
+// poolProxy selects a provider proxy for req, or the environment's proxy if
+// the pool has none for it
+func poolProxy(req *http.Request) (*url.URL, error) {
+	if proxy := network.Default().Select(model.ProxyTypeProviders, req.URL.String()); proxy != "" {
+		return url.Parse(proxy)
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
 // WithTimeout sets the response header timeout.
 // This is the total time to wait for a response header after sending the request.
 func WithTimeout(d time.Duration) Option {
@@ -68,6 +113,14 @@ func WithProxy(proxyURL string) Option {
 	}
 }
 
+// WithProxyFor picks a proxy from the shared pool for the given purpose and
+// target URL. An empty result leaves the connection direct.
+func WithProxyFor(purpose, target string) Option {
+	return func(ci *fs.ConfigInfo) {
+		ci.Proxy = network.Default().Select(purpose, target)
+	}
+}
+
 // WithUserAgent sets the User-Agent header for all requests.
 func WithUserAgent(ua string) Option {
 	return func(ci *fs.ConfigInfo) {
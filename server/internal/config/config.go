@@ -22,6 +22,7 @@ type Config struct {
 
 	RcloneConfigPath string   `koanf:"rclone_config_path"`
 	APIKey           string   `koanf:"api_key"`
+	SecretKey        string   `koanf:"secret_key"` // Encrypts stored credentials; generated in DataDir when empty
 	Database         DBConfig `koanf:"database"`
 
 	LogFile string `koanf:"log_file"`
@@ -12,6 +12,7 @@ import (
 
 	"gravity/internal/engine"
 	"gravity/internal/model"
+	"gravity/internal/network"
 
 	"github.com/anacrolix/torrent"
 	"go.uber.org/zap"
@@ -180,20 +181,41 @@ func (e *Engine) Add(ctx context.Context, url string, opts engine.DownloadOption
 	if opts.UserAgent != nil && *opts.UserAgent != "" {
 		ariaOpts["user-agent"] = *opts.UserAgent
 	}
+	if opts.Referer != nil && *opts.Referer != "" {
+		ariaOpts["referer"] = *opts.Referer
+	}
+	if opts.MaxConnectionPerServer != nil && *opts.MaxConnectionPerServer > 0 {
+		ariaOpts["max-connection-per-server"] = strconv.Itoa(*opts.MaxConnectionPerServer)
+	}
+	if opts.ConnectTimeout != nil && *opts.ConnectTimeout > 0 {
+		ariaOpts["connect-timeout"] = strconv.Itoa(*opts.ConnectTimeout)
+	}
+	if opts.CheckCertificate != nil {
+		ariaOpts["check-certificate"] = strconv.FormatBool(*opts.CheckCertificate)
+	}
+	if opts.MaxDownloadSpeed != nil && *opts.MaxDownloadSpeed != "" {
+		ariaOpts["max-download-limit"] = *opts.MaxDownloadSpeed
+	}
+	if opts.MaxUploadSpeed != nil && *opts.MaxUploadSpeed != "" {
+		ariaOpts["max-upload-limit"] = *opts.MaxUploadSpeed
+	}
+	if opts.LowestSpeedLimit != nil && *opts.LowestSpeedLimit != "" {
+		ariaOpts["lowest-speed-limit"] = *opts.LowestSpeedLimit
+	}
+	if opts.MinSplitSize != nil && *opts.MinSplitSize != "" {
+		ariaOpts["min-split-size"] = *opts.MinSplitSize
+	}
+	if opts.PreAllocateSpace != nil && *opts.PreAllocateSpace {
+		ariaOpts["file-allocation"] = "prealloc"
+	}
 
-	// Proxies
-	if len(opts.Proxies) > 0 {
-		// aria2 takes a single proxy via all-proxy
-		ariaOpts["all-proxy"] = opts.Proxies[0].URL
-	} else {
-		// Auto-configure proxy based on settings
-		e.mu.RLock()
-		s := e.settings
-		e.mu.RUnlock()
-
-		if s != nil && len(s.Network.Proxies) > 0 {
-			ariaOpts["all-proxy"] = s.Network.Proxies[0].URL
-		}
+	// Proxies: aria2 takes a single proxy per download via all-proxy
+	purpose := model.ProxyTypeDownloads
+	if strings.HasPrefix(url, "magnet:") || strings.HasSuffix(url, ".torrent") || opts.TorrentData != "" {
+		purpose = model.ProxyTypeMagnets
+	}
+	if proxyURL := network.Default().SelectFor(opts.Proxies, purpose, url); proxyURL != "" {
+		ariaOpts["all-proxy"] = proxyURL
 	}
 
 	// File selection for torrents/magnets
@@ -367,8 +389,10 @@ func (e *Engine) Configure(ctx context.Context, settings *model.Settings) error
 	ariaOpts := make(map[string]any)
 
 	// Download
-	if settings.Download.DownloadDir != "" {
-		ariaOpts["dir"] = settings.Download.DownloadDir
+	// Downloads are always added with their expanded dir; the global one
+	// is only a fallback, so a template is cut back to its root
+	if dir := engine.TemplateRoot(settings.Download.DownloadDir); dir != "" {
+		ariaOpts["dir"] = dir
 	}
 	if settings.Download.MaxConcurrentDownloads > 0 {
 		ariaOpts["max-concurrent-downloads"] = strconv.Itoa(settings.Download.MaxConcurrentDownloads)
@@ -406,12 +430,9 @@ func (e *Engine) Configure(ctx context.Context, settings *model.Settings) error
 		ariaOpts["min-split-size"] = settings.Download.MinSplitSize
 	}
 
-	// Network
-	if len(settings.Network.Proxies) > 0 {
-		ariaOpts["all-proxy"] = settings.Network.Proxies[0].URL
-	} else {
-		ariaOpts["all-proxy"] = ""
-	}
+	// Network: downloads pick their proxy in Add, the global one is only
+	// the fallback for anything aria2 fetches on its own
+	ariaOpts["all-proxy"] = network.Default().Select(model.ProxyTypeDownloads, "")
 	if settings.Network.InterfaceBinding != "" {
 		ariaOpts["interface"] = settings.Network.InterfaceBinding
 	}
@@ -455,6 +476,62 @@ func (e *Engine) Version(ctx context.Context) (string, error) {
 	return v.Version, nil
 }
 
+func (e *Engine) Capabilities() engine.Capabilities {
+	return engine.Capabilities{
+		Engine:    "aria2",
+		Protocols: []string{"http", "https", "ftp", "sftp", "magnet", "torrent"},
+		Options: []string{
+			engine.OptHeaders,
+			engine.OptReferer,
+			engine.OptUserAgent,
+			engine.OptSelectedFiles,
+			engine.OptSplit,
+			engine.OptMaxConnectionPerServer,
+			engine.OptConnectTimeout,
+			engine.OptMaxTries,
+			engine.OptCheckCertificate,
+			engine.OptMaxDownloadSpeed,
+			engine.OptMaxUploadSpeed,
+			engine.OptLowestSpeedLimit,
+			engine.OptMinSplitSize,
+			engine.OptPreAllocateSpace,
+			engine.OptProxies,
+		},
+		LiveOptions: []string{
+			"download.downloadDir",
+			"download.maxConcurrentDownloads",
+			"download.maxDownloadSpeed",
+			"download.maxUploadSpeed",
+			"download.maxConnectionPerServer",
+			"download.split",
+			"download.userAgent",
+			"download.connectTimeout",
+			"download.maxTries",
+			"download.preAllocateSpace",
+			"download.diskCache",
+			"download.minSplitSize",
+			"network.proxies",
+			"network.interfaceBinding",
+			"network.tcpPortRange",
+			"torrent.seedRatio",
+			"torrent.seedTime",
+			"torrent.listenPort",
+			"torrent.enableDht",
+			"torrent.enablePex",
+			"torrent.enableLpd",
+			"torrent.encryption",
+			"torrent.maxPeers",
+		},
+		Torrent: engine.TorrentCapabilities{
+			Magnets:       true,
+			TorrentFiles:  true,
+			FileSelection: true,
+			Seeding:       true,
+			PeerDetails:   true,
+		},
+	}
+}
+
 func (e *Engine) GetClient() *Client {
 	return e.client
 }
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"slices"
+	"sort"
+)
+
+// Per-download option keys. These match the JSON names of the
+// corresponding DownloadOptions fields.
+const (
+	OptHeaders                = "headers"
+	OptReferer                = "referer"
+	OptUserAgent              = "userAgent"
+	OptSelectedFiles          = "selectedFiles"
+	OptSplit                  = "split"
+	OptMaxConnectionPerServer = "maxConnectionPerServer"
+	OptConnectTimeout         = "connectTimeout"
+	OptMaxTries               = "maxTries"
+	OptCheckCertificate       = "checkCertificate"
+	OptMaxDownloadSpeed       = "maxDownloadSpeed"
+	OptMaxUploadSpeed         = "maxUploadSpeed"
+	OptLowestSpeedLimit       = "lowestSpeedLimit"
+	OptDiskCache              = "diskCache"
+	OptMinSplitSize           = "minSplitSize"
+	OptPreAllocateSpace       = "preAllocateSpace"
+	OptProxies                = "proxies"
+	OptStreamToDestination    = "streamToDestination"
+)
+
+// Capabilities describes what a download engine actually honors, so callers
+// can tell which options would be silently dropped.
+type Capabilities struct {
+	Engine    string   `json:"engine" example:"aria2"`
+	Protocols []string `json:"protocols" example:"http,https,magnet"`
+
+	// Options lists the per-download DownloadOptions keys honored by Add
+	Options []string `json:"options"`
+
+	// LiveOptions lists the settings (section.field) applied by Configure
+	// without restarting the engine
+	LiveOptions []string `json:"liveOptions"`
+
+	Torrent TorrentCapabilities `json:"torrent"`
+
+	// Streaming reports whether the engine can write a download straight to
+	// a remote destination without staging it on local disk
+	Streaming bool `json:"streaming"`
+}
+
+type TorrentCapabilities struct {
+	Magnets       bool `json:"magnets"`
+	TorrentFiles  bool `json:"torrentFiles"`
+	FileSelection bool `json:"fileSelection"`
+	Seeding       bool `json:"seeding"`
+	PeerDetails   bool `json:"peerDetails"`
+}
+
+// SupportsOption reports whether the per-download option key is honored
+func (c Capabilities) SupportsOption(key string) bool {
+	return slices.Contains(c.Options, key)
+}
+
+// SupportsProtocol reports whether the engine can fetch the given scheme
+func (c Capabilities) SupportsProtocol(protocol string) bool {
+	return slices.Contains(c.Protocols, protocol)
+}
+
+// Merge returns the union of two capability sets under the given engine name
+func (c Capabilities) Merge(name string, other Capabilities) Capabilities {
+	return Capabilities{
+		Engine:      name,
+		Protocols:   union(c.Protocols, other.Protocols),
+		Options:     union(c.Options, other.Options),
+		LiveOptions: union(c.LiveOptions, other.LiveOptions),
+		Torrent: TorrentCapabilities{
+			Magnets:       c.Torrent.Magnets || other.Torrent.Magnets,
+			TorrentFiles:  c.Torrent.TorrentFiles || other.Torrent.TorrentFiles,
+			FileSelection: c.Torrent.FileSelection || other.Torrent.FileSelection,
+			Seeding:       c.Torrent.Seeding || other.Torrent.Seeding,
+			PeerDetails:   c.Torrent.PeerDetails || other.Torrent.PeerDetails,
+		},
+		Streaming: c.Streaming || other.Streaming,
+	}
+}
+
+// RequestedOptions returns the keys of the per-download overrides that are set
+func RequestedOptions(opts DownloadOptions) []string {
+	var keys []string
+	add := func(set bool, key string) {
+		if set {
+			keys = append(keys, key)
+		}
+	}
+
+	add(len(opts.Headers) > 0, OptHeaders)
+	add(opts.Referer != nil && *opts.Referer != "", OptReferer)
+	add(opts.UserAgent != nil && *opts.UserAgent != "", OptUserAgent)
+	add(len(opts.SelectedFiles) > 0, OptSelectedFiles)
+	add(opts.Split != nil, OptSplit)
+	add(opts.MaxConnectionPerServer != nil, OptMaxConnectionPerServer)
+	add(opts.ConnectTimeout != nil, OptConnectTimeout)
+	add(opts.MaxTries != nil, OptMaxTries)
+	add(opts.CheckCertificate != nil, OptCheckCertificate)
+	add(opts.MaxDownloadSpeed != nil, OptMaxDownloadSpeed)
+	add(opts.MaxUploadSpeed != nil, OptMaxUploadSpeed)
+	add(opts.LowestSpeedLimit != nil, OptLowestSpeedLimit)
+	add(opts.DiskCache != nil, OptDiskCache)
+	add(opts.MinSplitSize != nil, OptMinSplitSize)
+	add(opts.PreAllocateSpace != nil, OptPreAllocateSpace)
+	add(len(opts.Proxies) > 0, OptProxies)
+	add(opts.StreamToDestination, OptStreamToDestination)
+
+	return keys
+}
+
+// UnsupportedOptions returns the requested per-download options the engine
+// would silently drop
+func UnsupportedOptions(opts DownloadOptions, caps Capabilities) []string {
+	var dropped []string
+	for _, key := range RequestedOptions(opts) {
+		if !caps.SupportsOption(key) {
+			dropped = append(dropped, key)
+		}
+	}
+	return dropped
+}
+
+func union(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var out []string
+	for _, s := range append(slices.Clone(a), b...) {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
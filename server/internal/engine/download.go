@@ -69,6 +69,7 @@ type DownloadEngine interface {
 
 	// Meta
 	Version(ctx context.Context) (string, error)
+	Capabilities() Capabilities
 
 	// Events
 	OnProgress(handler func(id string, progress Progress))
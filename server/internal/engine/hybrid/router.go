@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"gravity/internal/engine"
+	"gravity/internal/engine/rclone"
 	"gravity/internal/logger"
 	"gravity/internal/model"
 
@@ -62,8 +63,24 @@ func (h *HybridRouter) Stop() error {
 	return nil
 }
 
-func (h *HybridRouter) Add(ctx context.Context, url string, opts engine.DownloadOptions) (string, error) {
+// route picks the engine a download would be sent to. An explicit
+// per-download engine wins over the configured preference, except for
+// rclone sources which only the native engine can read. Downloads streamed
+// to a remote also go to native unless an engine was picked explicitly.
+func (h *HybridRouter) route(url string, opts engine.DownloadOptions) string {
+	if rclone.IsRemoteSource(url) {
+		return "native"
+	}
+	if opts.StreamToDestination && opts.Engine == "" {
+		return "native"
+	}
+	if opts.Engine == "aria2" || opts.Engine == "native" {
+		return opts.Engine
+	}
+
 	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	pref := "aria2"
 	if h.settings != nil {
 		if strings.HasPrefix(url, "magnet:") || opts.TorrentData != "" {
@@ -72,7 +89,11 @@ func (h *HybridRouter) Add(ctx context.Context, url string, opts engine.Download
 			pref = h.settings.Download.PreferredEngine
 		}
 	}
-	h.mu.RUnlock()
+	return pref
+}
+
+func (h *HybridRouter) Add(ctx context.Context, url string, opts engine.DownloadOptions) (string, error) {
+	pref := h.route(url, opts)
 
 	var gid string
 	var err error
@@ -156,6 +177,23 @@ func (h *HybridRouter) Version(ctx context.Context) (string, error) {
 	return h.aria2.Version(ctx)
 }
 
+func (h *HybridRouter) Capabilities() engine.Capabilities {
+	return h.aria2.Capabilities().Merge("hybrid", h.native.Capabilities())
+}
+
+// CapabilitiesFor reports the capabilities of the engine the download would
+// be routed to
+func (h *HybridRouter) CapabilitiesFor(url string, opts engine.DownloadOptions) engine.Capabilities {
+	if h.route(url, opts) == "native" {
+		return h.native.Capabilities()
+	}
+	return h.aria2.Capabilities()
+}
+
+func (h *HybridRouter) GetCapabilities() []engine.Capabilities {
+	return []engine.Capabilities{h.aria2.Capabilities(), h.native.Capabilities()}
+}
+
 func (h *HybridRouter) GetVersions(ctx context.Context) (aria2 string, native string) {
 	aria2, _ = h.aria2.Version(ctx)
 	native, _ = h.native.Version(ctx)
@@ -206,12 +244,7 @@ func (h *HybridRouter) GetTorrentFiles(ctx context.Context, torrentBase64 string
 }
 
 func (h *HybridRouter) AddMagnetWithSelection(ctx context.Context, magnet string, selectedIndexes []string, opts engine.DownloadOptions) (string, error) {
-	h.mu.RLock()
-	pref := "aria2"
-	if h.settings != nil {
-		pref = h.settings.Download.PreferredMagnetEngine
-	}
-	h.mu.RUnlock()
+	pref := h.route(magnet, opts)
 
 	var gid string
 	var err error
@@ -15,8 +15,10 @@ import (
 
 	"gravity/internal/client"
 	"gravity/internal/engine"
+	"gravity/internal/engine/rclone"
 	"gravity/internal/logger"
 	"gravity/internal/model"
+	"gravity/internal/network"
 
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/metainfo"
@@ -27,6 +29,7 @@ import (
 	"github.com/rclone/rclone/fs/operations"
 	"go.uber.org/zap"
 	"golang.org/x/net/proxy"
+	"golang.org/x/time/rate"
 )
 
 type NativeEngine struct {
@@ -35,6 +38,7 @@ type NativeEngine struct {
 	cancel        context.CancelFunc
 	dataDir       string
 	storage       *DynamicStorage
+	bandwidth     *Bandwidth
 	logger        *zap.Logger
 
 	onProgress func(id string, progress engine.Progress)
@@ -54,6 +58,7 @@ type taskType string
 const (
 	taskTypeTorrent taskType = "torrent"
 	taskTypeRclone  taskType = "rclone"
+	taskTypeRemote  taskType = "remote" // Copy from an rclone remote or sftp/ftp URL
 )
 
 type task struct {
@@ -74,7 +79,11 @@ type task struct {
 	proxyUser     string
 	proxyPassword string
 
-	split int
+	split            int
+	connectTimeout   *int
+	checkCertificate *bool
+	limiter          *rate.Limiter // per-download bucket, nil if unlimited
+	infoHash         string        // hex infohash of a torrent, if known when added
 
 	lastRead    int64
 	lastWrite   int64
@@ -103,9 +112,10 @@ func (t *task) getStatus() string {
 
 func NewNativeEngine(dataDir string) *NativeEngine {
 	e := &NativeEngine{
-		dataDir: dataDir,
-		done:    make(chan struct{}),
-		logger:  logger.Component("NATIVE"),
+		dataDir:   dataDir,
+		bandwidth: NewBandwidth(),
+		done:      make(chan struct{}),
+		logger:    logger.Component("NATIVE"),
 	}
 	e.pollingCond = sync.NewCond(&e.mu)
 	return e
@@ -130,6 +140,12 @@ func (e *NativeEngine) Start(ctx context.Context) error {
 	}
 	cfg.ListenPort = listenPort
 
+	// Share the global buckets with the torrent client. They must be non-nil
+	// here to be adjustable later from Configure.
+	e.bandwidth.Configure(s)
+	cfg.DownloadRateLimiter = e.bandwidth.DownloadLimiter()
+	cfg.UploadRateLimiter = e.bandwidth.UploadLimiter()
+
 	// Use the downloads subdirectory
 	cfg.DataDir = filepath.Join(e.dataDir, ".metadata")
 	defaultDownloadsDir := filepath.Join(e.dataDir, "downloads")
@@ -137,20 +153,48 @@ func (e *NativeEngine) Start(ctx context.Context) error {
 	os.MkdirAll(cfg.DataDir, 0755)
 
 	// Use .metadata dir for completion DB
-	e.storage = NewDynamicStorage(defaultDownloadsDir, cfg.DataDir)
+	e.storage = NewDynamicStorage(defaultDownloadsDir, cfg.DataDir, e.bandwidth)
 	cfg.DefaultStorage = e.storage
 
-	if s != nil && len(s.Network.Proxies) > 0 {
-		proxyURL, err := url.Parse(s.Network.Proxies[0].URL)
-		if err == nil {
-			dialer, err := proxy.FromURL(proxyURL, proxy.Direct)
-			if err == nil {
-				cfg.HTTPProxy = http.ProxyURL(proxyURL)
-				cfg.TrackerDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-					return dialer.Dial(network, addr)
+	// Metainfo/webseed requests and tracker connections go through the
+	// magnet proxies. Picked per connection so health checks and settings
+	// changes apply without a restart.
+	pool := network.Default()
+	dialer := network.DefaultDialer()
+	cfg.HTTPDialContext = dialer.DialContext
+	cfg.HTTPProxy = func(req *http.Request) (*url.URL, error) {
+		if p := pool.Select(model.ProxyTypeMagnets, req.URL.String()); p != "" {
+			return url.Parse(p)
+		}
+		return nil, nil
+	}
+	cfg.TrackerDialContext = func(ctx context.Context, netw, addr string) (net.Conn, error) {
+		if p := pool.Select(model.ProxyTypeMagnets, addr); p != "" {
+			if proxyURL, err := url.Parse(p); err == nil {
+				// Only SOCKS proxies can carry raw tracker connections
+				if pd, err := proxy.FromURL(proxyURL, dialer); err == nil {
+					if cd, ok := pd.(proxy.ContextDialer); ok {
+						return cd.DialContext(ctx, netw, addr)
+					}
+					return pd.Dial(netw, addr)
 				}
 			}
 		}
+		return dialer.DialContext(ctx, netw, addr)
+	}
+
+	// Peer connections are made from the listen sockets, so binding those
+	// keeps torrent traffic on the configured interface. Applied on start.
+	cfg.ListenHost = func(netw string) string {
+		ip, err := dialer.BindIP(netw == "tcp6" || netw == "udp6")
+		if err != nil {
+			e.logger.Warn("torrent listener not bound", zap.Error(err))
+			return ""
+		}
+		if ip == nil {
+			return ""
+		}
+		return ip.String()
 	}
 
 	tc, err := torrent.NewClient(cfg)
@@ -160,6 +204,7 @@ func (e *NativeEngine) Start(ctx context.Context) error {
 	}
 	e.torrentClient = tc
 	go e.poll()
+	go e.watchSchedule()
 	return nil
 }
 
@@ -202,30 +247,38 @@ func (e *NativeEngine) Add(ctx context.Context, url string, opts engine.Download
 		headers:  opts.Headers,
 		modTime:  opts.ModTime,
 		done:     make(chan struct{}),
-		split:    *opts.Split,
+
+		connectTimeout:   opts.ConnectTimeout,
+		checkCertificate: opts.CheckCertificate,
 	}
 
 	if opts.Split != nil {
 		t.split = *opts.Split
 	}
+	// Torrents apply it to their piece writes, see DynamicStorage
+	if opts.MaxDownloadSpeed != nil && parseSpeed(*opts.MaxDownloadSpeed) != rate.Inf {
+		t.limiter = newLimiter(*opts.MaxDownloadSpeed)
+	}
 
 	if strings.HasPrefix(url, "magnet:") || strings.HasSuffix(url, ".torrent") || opts.TorrentData != "" {
 		t.taskType = taskTypeTorrent
 
-		// Register custom storage path
-		if opts.DownloadDir != "" {
-			if opts.TorrentData != "" {
-				mi, _ := metainfo.Load(strings.NewReader(opts.TorrentData))
-				if mi != nil {
-					e.storage.Register(mi.HashInfoBytes().HexString(), opts.DownloadDir)
-				}
-			} else if strings.HasPrefix(url, "magnet:") {
-				m, err := metainfo.ParseMagnetUri(url)
-				if err == nil {
-					e.storage.Register(m.InfoHash.HexString(), opts.DownloadDir)
-				}
+		// Register custom storage path and speed limit
+		if opts.TorrentData != "" {
+			mi, _ := metainfo.Load(strings.NewReader(opts.TorrentData))
+			if mi != nil {
+				t.infoHash = mi.HashInfoBytes().HexString()
+			}
+		} else if strings.HasPrefix(url, "magnet:") {
+			m, err := metainfo.ParseMagnetUri(url)
+			if err == nil {
+				t.infoHash = m.InfoHash.HexString()
 			}
 		}
+		if t.infoHash != "" {
+			e.storage.Register(t.infoHash, opts.DownloadDir)
+			e.throttleTorrent(t)
+		}
 
 		var dl *torrent.Torrent
 		var err error
@@ -283,8 +336,14 @@ func (e *NativeEngine) Add(ctx context.Context, url string, opts engine.Download
 		}(dl, id)
 
 		t.tDownload = dl
+	} else if rclone.IsRemoteSource(url) {
+		t.taskType = taskTypeRemote
+		taskCtx, cancel := context.WithCancel(e.ctx)
+		t.cancel = cancel
+		go e.runRemoteDownload(taskCtx, t)
 	} else {
 		t.taskType = taskTypeRclone
+		t.proxyURL = network.Default().SelectFor(opts.Proxies, model.ProxyTypeDownloads, url)
 		taskCtx, cancel := context.WithCancel(e.ctx)
 		t.cancel = cancel
 		go e.runRcloneDownload(taskCtx, t)
@@ -295,6 +354,34 @@ func (e *NativeEngine) Add(ctx context.Context, url string, opts engine.Download
 	return id, nil
 }
 
+// throttleTorrent gives the piece writes of a torrent a new context, so a
+// resumed torrent writes again after its pause cancelled the previous one
+func (e *NativeEngine) throttleTorrent(t *task) {
+	if t.infoHash == "" {
+		return
+	}
+	ctx, cancel := context.WithCancel(e.ctx)
+	t.cancel = cancel
+	e.storage.Throttle(ctx, t.infoHash, t.limiter)
+}
+
+// watchSchedule follows schedule windows opening and closing
+func (e *NativeEngine) watchSchedule() {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if limit, changed := e.bandwidth.ApplySchedule(now); changed {
+				e.logger.Info("download bandwidth limit changed", zap.String("limit", limit))
+			}
+		}
+	}
+}
+
 func (e *NativeEngine) runRcloneDownload(ctx context.Context, t *task) {
 	// Capture callbacks under lock for thread-safe access
 	e.mu.RLock()
@@ -346,9 +433,18 @@ func (e *NativeEngine) runRcloneDownload(ctx context.Context, t *task) {
 		return
 	}
 
+	checkCertificate := s.Download.CheckCertificate
+	if t.checkCertificate != nil {
+		checkCertificate = *t.checkCertificate
+	}
+	connectTimeout := s.Download.ConnectTimeout
+	if t.connectTimeout != nil && *t.connectTimeout > 0 {
+		connectTimeout = *t.connectTimeout
+	}
+
 	client := client.New(ctx, "", client.WithProxy(t.proxyURL),
-		client.WithInsecureSkipVerify(!s.Download.CheckCertificate),
-		client.WithConnectTimeout(time.Duration(s.Download.ConnectTimeout)*time.Second),
+		client.WithInsecureSkipVerify(!checkCertificate),
+		client.WithConnectTimeout(time.Duration(connectTimeout)*time.Second),
 	)
 
 	srcObj := NewHTTPObject(ctx,
@@ -358,6 +454,7 @@ func (e *NativeEngine) runRcloneDownload(ctx context.Context, t *task) {
 		WithRemote(t.filename),
 		WithModTime(*t.modTime),
 		WithClient(client),
+		WithLimiters(e.bandwidth.Chain(t.filename, t.limiter)),
 	)
 
 	destObj, err := operations.CopyURLMulti(accCtx, dstFs, t.filename, srcObj, false)
@@ -569,6 +666,10 @@ func (e *NativeEngine) Pause(ctx context.Context, id string) error {
 		for _, f := range t.tDownload.Files() {
 			f.SetPriority(torrent.PiecePriorityNone)
 		}
+		// Writes waiting on a bucket give up
+		if t.cancel != nil {
+			t.cancel()
+		}
 		t.setStatus("paused")
 	} else if t.cancel != nil {
 		// For rclone tasks, cancel is the only option as they can't be paused
@@ -590,6 +691,7 @@ func (e *NativeEngine) Resume(ctx context.Context, id string) error {
 		for _, f := range t.tDownload.Files() {
 			f.SetPriority(torrent.PiecePriorityNormal)
 		}
+		e.throttleTorrent(t)
 		t.setStatus("active")
 	}
 	// Rclone tasks cannot be resumed, need to restart
@@ -605,7 +707,14 @@ func (e *NativeEngine) Cancel(ctx context.Context, id string) error {
 	return nil
 }
 func (e *NativeEngine) Remove(ctx context.Context, id string) error {
-	e.activeTasks.Delete(id)
+	if val, ok := e.activeTasks.LoadAndDelete(id); ok {
+		if t := val.(*task); t.infoHash != "" {
+			if t.cancel != nil {
+				t.cancel()
+			}
+			e.storage.Unregister(t.infoHash)
+		}
+	}
 	return nil
 }
 
@@ -633,6 +742,8 @@ func (e *NativeEngine) Configure(ctx context.Context, s *model.Settings) error {
 	e.mu.Lock()
 	e.settings = s
 	e.mu.Unlock()
+
+	e.bandwidth.Configure(s)
 	return nil
 }
 func (e *NativeEngine) Version(ctx context.Context) (string, error) {
@@ -646,6 +757,39 @@ func (e *NativeEngine) Version(ctx context.Context) (string, error) {
 	}
 	return "native-hybrid-1.0", nil
 }
+func (e *NativeEngine) Capabilities() engine.Capabilities {
+	return engine.Capabilities{
+		Engine:    "native",
+		Protocols: []string{"http", "https", "magnet", "torrent", "sftp", "ftp", "ftps", "rclone"},
+		Options: []string{
+			engine.OptHeaders,
+			engine.OptSelectedFiles,
+			engine.OptSplit,
+			engine.OptConnectTimeout,
+			engine.OptCheckCertificate,
+			engine.OptMaxDownloadSpeed,
+			engine.OptProxies,
+			engine.OptStreamToDestination,
+		},
+		LiveOptions: []string{
+			"download.connectTimeout",
+			"download.checkCertificate",
+			"download.maxDownloadSpeed",
+			"download.maxUploadSpeed",
+			"automation.categories",
+			"network.proxies",
+			"network.dnsOverHttps",
+		},
+		Torrent: engine.TorrentCapabilities{
+			Magnets:       true,
+			TorrentFiles:  true,
+			FileSelection: true,
+			PeerDetails:   true,
+		},
+		Streaming: true,
+	}
+}
+
 func (e *NativeEngine) OnProgress(h func(string, engine.Progress)) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -14,6 +14,7 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/rest"
+	"golang.org/x/time/rate"
 )
 
 var (
@@ -63,6 +64,11 @@ func WithClient(client *client.Client) Option {
 		o.client = client
 	}
 }
+func WithLimiters(chain []*rate.Limiter) Option {
+	return func(o *HTTPObject) {
+		o.limiters = chain
+	}
+}
 
 type HTTPObject struct {
 	p        *fs.Pacer
@@ -73,6 +79,7 @@ type HTTPObject struct {
 	modTime  time.Time
 	mimeType string
 	retries  int
+	limiters []*rate.Limiter
 }
 
 func NewHTTPObject(ctx context.Context, opts ...Option) *HTTPObject {
@@ -131,7 +138,7 @@ func (o *HTTPObject) Open(ctx context.Context, options ...fs.OpenOption) (io.Rea
 	if err != nil {
 		return nil, fmt.Errorf("Open failed: %w", err)
 	}
-	return res.Body, nil
+	return newLimitedReader(ctx, res.Body, o.limiters), nil
 }
 
 func (o *HTTPObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
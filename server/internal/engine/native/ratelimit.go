@@ -0,0 +1,224 @@
+package native
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gravity/internal/model"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Large enough for the torrent client's read loop and webseed bodies
+	minLimiterBurst = 1 << 20
+	// How often the download limit is re-evaluated against the schedule
+	scheduleInterval = time.Minute
+)
+
+// Bandwidth is a hierarchical token bucket shared by every transfer of the
+// engine. A read is admitted by its per-download bucket, then its category
+// bucket, then the global one. Buckets are never replaced, only re-limited,
+// so in-flight transfers pick up new limits immediately.
+type Bandwidth struct {
+	mu sync.RWMutex
+
+	// Global buckets, also handed to the torrent client
+	download *rate.Limiter
+	upload   *rate.Limiter
+
+	categories map[string]*rate.Limiter // category ID -> bucket
+	extensions map[string]string        // extension -> category ID
+
+	settings      *model.Settings
+	downloadLimit string // Global download limit in effect
+}
+
+func NewBandwidth() *Bandwidth {
+	return &Bandwidth{
+		download:   rate.NewLimiter(rate.Inf, minLimiterBurst),
+		upload:     rate.NewLimiter(rate.Inf, minLimiterBurst),
+		categories: make(map[string]*rate.Limiter),
+		extensions: make(map[string]string),
+	}
+}
+
+// downloadLimitAt returns the global download limit in effect at now: the
+// active schedule rule's DownloadLimit if it sets a speed, otherwise
+// MaxDownloadSpeed. A rule that pauses downloads sets no speed.
+func downloadLimitAt(s *model.Settings, now time.Time) string {
+	if r := s.Automation.ActiveRule(now); r != nil && r.DownloadLimit != "" && r.DownloadLimit != "paused" {
+		return r.DownloadLimit
+	}
+	return s.Download.MaxDownloadSpeed
+}
+
+// Configure applies the global and per-category limits from settings
+func (b *Bandwidth) Configure(s *model.Settings) {
+	if s == nil {
+		return
+	}
+
+	setLimit(b.upload, s.Download.MaxUploadSpeed)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.settings = s
+	b.downloadLimit = downloadLimitAt(s, time.Now())
+	setLimit(b.download, b.downloadLimit)
+
+	// Removed categories keep their bucket (readers may still hold it) but
+	// stop limiting
+	for _, l := range b.categories {
+		l.SetLimit(rate.Inf)
+	}
+	b.extensions = make(map[string]string)
+
+	for _, c := range s.Automation.Categories {
+		l, ok := b.categories[c.ID]
+		if !ok {
+			l = rate.NewLimiter(rate.Inf, minLimiterBurst)
+			b.categories[c.ID] = l
+		}
+		setLimit(l, c.MaxDownloadSpeed)
+
+		for _, ext := range c.Extensions {
+			ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+			if _, taken := b.extensions[ext]; !taken {
+				b.extensions[ext] = c.ID
+			}
+		}
+	}
+}
+
+// ApplySchedule re-limits the global download bucket when a schedule window
+// opens or closes. It returns the new limit and whether it changed.
+func (b *Bandwidth) ApplySchedule(now time.Time) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.settings == nil {
+		return "", false
+	}
+	limit := downloadLimitAt(b.settings, now)
+	if limit == b.downloadLimit {
+		return limit, false
+	}
+	b.downloadLimit = limit
+	setLimit(b.download, limit)
+	return limit, true
+}
+
+// DownloadLimiter is the global download bucket
+func (b *Bandwidth) DownloadLimiter() *rate.Limiter {
+	return b.download
+}
+
+// UploadLimiter is the global upload bucket
+func (b *Bandwidth) UploadLimiter() *rate.Limiter {
+	return b.upload
+}
+
+// Chain returns the buckets a download of the given file passes through,
+// innermost first. own may be nil.
+func (b *Bandwidth) Chain(filename string, own *rate.Limiter) []*rate.Limiter {
+	var chain []*rate.Limiter
+	if own != nil {
+		chain = append(chain, own)
+	}
+
+	if category := b.Category(filename); category != nil {
+		chain = append(chain, category)
+	}
+	return append(chain, b.download)
+}
+
+// Category returns the bucket of the category the file belongs to by its
+// extension, or nil
+func (b *Bandwidth) Category(filename string) *rate.Limiter {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if id, ok := b.extensions[ext]; ok {
+		return b.categories[id]
+	}
+	return nil
+}
+
+// newLimiter creates a bucket for a speed string such as "10M" or "500K".
+// Empty or "0" means unlimited.
+func newLimiter(speed string) *rate.Limiter {
+	l := rate.NewLimiter(rate.Inf, minLimiterBurst)
+	setLimit(l, speed)
+	return l
+}
+
+func setLimit(l *rate.Limiter, speed string) {
+	limit := parseSpeed(speed)
+	if limit > minLimiterBurst {
+		l.SetBurst(int(limit))
+	} else {
+		l.SetBurst(minLimiterBurst)
+	}
+	l.SetLimit(limit)
+}
+
+func parseSpeed(speed string) rate.Limit {
+	if speed == "" || speed == "0" {
+		return rate.Inf
+	}
+	var size fs.SizeSuffix
+	if err := size.Set(speed); err != nil || size <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(size)
+}
+
+// limitedReader throttles reads through a chain of token buckets
+type limitedReader struct {
+	ctx   context.Context
+	rc    io.ReadCloser
+	chain []*rate.Limiter
+}
+
+func newLimitedReader(ctx context.Context, rc io.ReadCloser, chain []*rate.Limiter) io.ReadCloser {
+	if len(chain) == 0 {
+		return rc
+	}
+	return &limitedReader{ctx: ctx, rc: rc, chain: chain}
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	// Never ask a bucket for more than it can hold, whatever its limit
+	if len(p) > minLimiterBurst {
+		p = p[:minLimiterBurst]
+	}
+
+	n, err := r.rc.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+
+	// Innermost first, so a slow download doesn't hold tokens of the
+	// shared buckets while it waits on its own
+	for _, l := range r.chain {
+		if l.Limit() == rate.Inf {
+			continue
+		}
+		if werr := l.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *limitedReader) Close() error {
+	return r.rc.Close()
+}
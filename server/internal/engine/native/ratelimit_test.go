@@ -0,0 +1,110 @@
+package native
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gravity/internal/model"
+
+	"golang.org/x/time/rate"
+)
+
+func testBandwidthSettings() *model.Settings {
+	s := &model.Settings{}
+	s.Download.MaxDownloadSpeed = "10M"
+	s.Automation.Categories = []model.Category{
+		{ID: "movies", Extensions: []string{".MKV", "mp4"}, MaxDownloadSpeed: "2M"},
+		{ID: "music", Extensions: []string{"mp3"}},
+	}
+	return s
+}
+
+func TestBandwidthChain(t *testing.T) {
+	b := NewBandwidth()
+	b.Configure(testBandwidthSettings())
+	own := newLimiter("1M")
+
+	chain := b.Chain("Movie.mkv", own)
+	if len(chain) != 3 || chain[0] != own || chain[2] != b.DownloadLimiter() {
+		t.Fatalf("chain of %d buckets, want own, category, global", len(chain))
+	}
+	for i, want := range []rate.Limit{1 << 20, 2 << 20, 10 << 20} {
+		if got := chain[i].Limit(); got != want {
+			t.Errorf("bucket %d limit %v, want %v", i, got, want)
+		}
+	}
+
+	// Unlimited categories are still in the chain, other files skip them
+	if chain := b.Chain("song.mp3", nil); len(chain) != 2 || chain[0].Limit() != rate.Inf {
+		t.Errorf("music chain = %d buckets", len(chain))
+	}
+	if chain := b.Chain("disk.iso", nil); len(chain) != 1 || chain[0] != b.DownloadLimiter() {
+		t.Errorf("uncategorized chain = %d buckets", len(chain))
+	}
+
+	// Reconfiguring re-limits the buckets readers already hold
+	s := testBandwidthSettings()
+	s.Download.MaxDownloadSpeed = "0"
+	s.Automation.Categories = s.Automation.Categories[1:]
+	b.Configure(s)
+	if chain[1].Limit() != rate.Inf || chain[2].Limit() != rate.Inf {
+		t.Errorf("after reconfigure: category %v, global %v", chain[1].Limit(), chain[2].Limit())
+	}
+	if b.Category("Movie.mkv") != nil {
+		t.Error("removed category still matches")
+	}
+}
+
+func TestBandwidthSchedule(t *testing.T) {
+	s := testBandwidthSettings()
+	s.Automation.ScheduleEnabled = true
+	s.Automation.Rules = []model.ScheduleRule{
+		{Enabled: true, StartTime: "09:00", EndTime: "17:00", DownloadLimit: "500K"},
+		{Enabled: true, StartTime: "20:00", EndTime: "22:00", DownloadLimit: "paused"},
+	}
+	b := NewBandwidth()
+	b.Configure(s)
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	b.ApplySchedule(day)
+	steps := []struct {
+		at      time.Duration
+		limit   rate.Limit
+		changed bool
+	}{
+		{10 * time.Hour, 500 << 10, true},
+		{11 * time.Hour, 500 << 10, false},
+		{18 * time.Hour, 10 << 20, true},
+		{21 * time.Hour, 10 << 20, false}, // Pausing is not a speed
+	}
+	for _, step := range steps {
+		_, changed := b.ApplySchedule(day.Add(step.at))
+		if got := b.DownloadLimiter().Limit(); got != step.limit || changed != step.changed {
+			t.Errorf("at %v: limit %v changed %v, want %v %v", step.at, got, changed, step.limit, step.changed)
+		}
+	}
+}
+
+func TestLimitedPieceCancel(t *testing.T) {
+	l := rate.NewLimiter(1, minLimiterBurst)
+	l.WaitN(context.Background(), minLimiterBurst) // Drain the bucket
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &limitedPiece{ctx: ctx, chain: []*rate.Limiter{l}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.WriteAt(make([]byte, 1<<10), 0)
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("write went through an empty bucket")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write still blocked after its torrent was paused")
+	}
+}
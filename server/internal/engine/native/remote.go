@@ -0,0 +1,103 @@
+package native
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"gravity/internal/engine"
+	"gravity/internal/engine/rclone"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/sync"
+	"go.uber.org/zap"
+)
+
+// runRemoteDownload copies a file or folder from an rclone source into
+// t.dir, which may be a local directory or another remote. Remote to remote
+// copies are done server-side when both ends are the same backend.
+func (e *NativeEngine) runRemoteDownload(ctx context.Context, t *task) {
+	e.mu.RLock()
+	onError := e.onError
+	onProgress := e.onProgress
+	onComplete := e.onComplete
+	e.mu.RUnlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.logger.Error("engine panic during remote download", zap.Any("panic", r))
+			if onError != nil {
+				onError(t.id, fmt.Errorf("engine panic: %v", r))
+			}
+		}
+	}()
+
+	e.logger.Debug("starting remote download", zap.String("id", t.id), zap.String("dir", t.dir))
+
+	ctx, ci := fs.AddConfig(ctx)
+	if t.split > 0 {
+		ci.MultiThreadStreams = t.split
+	}
+
+	accCtx := accounting.WithStatsGroup(ctx, t.id)
+	t.stats = accounting.StatsGroup(accCtx, t.id)
+
+	finalPath, err := e.copyRemote(accCtx, t)
+	if err != nil {
+		e.logger.Error("remote download failed", zap.String("id", t.id), zap.Error(err))
+		if onError != nil {
+			onError(t.id, err)
+		}
+	} else {
+		e.logger.Debug("download complete", zap.String("path", finalPath))
+
+		if onProgress != nil {
+			downloaded := t.stats.GetBytes()
+			onProgress(t.id, engine.Progress{Downloaded: downloaded, Size: max(t.size, downloaded)})
+		}
+		if onComplete != nil {
+			onComplete(t.id, finalPath)
+		}
+	}
+
+	e.Remove(e.ctx, t.id)
+}
+
+func (e *NativeEngine) copyRemote(ctx context.Context, t *task) (string, error) {
+	srcFs, obj, err := rclone.OpenSource(ctx, t.url)
+	if err != nil {
+		return "", err
+	}
+
+	if obj != nil {
+		name := t.filename
+		if name == "" {
+			name = path.Base(obj.Remote())
+		}
+		dstFs, err := fs.NewFs(ctx, t.dir)
+		if err != nil {
+			return "", fmt.Errorf("failed to open destination: %w", err)
+		}
+		if _, err := operations.Copy(ctx, dstFs, nil, name, obj); err != nil {
+			return "", err
+		}
+		return fspath.JoinRootPath(t.dir, name), nil
+	}
+
+	name := t.filename
+	if name == "" {
+		name = path.Base(srcFs.Root())
+	}
+	dst := fspath.JoinRootPath(t.dir, name)
+	dstFs, err := fs.NewFs(ctx, dst)
+	if err != nil {
+		return "", fmt.Errorf("failed to open destination: %w", err)
+	}
+	if err := sync.CopyDir(ctx, dstFs, srcFs, true); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
@@ -2,19 +2,31 @@ package native
 
 import (
 	"context"
+	"io"
 	"sync"
 
+	g "github.com/anacrolix/generics"
 	"github.com/anacrolix/torrent/metainfo"
 	"github.com/anacrolix/torrent/storage"
+	"golang.org/x/time/rate"
 )
 
 type DynamicStorage struct {
 	baseDir    string
 	completion storage.PieceCompletion
+	bandwidth  *Bandwidth
 	dirMap     sync.Map // map[string]string (HashHex -> Directory)
+	throttles  sync.Map // map[string]*pieceThrottle (HashHex -> throttle)
 }
 
-func NewDynamicStorage(baseDir, metadataDir string) *DynamicStorage {
+// pieceThrottle is a torrent's own bucket and the context its piece writes
+// wait in, cancelled when the torrent is paused or removed
+type pieceThrottle struct {
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+func NewDynamicStorage(baseDir, metadataDir string, bandwidth *Bandwidth) *DynamicStorage {
 	// Initialize global completion storage in metadata dir
 	// This prevents .torrent.db files appearing in download directories
 	pc, err := storage.NewDefaultPieceCompletionForDir(metadataDir)
@@ -26,6 +38,7 @@ func NewDynamicStorage(baseDir, metadataDir string) *DynamicStorage {
 	return &DynamicStorage{
 		baseDir:    baseDir,
 		completion: pc,
+		bandwidth:  bandwidth,
 	}
 }
 
@@ -36,6 +49,17 @@ func (s *DynamicStorage) Register(infoHash string, dir string) {
 	}
 }
 
+// Throttle sets the context and, if not nil, the own bucket that piece
+// writes of an infohash wait in
+func (s *DynamicStorage) Throttle(ctx context.Context, infoHash string, limiter *rate.Limiter) {
+	s.throttles.Store(infoHash, &pieceThrottle{ctx: ctx, limiter: limiter})
+}
+
+// Unregister forgets the throttle of an infohash once its download is gone
+func (s *DynamicStorage) Unregister(infoHash string) {
+	s.throttles.Delete(infoHash)
+}
+
 func (s *DynamicStorage) OpenTorrent(ctx context.Context, info *metainfo.Info, infoHash metainfo.Hash) (storage.TorrentImpl, error) {
 	dir := s.baseDir
 
@@ -51,7 +75,95 @@ func (s *DynamicStorage) OpenTorrent(ctx context.Context, info *metainfo.Info, i
 	}
 
 	// OpenTorrent on the configured file client
-	return storage.NewFileOpts(opts).OpenTorrent(ctx, info, infoHash)
+	impl, err := storage.NewFileOpts(opts).OpenTorrent(ctx, info, infoHash)
+	if err != nil {
+		return impl, err
+	}
+
+	// The client-wide buckets throttle the peer connections; a download's
+	// own bucket and its category's are applied where its pieces are written
+	hash := infoHash.HexString()
+	name := largestFile(info)
+	wrap := func(piece storage.PieceImpl, length int64) storage.PieceImpl {
+		ctx := context.Background()
+		var chain []*rate.Limiter
+		if val, ok := s.throttles.Load(hash); ok {
+			th := val.(*pieceThrottle)
+			ctx = th.ctx
+			if th.limiter != nil {
+				chain = append(chain, th.limiter)
+			}
+		}
+		if s.bandwidth != nil {
+			if category := s.bandwidth.Category(name); category != nil {
+				chain = append(chain, category)
+			}
+		}
+		if len(chain) == 0 {
+			return piece
+		}
+		return &limitedPiece{PieceImpl: piece, ctx: ctx, chain: chain, length: length}
+	}
+	if piece := impl.Piece; piece != nil {
+		impl.Piece = func(p metainfo.Piece) storage.PieceImpl {
+			return wrap(piece(p), p.Length())
+		}
+	}
+	if piece := impl.PieceWithHash; piece != nil {
+		impl.PieceWithHash = func(p metainfo.Piece, hash g.Option[[]byte]) storage.PieceImpl {
+			return wrap(piece(p, hash), p.Length())
+		}
+	}
+	return impl, nil
+}
+
+// largestFile is the path of the biggest file of a torrent, which decides
+// its category
+func largestFile(info *metainfo.Info) string {
+	name, size := info.Name, int64(-1)
+	for _, f := range info.UpvertedFiles() {
+		if f.Length > size {
+			name, size = f.DisplayPath(info), f.Length
+		}
+	}
+	return name
+}
+
+// limitedPiece waits on a torrent's buckets before each write to a piece
+type limitedPiece struct {
+	storage.PieceImpl
+	ctx    context.Context
+	chain  []*rate.Limiter
+	length int64
+}
+
+func (p *limitedPiece) WriteAt(b []byte, off int64) (int, error) {
+	// Chunks are far smaller than a bucket's burst, but never ask for more
+	for _, l := range p.chain {
+		for rest := len(b); rest > 0 && l.Limit() != rate.Inf; {
+			n := min(rest, l.Burst())
+			if err := l.WaitN(p.ctx, n); err != nil {
+				return 0, err
+			}
+			rest -= n
+		}
+	}
+	return p.PieceImpl.WriteAt(b, off)
+}
+
+// WriteTo and Flush keep the optional interfaces of the wrapped piece
+func (p *limitedPiece) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := p.PieceImpl.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, io.NewSectionReader(p.PieceImpl, 0, p.length))
+}
+
+func (p *limitedPiece) Flush() error {
+	if f, ok := p.PieceImpl.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
 }
 
 func (s *DynamicStorage) Close() error {
@@ -3,6 +3,7 @@ package engine
 import (
 	"fmt"
 	"gravity/internal/model"
+	"maps"
 	"strings"
 	"time"
 )
@@ -57,6 +58,9 @@ type DownloadOptions struct {
 	RemoveLocal       *bool `json:"removeLocal,omitempty"`       // Remove local file after upload
 	ConcurrentUploads *int  `json:"concurrentUploads,omitempty"` // Parallel uploads
 
+	// Write into Destination directly; DownloadDir is then the destination
+	StreamToDestination bool `json:"streamToDestination,omitempty"`
+
 	// Proxies
 	Proxies []model.Proxy `json:"proxies,omitempty"`
 
@@ -65,6 +69,9 @@ type DownloadOptions struct {
 
 	// Metadata
 	ModTime *time.Time `json:"modTime,omitempty"`
+
+	// Values for the {variables} in DownloadDir and Destination
+	TemplateVars map[string]string `json:"-"`
 }
 
 // EffectiveOptions holds the resolved/final values after merging with global settings
@@ -108,10 +115,14 @@ func FromModel(d *model.Download) DownloadOptions {
 		Split:         d.Split,
 		RemoveLocal:   d.RemoveLocal,
 
+		StreamToDestination: d.WritesToDestination(),
+
 		// Per-download overrides
 		MaxDownloadSpeed: d.MaxDownloadSpeed,
 		ConnectTimeout:   d.ConnectTimeout,
 		MaxTries:         d.MaxTries,
+
+		TemplateVars: TemplateVars(d),
 	}
 
 	if len(d.Proxies) > 0 {
@@ -148,6 +159,8 @@ func (r *OptionResolver) Resolve(opts DownloadOptions) EffectiveOptions {
 			Size:          opts.Size,
 			Engine:        opts.Engine,
 
+			StreamToDestination: opts.StreamToDestination,
+
 			// Resolve all overrideable fields
 			Split:                  derefInt(opts.Split, ds.Split, 8),
 			MaxConnectionPerServer: derefInt(opts.MaxConnectionPerServer, ds.MaxConnectionPerServer, 8),
@@ -175,13 +188,19 @@ func (r *OptionResolver) Resolve(opts DownloadOptions) EffectiveOptions {
 			Proxies: opts.Proxies,
 
 			// Metadata
-			ModTime: opts.ModTime,
+			ModTime:      opts.ModTime,
+			TemplateVars: opts.TemplateVars,
 		},
 	}
 
+	// Expand path templates
+	vars := r.templateVars(opts.Filename, opts.TemplateVars)
+	effective.DownloadDir = ExpandTemplate(effective.DownloadDir, vars)
+	effective.Destination = ExpandTemplate(effective.Destination, vars)
+
 	// Resolve local path
 	if effective.DownloadDir == "" {
-		effective.LocalPath = ds.DownloadDir
+		effective.LocalPath = ExpandTemplate(ds.DownloadDir, vars)
 	} else {
 		effective.LocalPath = effective.DownloadDir
 	}
@@ -197,6 +216,34 @@ func (r *OptionResolver) Resolve(opts DownloadOptions) EffectiveOptions {
 	return effective
 }
 
+// ExpandPath expands the path template tmpl for download d
+func (r *OptionResolver) ExpandPath(tmpl string, d *model.Download) string {
+	return ExpandTemplate(tmpl, r.templateVars(d.Filename, TemplateVars(d)))
+}
+
+// Preview shows how tmpl expands for download d
+func (r *OptionResolver) Preview(tmpl string, d *model.Download) TemplatePreview {
+	vars := r.templateVars(d.Filename, TemplateVars(d))
+	return TemplatePreview{
+		Template:  tmpl,
+		Path:      ExpandTemplate(tmpl, vars),
+		Variables: vars,
+		Unknown:   UnknownTemplateVars(tmpl),
+	}
+}
+
+// templateVars adds the category of filename to vars
+func (r *OptionResolver) templateVars(filename string, vars map[string]string) map[string]string {
+	out := make(map[string]string, len(vars)+1)
+	maps.Copy(out, vars)
+	if _, ok := out["category"]; !ok && r.settings != nil {
+		if c := r.settings.Automation.CategoryFor(filename); c != nil {
+			out["category"] = c.Name
+		}
+	}
+	return out
+}
+
 // Validate checks if the resolved options are valid
 func (r *OptionResolver) Validate(opts EffectiveOptions) error {
 	return opts.Validate()
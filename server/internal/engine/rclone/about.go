@@ -0,0 +1,36 @@
+package rclone
+
+import (
+	"context"
+	"time"
+
+	"gravity/internal/engine"
+	"gravity/internal/model"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// About returns the quota of remote as its backend reports it
+func (e *Engine) About(ctx context.Context, remote string) (*model.RemoteUsage, error) {
+	f, err := fs.NewFs(ctx, fsPath(remote, ""))
+	if err != nil {
+		return nil, err
+	}
+	about := f.Features().About
+	if about == nil {
+		return nil, engine.ErrUsageUnsupported
+	}
+	u, err := about(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RemoteUsage{
+		Remote:    remote,
+		Total:     u.Total,
+		Used:      u.Used,
+		Free:      u.Free,
+		Trashed:   u.Trashed,
+		Objects:   u.Objects,
+		UpdatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,271 @@
+package rclone
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gravity/internal/model"
+	"gravity/internal/network"
+
+	"github.com/rclone/rclone/fs"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// How often the upload limit is re-evaluated against the schedule rules
+	uploadLimitInterval = time.Minute
+	// Smallest burst of a bandwidth bucket, large enough for one read of
+	// any backend
+	minBucketBurst = 1 << 20
+)
+
+// uploadLimitAt returns the upload bandwidth in effect at now: the active
+// schedule rule's UploadLimit if it sets one, otherwise UploadBandwidth.
+// An empty result or "0" means unlimited.
+func uploadLimitAt(settings *model.Settings, now time.Time) string {
+	if r := settings.Automation.ActiveRule(now); r != nil && r.UploadLimit != "" {
+		return r.UploadLimit
+	}
+	return settings.Upload.UploadBandwidth
+}
+
+// applyUploadLimit re-limits the engine's upload bucket, which every upload
+// job passes through after its own. rclone's process-wide token bucket is
+// left alone, so downloads and API calls keep full speed.
+func (e *Engine) applyUploadLimit(now time.Time) {
+	e.mu.Lock()
+	if e.settings == nil {
+		e.mu.Unlock()
+		return
+	}
+	limit := uploadLimitAt(e.settings, now)
+	changed := limit != e.uploadLimit
+	e.uploadLimit = limit
+	e.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	bw, err := parseBandwidth(limit)
+	if err != nil {
+		e.logger.Warn("invalid upload bandwidth", zap.String("limit", limit), zap.Error(err))
+		return
+	}
+	setBucketLimit(e.uploadBucket, bw)
+	e.logger.Info("upload bandwidth limit changed", zap.String("limit", limit))
+}
+
+// watchUploadLimit follows schedule windows opening and closing
+func (e *Engine) watchUploadLimit() {
+	ticker := time.NewTicker(uploadLimitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.appCtx.Done():
+			return
+		case now := <-ticker.C:
+			e.applyUploadLimit(now)
+		}
+	}
+}
+
+// uploadContext applies the upload proxy and the remote's transfer settings
+// to ctx
+func (e *Engine) uploadContext(ctx context.Context, remoteName string) context.Context {
+	profile := e.uploadProfile(remoteName)
+	ctx, ci := fs.AddConfig(ctx)
+
+	// Route the transfer through the upload proxies, matched on the
+	// destination remote name
+	if proxyURL := network.Default().Select(model.ProxyTypeUploads, remoteName); proxyURL != "" {
+		ci.Proxy = proxyURL
+	}
+
+	if profile.Transfers > 0 {
+		ci.Transfers = profile.Transfers
+	}
+	return ctx
+}
+
+// uploadBuckets returns the buckets the transfers of a job to remoteName
+// pass through: a bucket of its own for the profile's bandwidth, shared by
+// all its parallel transfers, then the engine's upload bucket
+func (e *Engine) uploadBuckets(remoteName string) []*rate.Limiter {
+	var buckets []*rate.Limiter
+	profile := e.uploadProfile(remoteName)
+	bw, err := parseBandwidth(profile.Bandwidth)
+	if err != nil {
+		e.logger.Warn("invalid remote bandwidth", zap.String("remote", remoteName), zap.Error(err))
+	} else if bw > 0 {
+		bucket := rate.NewLimiter(rate.Inf, minBucketBurst)
+		setBucketLimit(bucket, bw)
+		buckets = append(buckets, bucket)
+	}
+	return append(buckets, e.uploadBucket)
+}
+
+// newDestinationFs opens remoteName:remotePath with the profile's chunk size
+// and upload concurrency as backend overrides. Backends without those options
+// ignore them; if a backend rejects the values the remote is opened with its
+// own configuration instead.
+func (e *Engine) newDestinationFs(ctx context.Context, remoteName, remotePath string) (fs.Fs, error) {
+	if remoteName == "" {
+		// A local folder
+		return fs.NewFs(ctx, remotePath)
+	}
+	if _, ok := localRoot(remoteName); ok {
+		return fs.NewFs(ctx, fsPath(remoteName, remotePath))
+	}
+	params := backendOverrides(e.uploadProfile(remoteName))
+	if len(params) > 0 && !strings.HasPrefix(remoteName, ":") {
+		f, err := fs.NewFs(ctx, remoteName+","+strings.Join(params, ",")+":"+remotePath)
+		if err == nil {
+			return f, nil
+		}
+		e.logger.Warn("remote rejected upload profile, using its defaults",
+			zap.String("remote", remoteName), zap.Strings("overrides", params), zap.Error(err))
+	}
+	return fs.NewFs(ctx, remoteName+":"+remotePath)
+}
+
+func (e *Engine) uploadProfile(remoteName string) model.RemoteUploadProfile {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.settings == nil {
+		return model.RemoteUploadProfile{Remote: remoteName}
+	}
+	return e.settings.Upload.ProfileFor(remoteName)
+}
+
+// backendOverrides returns the connection string parameters for profile
+func backendOverrides(profile model.RemoteUploadProfile) []string {
+	var params []string
+	if profile.ChunkSize != "" {
+		params = append(params, "chunk_size="+profile.ChunkSize)
+	}
+	if profile.UploadConcurrency > 0 {
+		params = append(params, "upload_concurrency="+strconv.Itoa(profile.UploadConcurrency))
+	}
+	return params
+}
+
+// limitSource throttles every read a job makes from src through buckets.
+// Reads stay unthrottled if src and dst are on the same remote, as wrapped
+// objects cannot be copied server-side.
+func limitSource(src, dst fs.Fs, buckets []*rate.Limiter) fs.Fs {
+	if len(buckets) == 0 || (dst != nil && src.Name() == dst.Name()) {
+		return src
+	}
+	f := &limitedFs{Fs: src, buckets: buckets}
+	// Recursive listings would hand out unwrapped objects
+	features := *src.Features()
+	features.ListR = nil
+	features.ListP = nil
+	f.features = &features
+	return f
+}
+
+// limitedFs wraps the objects of a source Fs in limitedObject
+type limitedFs struct {
+	fs.Fs
+	buckets  []*rate.Limiter
+	features *fs.Features
+}
+
+func (f *limitedFs) Features() *fs.Features {
+	return f.features
+}
+
+func (f *limitedFs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedObject{Object: o, buckets: f.buckets}, nil
+}
+
+func (f *limitedFs) List(ctx context.Context, dir string) (fs.DirEntries, error) {
+	entries, err := f.Fs.List(ctx, dir)
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = &limitedObject{Object: o, buckets: f.buckets}
+		}
+	}
+	return entries, err
+}
+
+type limitedObject struct {
+	fs.Object
+	buckets []*rate.Limiter
+}
+
+func (o *limitedObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	rc, err := o.Object.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedReader{ctx: ctx, rc: rc, buckets: o.buckets}, nil
+}
+
+// UnWrap returns the wrapped object, for backends that look through wrappers
+func (o *limitedObject) UnWrap() fs.Object {
+	return o.Object
+}
+
+// limitedReader waits on each bucket, innermost first, for the bytes read
+type limitedReader struct {
+	ctx     context.Context
+	rc      io.ReadCloser
+	buckets []*rate.Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > minBucketBurst {
+		p = p[:minBucketBurst]
+	}
+	n, err := r.rc.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+	for _, b := range r.buckets {
+		if b.Limit() == rate.Inf {
+			continue
+		}
+		if werr := b.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *limitedReader) Close() error {
+	return r.rc.Close()
+}
+
+// parseBandwidth parses a speed such as "10M". Empty or "0" is unlimited and
+// returns 0.
+func parseBandwidth(limit string) (fs.SizeSuffix, error) {
+	var bw fs.SizeSuffix
+	if limit == "" || limit == "0" {
+		return 0, nil
+	}
+	if err := bw.Set(limit); err != nil {
+		return 0, err
+	}
+	return max(bw, 0), nil
+}
+
+func setBucketLimit(l *rate.Limiter, bw fs.SizeSuffix) {
+	if bw <= 0 {
+		l.SetLimit(rate.Inf)
+		return
+	}
+	l.SetBurst(max(int(bw), minBucketBurst))
+	l.SetLimit(rate.Limit(bw))
+}
@@ -0,0 +1,74 @@
+package rclone
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gravity/internal/model"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/time/rate"
+)
+
+func TestUploadBuckets(t *testing.T) {
+	e := NewEngine(context.Background(), "")
+	settings := model.DefaultSettings()
+	settings.Upload.RemoteProfiles = []model.RemoteUploadProfile{{Remote: "gdrive", Transfers: 4, Bandwidth: "8M"}}
+	e.settings = settings
+
+	// The profile bandwidth is the job's, however many transfers it runs
+	buckets := e.uploadBuckets("gdrive")
+	if len(buckets) != 2 || buckets[0].Limit() != rate.Limit(8<<20) || buckets[1] != e.uploadBucket {
+		t.Fatalf("uploadBuckets(gdrive) = %v", buckets)
+	}
+	if buckets := e.uploadBuckets("other"); len(buckets) != 1 || buckets[0] != e.uploadBucket {
+		t.Errorf("uploadBuckets(other) = %v", buckets)
+	}
+	if e.uploadBuckets("gdrive")[0] == buckets[0] {
+		t.Error("jobs share a profile bucket")
+	}
+}
+
+func TestLimitSource(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.bin"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := fs.NewFs(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if limitSource(src, src, []*rate.Limiter{rate.NewLimiter(1, 1)}) != src {
+		t.Error("reads within one remote should not be wrapped")
+	}
+
+	bucket := rate.NewLimiter(1, minBucketBurst)
+	f := limitSource(src, nil, []*rate.Limiter{bucket})
+	if f.Features().ListR != nil {
+		t.Error("wrapped fs should list directory by directory")
+	}
+	entries, err := f.List(ctx, "")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List() = %v, %v", entries, err)
+	}
+	o, ok := entries[0].(*limitedObject)
+	if !ok {
+		t.Fatalf("List() returned %T", entries[0])
+	}
+	rc, err := o.Open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, err := io.ReadAll(rc); err != nil || string(b) != "hello" {
+		t.Fatalf("read %q, %v", b, err)
+	}
+	if tokens := bucket.Tokens(); tokens > minBucketBurst-4 {
+		t.Errorf("bucket has %v tokens, reads were not counted", tokens)
+	}
+}
@@ -3,6 +3,7 @@ package rclone
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -19,9 +20,11 @@ import (
 	rclConfig "github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/sync"
+	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/vfs"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 
 	_ "github.com/rclone/rclone/backend/all"
 )
@@ -39,6 +42,10 @@ type Engine struct {
 	cancel      context.CancelFunc
 	logger      *zap.Logger
 	configPath  string
+
+	settings     *model.Settings
+	uploadLimit  string        // Limit currently set on uploadBucket
+	uploadBucket *rate.Limiter // Shared by all upload jobs, see applyUploadLimit
 }
 
 type job struct {
@@ -51,6 +58,9 @@ type job struct {
 	lastRead    int64
 	lastChecked time.Time
 	speed       int64
+
+	onProgress func(engine.UploadProgress) // Overrides the engine callback
+	err        error                       // Set before done is closed
 }
 
 func NewEngine(ctx context.Context, configPath string) *Engine {
@@ -59,6 +69,8 @@ func NewEngine(ctx context.Context, configPath string) *Engine {
 		appCtx:     ctx,
 		logger:     logger.Component("RCLONE"),
 		configPath: configPath,
+
+		uploadBucket: rate.NewLimiter(rate.Inf, minBucketBurst),
 	}
 	e.pollingCond = stdSync.NewCond(&e.mu)
 	return e
@@ -70,7 +82,7 @@ func (e *Engine) Start(ctx context.Context) error {
 	ci.LogLevel = fs.LogLevelError
 
 	e.logger.Info("starting rclone engine")
-	if err := SyncGravityRoot(e.configPath, false); err != nil {
+	if err := e.syncRoot(false); err != nil {
 		return fmt.Errorf("failed to sync gravity root: %w", err)
 	}
 
@@ -83,10 +95,20 @@ func (e *Engine) Start(ctx context.Context) error {
 
 	e.appCtx, e.cancel = context.WithCancel(ctx)
 	go e.pollAccounting()
+	go e.watchUploadLimit()
 
 	return nil
 }
 
+// syncRoot rebuilds GravityRoot from the remotes and the local roots of the
+// current settings
+func (e *Engine) syncRoot(quiet bool) error {
+	e.mu.RLock()
+	roots := localRootsFor(e.settings)
+	e.mu.RUnlock()
+	return SyncGravityRoot(e.configPath, roots, quiet)
+}
+
 func (e *Engine) Stop() error {
 	if e.cancel != nil {
 		e.cancel()
@@ -134,6 +156,40 @@ func (e *Engine) List(ctx context.Context, virtualPath string) ([]engine.FileInf
 	return results, nil
 }
 
+// ListR lists the tree below virtualPath straight from the backend, skipping
+// the VFS directory cache. Backends without a recursive listing are walked.
+func (e *Engine) ListR(ctx context.Context, virtualPath string, fn func([]engine.FileInfo) error) error {
+	name, rpath := splitVirtual(virtualPath)
+	if name == "" {
+		return fmt.Errorf("%s is not on a remote", virtualPath)
+	}
+	f, err := fs.NewFs(ctx, fsPath(name, ""))
+	if err != nil {
+		return err
+	}
+
+	return walk.ListR(ctx, f, rpath, true, -1, walk.ListAll, func(entries fs.DirEntries) error {
+		results := make([]engine.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			info := engine.FileInfo{
+				Path:     path.Join("/", name, entry.Remote()),
+				Name:     path.Base(entry.Remote()),
+				ModTime:  entry.ModTime(ctx),
+				Type:     engine.FileTypeFile,
+				MimeType: fs.MimeTypeFromName(entry.Remote()),
+			}
+			if _, ok := entry.(fs.Directory); ok {
+				info.IsDir = true
+				info.Type = engine.FileTypeFolder
+			} else {
+				info.Size = entry.Size()
+			}
+			results = append(results, info)
+		}
+		return fn(results)
+	})
+}
+
 func (e *Engine) Stat(ctx context.Context, virtualPath string) (*engine.FileInfo, error) {
 	node, err := e.vfs.Stat(virtualPath)
 	if err != nil {
@@ -189,31 +245,79 @@ func (e *Engine) Delete(ctx context.Context, virtualPath string) error {
 	return node.Remove()
 }
 
+// RemoveAll deletes virtualPath with everything below it
+func (e *Engine) RemoveAll(ctx context.Context, virtualPath string) error {
+	node, err := e.vfs.Stat(virtualPath)
+	if err != nil {
+		return err
+	}
+	return node.RemoveAll()
+}
+
 func (e *Engine) Rename(ctx context.Context, virtualPath, newName string) error {
 	oldParent := path.Dir(virtualPath)
 	newPath := path.Join(oldParent, newName)
 	return e.vfs.Rename(virtualPath, newPath)
 }
 
+// MovePath moves a file or folder to dst, creating its parent folders. Within
+// one remote the backend moves it server-side where it can.
+func (e *Engine) MovePath(ctx context.Context, src, dst string) error {
+	if err := e.vfs.MkdirAll(path.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return e.vfs.Rename(src, dst)
+}
+
+// Put streams in to virtualPath through the VFS, replacing any existing file.
+// A partly written file is removed if the copy fails.
+func (e *Engine) Put(ctx context.Context, virtualPath string, in io.Reader) error {
+	fh, err := e.vfs.OpenFile(virtualPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fh, in)
+	if closeErr := fh.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if node, statErr := e.vfs.Stat(virtualPath); statErr == nil {
+			_ = node.Remove()
+		}
+		return err
+	}
+	return nil
+}
+
 // transferJob represents a file transfer operation
 type transferJob struct {
-	jobID     string
-	trackID   string
-	srcPath   string
-	dstPath   string
-	size      int64
-	operation func(ctx context.Context, srcFs, dstFs fs.Fs, srcRemote, dstRemote string) error
+	jobID   string
+	trackID string
+	srcPath string
+	dstPath string
+	size    int64
+	// srcPath and dstPath are rclone paths opened as is ("remote:path" or a
+	// local folder) rather than virtual "/remote/path" paths
+	direct bool
+	// configure adjusts the job context before the filesystems are opened
+	configure func(ctx context.Context) (context.Context, error)
+	// onProgress replaces the engine callbacks for this job; its outcome is
+	// read from the job once done is closed
+	onProgress func(engine.UploadProgress)
+	operation  func(ctx context.Context, srcFs, dstFs fs.Fs, srcRemote, dstRemote string) error
 }
 
 // runTransfer executes a transfer job with common setup/cleanup
-func (e *Engine) runTransfer(j *transferJob) (string, error) {
+func (e *Engine) runTransfer(j *transferJob) *job {
 	jobCtx, cancel := context.WithCancel(e.appCtx)
 	job := &job{
-		id:      j.jobID,
-		trackID: j.trackID,
-		size:    j.size,
-		done:    make(chan struct{}),
-		cancel:  cancel,
+		id:         j.jobID,
+		trackID:    j.trackID,
+		size:       j.size,
+		done:       make(chan struct{}),
+		cancel:     cancel,
+		onProgress: j.onProgress,
 	}
 
 	e.mu.Lock()
@@ -223,73 +327,78 @@ func (e *Engine) runTransfer(j *transferJob) (string, error) {
 	e.pollingCond.Broadcast()
 	e.mu.Unlock()
 
+	if j.onProgress != nil {
+		onError, onComplete = nil, nil
+	}
+
 	go func() {
 		defer close(job.done)
 		defer e.removeJob(j.jobID)
 
+		fail := func(err error) {
+			job.err = err
+			if onError != nil {
+				onError(j.trackID, err)
+			}
+		}
+
 		// Ensure stats are tracked for this group
 		jobCtx = accounting.WithStatsGroup(jobCtx, j.jobID)
 		job.stats = accounting.StatsGroup(jobCtx, j.jobID)
 
 		// Parse paths
-		srcClean := strings.TrimPrefix(j.srcPath, "/")
-		srcParts := strings.SplitN(srcClean, "/", 2)
-		srcRemoteName := strings.TrimSuffix(srcParts[0], ":")
-		srcRPath := ""
-		if len(srcParts) > 1 {
-			srcRPath = srcParts[1]
+		var srcRoot, srcRPath, dstRemoteName, dstRoot, dstRPath string
+		if j.direct {
+			srcRoot = j.srcPath
+			if name, rpath := splitFsPath(j.srcPath); name != "" {
+				if _, ok := localRoot(name); ok {
+					srcRoot = fsPath(name, rpath)
+				}
+			}
+			dstRemoteName, dstRoot = splitFsPath(j.dstPath)
+		} else {
+			var srcRemoteName string
+			srcRemoteName, srcRPath = splitVirtual(j.srcPath)
+			srcRoot = fsPath(srcRemoteName, "")
+			dstRemoteName, dstRPath = splitVirtual(j.dstPath)
 		}
 
-		dstClean := strings.TrimPrefix(j.dstPath, "/")
-		dstParts := strings.SplitN(dstClean, "/", 2)
-		dstRemoteName := strings.TrimSuffix(dstParts[0], ":")
-		dstRPath := ""
-		if len(dstParts) > 1 {
-			dstRPath = dstParts[1]
+		jobCtx = e.uploadContext(jobCtx, dstRemoteName)
+		if j.configure != nil {
+			var err error
+			if jobCtx, err = j.configure(jobCtx); err != nil {
+				fail(err)
+				return
+			}
 		}
 
 		// Create source and destination filesystems
-		srcFs, err := fs.NewFs(jobCtx, srcRemoteName+":")
+		srcFs, err := fs.NewFs(jobCtx, srcRoot)
 		if err != nil {
-			if onError != nil {
-				onError(j.trackID, fmt.Errorf("failed to create source fs: %w", err))
-			}
+			fail(fmt.Errorf("failed to create source fs: %w", err))
 			return
 		}
 
-		dstFs, err := fs.NewFs(jobCtx, dstRemoteName+":")
+		dstFs, err := e.newDestinationFs(jobCtx, dstRemoteName, dstRoot)
 		if err != nil {
-			if onError != nil {
-				onError(j.trackID, fmt.Errorf("failed to create destination fs: %w", err))
-			}
+			fail(fmt.Errorf("failed to create destination fs: %w", err))
 			return
 		}
+		srcFs = limitSource(srcFs, dstFs, e.uploadBuckets(dstRemoteName))
 
 		// Execute the operation
-		err = j.operation(jobCtx, srcFs, dstFs, srcRPath, dstRPath)
-
-		if err != nil {
-			if onError != nil {
-				onError(j.trackID, err)
-			}
-		} else {
-			if onComplete != nil {
-				onComplete(j.trackID)
-			}
+		if err := j.operation(jobCtx, srcFs, dstFs, srcRPath, dstRPath); err != nil {
+			fail(err)
+		} else if onComplete != nil {
+			onComplete(j.trackID)
 		}
 	}()
 
-	return j.jobID, nil
+	return job
 }
 
 func (e *Engine) Upload(ctx context.Context, src, dst string, opts engine.UploadOptions) (string, error) {
-	dstTrim := strings.TrimPrefix(dst, "/")
-	parts := strings.SplitN(dstTrim, "/", 2)
-	remoteName := strings.TrimSuffix(parts[0], ":")
-	remotePath := ""
-	if len(parts) > 1 {
-		remotePath = parts[1]
-	}
+	remoteName, remotePath := splitRemote(dst)
 
 	info, err := os.Stat(src)
 	if err != nil {
@@ -349,23 +458,32 @@ func (e *Engine) Upload(ctx context.Context, src, dst string, opts engine.Upload
 
 		jobCtx = accounting.WithStatsGroup(jobCtx, jobID)
 		j.stats = accounting.StatsGroup(jobCtx, jobID)
+		jobCtx = e.uploadContext(jobCtx, remoteName)
+		if opts.Force {
+			var ci *fs.ConfigInfo
+			jobCtx, ci = fs.AddConfig(jobCtx)
+			ci.IgnoreTimes = true
+		}
 
 		var err error
 		srcFs, fsErr := fs.NewFs(jobCtx, src)
 		if fsErr != nil {
 			err = fmt.Errorf("failed to create source fs: %w", fsErr)
 		} else {
-			dstFs, fsErr := fs.NewFs(jobCtx, remoteName+":"+remotePath)
+			dstFs, fsErr := e.newDestinationFs(jobCtx, remoteName, remotePath)
 			if fsErr != nil {
 				err = fmt.Errorf("failed to create destination fs: %w", fsErr)
-			} else if isDir {
-				err = sync.CopyDir(jobCtx, dstFs, srcFs, true)
 			} else {
-				srcObj, objErr := srcFs.NewObject(jobCtx, "")
-				if objErr != nil {
-					err = fmt.Errorf("failed to get source object: %w", objErr)
+				srcFs = limitSource(srcFs, dstFs, e.uploadBuckets(remoteName))
+				if isDir {
+					err = sync.CopyDir(jobCtx, dstFs, srcFs, true)
 				} else {
-					_, err = operations.Copy(jobCtx, dstFs, nil, srcObj.Remote(), srcObj)
+					srcObj, objErr := srcFs.NewObject(jobCtx, "")
+					if objErr != nil {
+						err = fmt.Errorf("failed to get source object: %w", objErr)
+					} else {
+						_, err = operations.Copy(jobCtx, dstFs, nil, srcObj.Remote(), srcObj)
+					}
 				}
 			}
 		}
@@ -449,12 +567,15 @@ func (e *Engine) pollAccounting() {
 			}
 			j.lastChecked = now
 
-			if onProgress != nil {
-				onProgress(j.trackID, engine.UploadProgress{
-					Uploaded: current,
-					Size:     j.size,
-					Speed:    j.speed,
-				})
+			progress := engine.UploadProgress{
+				Uploaded: current,
+				Size:     j.size,
+				Speed:    j.speed,
+			}
+			if j.onProgress != nil {
+				j.onProgress(progress)
+			} else if onProgress != nil {
+				onProgress(j.trackID, progress)
 			}
 		}
 	}
@@ -517,7 +638,7 @@ func (e *Engine) CreateRemote(ctx context.Context, name, rtype string, config ma
 	rclConfig.Data().SetValue(name, "type", rtype)
 	err := rclConfig.Data().Save()
 	if err == nil {
-		SyncGravityRoot(e.configPath, true)
+		e.syncRoot(true)
 	}
 	return err
 }
@@ -526,7 +647,7 @@ func (e *Engine) DeleteRemote(ctx context.Context, name string) error {
 	rclConfig.Data().DeleteSection(name)
 	err := rclConfig.Data().Save()
 	if err == nil {
-		SyncGravityRoot(e.configPath, true)
+		e.syncRoot(true)
 	}
 	return err
 }
@@ -536,6 +657,23 @@ func (e *Engine) TestRemote(ctx context.Context, name string) error {
 	return err
 }
 
+// PublicLink asks the backend holding virtualPath for a link of its own, or
+// with unlink removes it. Backends without public links return an error.
+func (e *Engine) PublicLink(ctx context.Context, virtualPath string, expire time.Duration, unlink bool) (string, error) {
+	name, remotePath := splitVirtual(virtualPath)
+	if name == "" {
+		return "", fmt.Errorf("%s is not on a remote", virtualPath)
+	}
+	f, err := fs.NewFs(ctx, fsPath(name, ""))
+	if err != nil {
+		return "", err
+	}
+	if f.Features().PublicLink == nil {
+		return "", fmt.Errorf("remote %s does not support public links", name)
+	}
+	return operations.PublicLink(ctx, f, remotePath, fs.Duration(expire), unlink)
+}
+
 func (e *Engine) Copy(ctx context.Context, srcPath, dstPath string) (string, error) {
 	jobID := "copy-" + strings.ReplaceAll(srcPath, "/", "-") + "-" + fmt.Sprint(time.Now().Unix())
 
@@ -553,7 +691,7 @@ func (e *Engine) Copy(ctx context.Context, srcPath, dstPath string) (string, err
 			_, err = operations.Copy(ctx, dstFs, nil, dstRemote, srcObj)
 			return err
 		},
-	})
+	}).id, nil
 }
 
 func (e *Engine) Move(ctx context.Context, srcPath, dstPath string) (string, error) {
@@ -573,7 +711,7 @@ func (e *Engine) Move(ctx context.Context, srcPath, dstPath string) (string, err
 			_, err = operations.Move(ctx, dstFs, nil, dstRemote, srcObj)
 			return err
 		},
-	})
+	}).id, nil
 }
 
 func (e *Engine) Cancel(ctx context.Context, jobID string) error {
@@ -592,6 +730,12 @@ func (e *Engine) Configure(ctx context.Context, settings *model.Settings) error
 		return nil
 	}
 
+	cfg := *settings
+	e.mu.Lock()
+	e.settings = &cfg
+	e.mu.Unlock()
+	e.applyUploadLimit(time.Now())
+
 	// 1. Cache Mode
 	if settings.Vfs.CacheMode != "" {
 		var mode vfscommon.CacheMode
@@ -662,6 +806,10 @@ func (e *Engine) Configure(ctx context.Context, settings *model.Settings) error
 }
 
 func (e *Engine) Restart(ctx context.Context) error {
+	e.mu.RLock()
+	roots := localRootsFor(e.settings)
+	e.mu.RUnlock()
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -672,7 +820,7 @@ func (e *Engine) Restart(ctx context.Context) error {
 	}
 
 	// Ensure GravityRoot exists before creating FS
-	if err := SyncGravityRoot(e.configPath, true); err != nil {
+	if err := SyncGravityRoot(e.configPath, roots, true); err != nil {
 		return fmt.Errorf("failed to sync gravity root during restart: %w", err)
 	}
 
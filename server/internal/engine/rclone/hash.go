@@ -0,0 +1,44 @@
+package rclone
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// HashTypes returns the names of the hashes the backend of remote supports
+func (e *Engine) HashTypes(ctx context.Context, remote string) ([]string, error) {
+	f, err := fs.NewFs(ctx, fsPath(remote, ""))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ht := range f.Hashes().Array() {
+		names = append(names, ht.String())
+	}
+	return names, nil
+}
+
+// Hash returns the hash named ht of the file at virtualPath. Backends that
+// do not store hashes, such as a local disk, read the whole file.
+func (e *Engine) Hash(ctx context.Context, virtualPath, ht string) (string, error) {
+	var t hash.Type
+	if err := t.Set(ht); err != nil {
+		return "", err
+	}
+	name, remotePath := splitVirtual(virtualPath)
+	if name == "" {
+		return "", fmt.Errorf("%s is not on a remote", virtualPath)
+	}
+	f, err := fs.NewFs(ctx, fsPath(name, ""))
+	if err != nil {
+		return "", err
+	}
+	obj, err := f.NewObject(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ctx, t)
+}
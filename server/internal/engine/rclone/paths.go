@@ -0,0 +1,82 @@
+package rclone
+
+import (
+	"maps"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rclone/rclone/fs/fspath"
+)
+
+// Local folders that are upstreams of GravityRoot, by name. They are not
+// rclone remotes, so "name:" does not open them; fsPath resolves them.
+var (
+	localRootsMu sync.RWMutex
+	localRootDir = map[string]string{}
+)
+
+func setLocalRoots(roots map[string]string) {
+	localRootsMu.Lock()
+	localRootDir = roots
+	localRootsMu.Unlock()
+}
+
+func localRoot(name string) (string, bool) {
+	localRootsMu.RLock()
+	defer localRootsMu.RUnlock()
+	dir, ok := localRootDir[name]
+	return dir, ok
+}
+
+// LocalRoots returns the local folders served in GravityRoot, by name
+func (e *Engine) LocalRoots() map[string]string {
+	localRootsMu.RLock()
+	defer localRootsMu.RUnlock()
+	return maps.Clone(localRootDir)
+}
+
+// fsPath returns the rclone path of rpath on remote name, or rpath itself
+// when there is no remote name. For a local root it
+// is a folder inside the root; rpath is cleaned first so it cannot climb out
+// of it.
+func fsPath(name, rpath string) string {
+	if name == "" {
+		return rpath
+	}
+	if dir, ok := localRoot(name); ok {
+		return filepath.Join(dir, filepath.FromSlash(path.Clean("/"+rpath)))
+	}
+	return name + ":" + rpath
+}
+
+// splitRemote splits "remote:path" or the virtual "/remote/path" form into
+// the remote name and the path on it
+func splitRemote(p string) (string, string) {
+	if name, rpath := splitFsPath(p); name != "" {
+		return name, rpath
+	}
+	return splitVirtual(p)
+}
+
+// splitVirtual splits a virtual "/remote/path" into the remote name and the
+// path on it
+func splitVirtual(p string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)
+	name := strings.TrimSuffix(parts[0], ":")
+	if len(parts) > 1 {
+		return name, parts[1]
+	}
+	return name, ""
+}
+
+// splitFsPath splits an rclone path into the remote name and the path on it.
+// Local paths have no remote name.
+func splitFsPath(p string) (string, string) {
+	parsed, err := fspath.Parse(p)
+	if err != nil || parsed.ConfigString == "" {
+		return "", p
+	}
+	return strings.TrimSuffix(parsed.ConfigString, ":"), parsed.Path
+}
@@ -2,9 +2,14 @@ package rclone
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
+	"gravity/internal/engine"
 	"gravity/internal/logger"
+	"gravity/internal/model"
 
 	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/configfile"
@@ -13,8 +18,10 @@ import (
 
 const GravityRootRemote = "GravityRoot"
 
-// SyncGravityRoot ensures the "GravityRoot" combine remote is configured with all current remotes
-func SyncGravityRoot(configPath string, quiet bool) error {
+// SyncGravityRoot ensures the "GravityRoot" combine remote is configured with
+// all current remotes and the local folders in roots. A local root named like
+// a remote is left out.
+func SyncGravityRoot(configPath string, roots []model.LocalRoot, quiet bool) error {
 	l := logger.Component("RCLONE")
 
 	// Install the config file handler and load from disk
@@ -45,6 +52,17 @@ func SyncGravityRoot(configPath string, quiet bool) error {
 		upstreams = append(upstreams, fmt.Sprintf("%s=%s:", r, r))
 	}
 
+	active := make(map[string]string)
+	for _, root := range roots {
+		if slices.Contains(remotes, root.Name) {
+			l.Warn("local root hidden by a remote with the same name", zap.String("name", root.Name))
+			continue
+		}
+		active[root.Name] = root.Path
+		upstreams = append(upstreams, quoteUpstream(root.Name+"="+root.Path))
+	}
+	setLocalRoots(active)
+
 	if len(upstreams) == 0 {
 		// If no remotes, use 'memory' backend to provide an empty valid root
 		// This prevents the engine from crashing on startup due to missing [GravityRoot] section
@@ -58,3 +76,59 @@ func SyncGravityRoot(configPath string, quiet bool) error {
 
 	return config.Data().Save()
 }
+
+// quoteUpstream quotes an upstream for the space separated list, which is
+// parsed as CSV
+func quoteUpstream(s string) string {
+	if !strings.ContainsAny(s, " \"") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+var nonNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// localRootsFor lists the local folders to show in GravityRoot: the download
+// dir as "local", absolute category folders outside it by category name, and
+// the extra roots from the VFS settings. Templated paths are cut at their
+// first variable.
+func localRootsFor(settings *model.Settings) []model.LocalRoot {
+	if settings == nil {
+		return nil
+	}
+
+	var roots []model.LocalRoot
+	seen := make(map[string]bool)
+	add := func(name, dir string) {
+		if name == "" || dir == "" || seen[name] || !filepath.IsAbs(dir) {
+			return
+		}
+		seen[name] = true
+		roots = append(roots, model.LocalRoot{Name: name, Path: filepath.Clean(dir)})
+	}
+
+	downloadDir := engine.TemplateRoot(settings.Download.DownloadDir)
+	add(model.LocalRootDownloads, downloadDir)
+
+	for _, c := range settings.Automation.Categories {
+		dir := engine.TemplateRoot(c.Path)
+		if !filepath.IsAbs(dir) || isWithin(dir, downloadDir) {
+			continue
+		}
+		add(strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(c.Name), "-"), "-"), dir)
+	}
+
+	for _, r := range settings.Vfs.LocalRoots {
+		add(r.Name, r.Path)
+	}
+	return roots
+}
+
+// isWithin reports whether dir is root or inside it
+func isWithin(dir, root string) bool {
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
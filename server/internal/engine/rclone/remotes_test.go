@@ -0,0 +1,66 @@
+package rclone
+
+import (
+	"testing"
+
+	"gravity/internal/model"
+)
+
+func TestLocalRootsFor(t *testing.T) {
+	settings := &model.Settings{
+		Download: model.DownloadSettings{DownloadDir: "/data/downloads/{year}"},
+		Automation: model.AutomationSettings{Categories: []model.Category{
+			{Name: "Movies", Path: "movies"},
+			{Name: "TV Shows", Path: "/mnt/tv"},
+			{Name: "Music", Path: "/data/downloads/music"},
+		}},
+		Vfs: model.VfsSettings{LocalRoots: []model.LocalRoot{
+			{Name: "media", Path: "/mnt/media/"},
+			{Name: "relative", Path: "media"},
+		}},
+	}
+
+	want := []model.LocalRoot{
+		{Name: "local", Path: "/data/downloads"},
+		{Name: "tv-shows", Path: "/mnt/tv"},
+		{Name: "media", Path: "/mnt/media"},
+	}
+	got := localRootsFor(settings)
+	if len(got) != len(want) {
+		t.Fatalf("localRootsFor() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("root %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFsPath(t *testing.T) {
+	setLocalRoots(map[string]string{"local": "/data/downloads"})
+	defer setLocalRoots(map[string]string{})
+
+	tests := []struct {
+		name, rpath, want string
+	}{
+		{"local", "movies/a.mkv", "/data/downloads/movies/a.mkv"},
+		{"local", "../../etc/passwd", "/data/downloads/etc/passwd"},
+		{"local", "", "/data/downloads"},
+		{"gdrive", "movies", "gdrive:movies"},
+		{"", "/tmp/file", "/tmp/file"},
+	}
+	for _, tt := range tests {
+		if got := fsPath(tt.name, tt.rpath); got != tt.want {
+			t.Errorf("fsPath(%q, %q) = %q, want %q", tt.name, tt.rpath, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteUpstream(t *testing.T) {
+	if got := quoteUpstream("local=/data/downloads"); got != "local=/data/downloads" {
+		t.Errorf("plain upstream was quoted: %q", got)
+	}
+	if got := quoteUpstream(`media=/mnt/my "media"`); got != `"media=/mnt/my ""media"""` {
+		t.Errorf("quoteUpstream() = %q", got)
+	}
+}
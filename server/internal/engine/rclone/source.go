@@ -0,0 +1,176 @@
+package rclone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	rclConfig "github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// URL schemes that are mapped to an on-the-fly rclone backend
+var sourceSchemes = map[string]string{
+	"sftp": "sftp",
+	"ftp":  "ftp",
+	"ftps": "ftp",
+}
+
+// SourceCredentials supplies the user and password stored for an sftp/ftp
+// source, so they never have to be kept in the download URL
+type SourceCredentials interface {
+	SourceLogin(rawURL string) (user, pass string, ok bool)
+}
+
+type sourceCredentialsHolder struct{ SourceCredentials }
+
+var sourceCredentials atomic.Pointer[sourceCredentialsHolder]
+
+// SetSourceCredentials installs the credential lookup used by SourcePath
+func SetSourceCredentials(c SourceCredentials) {
+	sourceCredentials.Store(&sourceCredentialsHolder{c})
+}
+
+// SourceInfo describes a remote download source
+type SourceInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	Files   int64
+}
+
+// IsRemoteSource reports whether src is a download source served by rclone:
+// "remote:path" on a configured remote, or an sftp/ftp URL
+func IsRemoteSource(src string) bool {
+	if u, err := url.Parse(src); err == nil && u.Host != "" {
+		_, ok := sourceSchemes[strings.ToLower(u.Scheme)]
+		return ok
+	}
+
+	name, _, ok := strings.Cut(src, ":")
+	if !ok || name == "" || strings.HasPrefix(src, ":") {
+		return false
+	}
+	return slices.Contains(rclConfig.GetRemoteNames(), name)
+}
+
+// SourcePath returns the rclone path for src. sftp/ftp URLs become
+// connection strings like ":sftp,host=example.com,user=bob:/data/file.iso".
+// A URL without a password takes the stored credentials for its host.
+func SourcePath(src string) (string, error) {
+	u, err := url.Parse(src)
+	if err != nil || u.Host == "" {
+		if !IsRemoteSource(src) {
+			return "", fmt.Errorf("not a configured remote: %s", src)
+		}
+		return src, nil
+	}
+
+	backend, ok := sourceSchemes[strings.ToLower(u.Scheme)]
+	if !ok {
+		return "", fmt.Errorf("unsupported source scheme: %s", u.Scheme)
+	}
+
+	params := []string{"host=" + quoteParam(u.Hostname())}
+	if port := u.Port(); port != "" {
+		params = append(params, "port="+port)
+	}
+
+	user := u.User.Username()
+	pass, hasPass := u.User.Password()
+	if h := sourceCredentials.Load(); !hasPass && h != nil && h.SourceCredentials != nil {
+		if storedUser, storedPass, ok := h.SourceLogin(src); ok && (user == "" || user == storedUser) {
+			user, pass, hasPass = storedUser, storedPass, true
+		}
+	}
+	if backend == "ftp" && user == "" {
+		user, pass, hasPass = "anonymous", "anonymous", true
+	}
+	if user != "" {
+		params = append(params, "user="+quoteParam(user))
+	}
+	if hasPass {
+		obscured, err := obscure.Obscure(pass)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, "pass="+quoteParam(obscured))
+	}
+	if strings.ToLower(u.Scheme) == "ftps" {
+		params = append(params, "tls=true")
+	}
+
+	return ":" + backend + "," + strings.Join(params, ",") + ":" + u.Path, nil
+}
+
+// OpenSource opens src. For a single file it returns the Fs of the parent
+// directory and the object; for a directory obj is nil.
+func OpenSource(ctx context.Context, src string) (f fs.Fs, obj fs.Object, err error) {
+	p, err := SourcePath(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err = fs.NewFs(ctx, p)
+	if errors.Is(err, fs.ErrorIsFile) {
+		_, leaf, splitErr := fspath.Split(p)
+		if splitErr != nil {
+			return nil, nil, splitErr
+		}
+		obj, err = f.NewObject(ctx, leaf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open source: %w", err)
+		}
+		return f, obj, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open source: %w", err)
+	}
+	return f, nil, nil
+}
+
+// StatSource returns the name and size of src, walking it when it is a
+// directory
+func StatSource(ctx context.Context, src string) (*SourceInfo, error) {
+	f, obj, err := OpenSource(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if obj != nil {
+		return &SourceInfo{
+			Name:    path.Base(obj.Remote()),
+			Size:    obj.Size(),
+			ModTime: obj.ModTime(ctx),
+			Files:   1,
+		}, nil
+	}
+
+	files, size, _, err := operations.Count(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source: %w", err)
+	}
+	name := path.Base(strings.TrimSuffix(f.Root(), "/"))
+	if name == "." || name == "/" || name == "" {
+		name = f.Name()
+	}
+	return &SourceInfo{Name: name, Size: size, IsDir: true, Files: files}, nil
+}
+
+// quoteParam quotes a connection string value if it contains characters
+// that would end it early
+func quoteParam(v string) string {
+	if !strings.ContainsAny(v, `,:"'`) {
+		return v
+	}
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
@@ -0,0 +1,122 @@
+package rclone
+
+import (
+	"context"
+	"fmt"
+
+	"gravity/internal/engine"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/sync"
+)
+
+// Sync runs a copy, move or sync between two rclone paths as a transfer
+// job, so it is tracked and cancelled like any other transfer
+func (e *Engine) Sync(ctx context.Context, opts engine.SyncOptions) (*engine.SyncStats, error) {
+	var op func(ctx context.Context, dst, src fs.Fs) error
+	switch opts.Mode {
+	case "copy":
+		op = func(ctx context.Context, dst, src fs.Fs) error { return sync.CopyDir(ctx, dst, src, false) }
+	case "move":
+		op = func(ctx context.Context, dst, src fs.Fs) error { return sync.MoveDir(ctx, dst, src, true, false) }
+	case "sync":
+		op = func(ctx context.Context, dst, src fs.Fs) error { return sync.Sync(ctx, dst, src, false) }
+	default:
+		return nil, fmt.Errorf("unknown sync mode %q", opts.Mode)
+	}
+
+	onProgress := opts.OnProgress
+	if onProgress == nil {
+		onProgress = func(engine.UploadProgress) {}
+	}
+
+	j := e.runTransfer(&transferJob{
+		jobID:      opts.TrackingID,
+		trackID:    opts.TrackingID,
+		srcPath:    opts.Src,
+		dstPath:    opts.Dst,
+		direct:     true,
+		onProgress: onProgress,
+		configure: func(ctx context.Context) (context.Context, error) {
+			return syncContext(ctx, opts)
+		},
+		operation: func(ctx context.Context, srcFs, dstFs fs.Fs, _, _ string) error {
+			return op(ctx, dstFs, srcFs)
+		},
+	})
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		j.cancel()
+		<-j.done
+	}
+
+	stats := &engine.SyncStats{}
+	if j.stats != nil {
+		stats.Bytes = j.stats.GetBytes()
+		stats.Transfers = j.stats.GetTransfers()
+		stats.Checks = j.stats.GetChecks()
+		stats.Deletes = j.stats.GetDeletes()
+		stats.Errors = j.stats.GetErrors()
+	}
+	return stats, j.err
+}
+
+// syncContext applies the dry-run flag and the filters of opts to ctx
+func syncContext(ctx context.Context, opts engine.SyncOptions) (context.Context, error) {
+	ctx, ci := fs.AddConfig(ctx)
+	ci.DryRun = opts.DryRun
+
+	fopt := filter.Opt
+	fopt.FilterRule = filterRules(opts.Include, opts.Exclude)
+	if err := setSize(&fopt.MinSize, opts.MinSize); err != nil {
+		return nil, fmt.Errorf("invalid min size: %w", err)
+	}
+	if err := setSize(&fopt.MaxSize, opts.MaxSize); err != nil {
+		return nil, fmt.Errorf("invalid max size: %w", err)
+	}
+	if err := setAge(&fopt.MinAge, opts.MinAge); err != nil {
+		return nil, fmt.Errorf("invalid min age: %w", err)
+	}
+	if err := setAge(&fopt.MaxAge, opts.MaxAge); err != nil {
+		return nil, fmt.Errorf("invalid max age: %w", err)
+	}
+
+	fi, err := filter.NewFilter(&fopt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return filter.ReplaceConfig(ctx, fi), nil
+}
+
+// filterRules turns include and exclude patterns into rclone filter rules.
+// Excludes win; with any includes, everything not included is skipped.
+func filterRules(include, exclude []string) []string {
+	var rules []string
+	for _, p := range exclude {
+		rules = append(rules, "- "+p)
+	}
+	for _, p := range include {
+		rules = append(rules, "+ "+p)
+	}
+	if len(include) > 0 {
+		rules = append(rules, "- **")
+	}
+	return rules
+}
+
+func setSize(dst *fs.SizeSuffix, v string) error {
+	if v == "" {
+		return nil
+	}
+	return dst.Set(v)
+}
+
+func setAge(dst *fs.Duration, v string) error {
+	if v == "" {
+		return nil
+	}
+	return dst.Set(v)
+}
@@ -0,0 +1,132 @@
+package rclone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"gravity/internal/engine"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// Files up to this size are downloaded and compared byte for byte when the
+// remote shares no hash type with the local disk
+const verifyDownloadMax = 32 << 20
+
+// Verification methods from strongest to weakest
+var verifyMethods = []string{"hash", "download", "modtime", "size"}
+
+// Verify compares the local file or folder src with its uploaded copy at
+// dst. Files are compared by hash where the remote supports a common hash
+// type, by content for small files, and by size and modification time
+// otherwise.
+func (e *Engine) Verify(ctx context.Context, src, dst string) (*engine.VerifyResult, error) {
+	remoteName, remotePath := splitRemote(dst)
+	ctx = e.uploadContext(ctx, remoteName)
+
+	var objs []fs.Object
+	srcFs, err := fs.NewFs(ctx, src)
+	switch {
+	case errors.Is(err, fs.ErrorIsFile):
+		obj, err := srcFs.NewObject(ctx, filepath.Base(src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open source: %w", err)
+		}
+		objs = append(objs, obj)
+		// dst names the file itself; look it up in its parent
+		remotePath = path.Dir(remotePath)
+		if remotePath == "." {
+			remotePath = ""
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to open source: %w", err)
+	default:
+		if err := operations.ListFn(ctx, srcFs, func(o fs.Object) {
+			objs = append(objs, o)
+		}); err != nil {
+			return nil, fmt.Errorf("failed to list source: %w", err)
+		}
+	}
+
+	result := &engine.VerifyResult{}
+	dstFs, err := fs.NewFs(ctx, fsPath(remoteName, remotePath))
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		for _, src := range objs {
+			result.Missing = append(result.Missing, src.Remote())
+		}
+		return result, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrorIsFile) {
+		return nil, fmt.Errorf("failed to open destination: %w", err)
+	}
+
+	for _, src := range objs {
+		dst, err := dstFs.NewObject(ctx, src.Remote())
+		if errors.Is(err, fs.ErrorObjectNotFound) || errors.Is(err, fs.ErrorDirNotFound) {
+			result.Missing = append(result.Missing, src.Remote())
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s on destination: %w", src.Remote(), err)
+		}
+
+		method, equal, err := compareObjects(ctx, src, dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s: %w", src.Remote(), err)
+		}
+		result.Files++
+		if !equal {
+			result.Mismatched = append(result.Mismatched, src.Remote())
+		}
+		if result.Method == "" || weaker(method, result.Method) {
+			result.Method = method
+		}
+	}
+	return result, nil
+}
+
+// compareObjects checks whether dst holds the same data as src and returns
+// the method used. Hash methods are reported by hash name.
+func compareObjects(ctx context.Context, src, dst fs.Object) (string, bool, error) {
+	if src.Size() >= 0 && dst.Size() >= 0 && src.Size() != dst.Size() {
+		return "size", false, nil
+	}
+
+	equal, ht, err := operations.CheckHashes(ctx, src, dst)
+	if err != nil {
+		return "", false, err
+	}
+	if ht != hash.None {
+		return ht.String(), equal, nil
+	}
+
+	if src.Size() >= 0 && src.Size() <= verifyDownloadMax {
+		equal, err := operations.CheckIdenticalDownload(ctx, src, dst)
+		return "download", equal, err
+	}
+
+	precision := max(src.Fs().Precision(), dst.Fs().Precision())
+	if precision == fs.ModTimeNotSupported {
+		return "size", true, nil
+	}
+	dt := src.ModTime(ctx).Sub(dst.ModTime(ctx))
+	return "modtime", dt.Abs() <= precision, nil
+}
+
+// weaker reports whether method a is a weaker check than b. Hash names
+// count as "hash".
+func weaker(a, b string) bool {
+	rank := func(m string) int {
+		if i := slices.Index(verifyMethods, m); i >= 0 {
+			return i
+		}
+		return 0
+	}
+	return rank(a) > rank(b)
+}
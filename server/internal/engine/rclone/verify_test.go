@@ -0,0 +1,163 @@
+package rclone
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+func TestSplitRemote(t *testing.T) {
+	tests := []struct {
+		in, name, path string
+	}{
+		{"gdrive:", "gdrive", ""},
+		{"gdrive:movies/2024", "gdrive", "movies/2024"},
+		{"/gdrive/movies/2024", "gdrive", "movies/2024"},
+		{"/gdrive", "gdrive", ""},
+	}
+	for _, tt := range tests {
+		name, path := splitRemote(tt.in)
+		if name != tt.name || path != tt.path {
+			t.Errorf("splitRemote(%q) = %q, %q; want %q, %q", tt.in, name, path, tt.name, tt.path)
+		}
+	}
+}
+
+func TestWeaker(t *testing.T) {
+	if !weaker("size", "md5") || !weaker("modtime", "download") {
+		t.Error("expected size and modtime to be weaker checks")
+	}
+	if weaker("sha1", "download") || weaker("md5", "sha1") {
+		t.Error("hash checks should not be weaker than download or each other")
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	src, dst := t.TempDir(), t.TempDir()
+	setLocalRoots(map[string]string{"backup": dst})
+	defer setLocalRoots(map[string]string{})
+	e := &Engine{}
+
+	writeFiles(t, src, map[string]string{
+		"show/e1.mkv":     "episode one",
+		"show/e2.mkv":     "episode two",
+		"show/e3.mkv":     "episode three",
+		"show/sub/e4.srt": "subtitles",
+	})
+	writeFiles(t, dst, map[string]string{
+		"show/e1.mkv":     "episode one",
+		"show/e2.mkv":     "episode 2!!",    // Same size, other content
+		"show/e3.mkv":     "episode three!", // Other size
+		"show/sub/e4.srt": "subtitles",
+	})
+
+	result, err := e.Verify(ctx, filepath.Join(src, "show"), "/backup/show")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 4 || len(result.Missing) != 0 {
+		t.Errorf("%d files compared, missing %v", result.Files, result.Missing)
+	}
+	// Each difference is recorded against its own file
+	slices.Sort(result.Mismatched)
+	if !slices.Equal(result.Mismatched, []string{"e2.mkv", "e3.mkv"}) {
+		t.Errorf("mismatched = %v", result.Mismatched)
+	}
+	// The size check of e3 is the weakest method used
+	if result.Method != "size" {
+		t.Errorf("method = %q", result.Method)
+	}
+
+	// A single file is looked up in the destination's parent
+	result, err = e.Verify(ctx, filepath.Join(src, "show", "e1.mkv"), "/backup/show/e1.mkv")
+	if err != nil || result.Files != 1 || len(result.Mismatched) != 0 || result.Method != "md5" {
+		t.Errorf("single file: %+v, %v", result, err)
+	}
+
+	os.Remove(filepath.Join(dst, "show", "e1.mkv"))
+	result, err = e.Verify(ctx, filepath.Join(src, "show"), "/backup/show")
+	if err != nil || !slices.Equal(result.Missing, []string{"e1.mkv"}) || result.Files != 3 {
+		t.Errorf("missing file: %+v, %v", result, err)
+	}
+
+	// Nothing was uploaded at all
+	result, err = e.Verify(ctx, filepath.Join(src, "show"), "/backup/other")
+	if err != nil || len(result.Missing) != 4 || result.Files != 0 {
+		t.Errorf("missing folder: %+v, %v", result, err)
+	}
+}
+
+// bareObject hides the hashes of an object and reports a size of its own,
+// like a large file on a remote without a common hash type
+type bareObject struct {
+	fs.Object
+	size int64
+}
+
+func (o bareObject) Size() int64 { return o.size }
+func (o bareObject) Fs() fs.Info { return bareInfo{o.Object.Fs()} }
+func (o bareObject) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+type bareInfo struct{ fs.Info }
+
+func (bareInfo) Hashes() hash.Set { return hash.Set(hash.None) }
+
+func TestCompareObjectsFallback(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.bin": "same", "b.bin": "same", "c.bin": "diff"})
+	f, err := fs.NewFs(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(name string, size int64) fs.Object {
+		obj, err := f.NewObject(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bareObject{Object: obj, size: size}
+	}
+
+	// Small files without a common hash are compared by content
+	if method, equal, err := compareObjects(ctx, open("a.bin", 4), open("c.bin", 4)); err != nil || method != "download" || equal {
+		t.Errorf("download: %s %v %v", method, equal, err)
+	}
+
+	// Large ones by size and modification time
+	big := int64(verifyDownloadMax + 1)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "a.bin"), old, old)
+	os.Chtimes(filepath.Join(dir, "b.bin"), old, old)
+	if method, equal, err := compareObjects(ctx, open("a.bin", big), open("b.bin", big)); err != nil || method != "modtime" || !equal {
+		t.Errorf("same modtime: %s %v %v", method, equal, err)
+	}
+	os.Chtimes(filepath.Join(dir, "b.bin"), time.Now(), time.Now())
+	if method, equal, _ := compareObjects(ctx, open("a.bin", big), open("b.bin", big)); method != "modtime" || equal {
+		t.Errorf("other modtime: %s %v", method, equal)
+	}
+	if method, equal, _ := compareObjects(ctx, open("a.bin", big), open("b.bin", big+1)); method != "size" || equal {
+		t.Errorf("other size: %s %v", method, equal)
+	}
+}
@@ -0,0 +1,146 @@
+package rclone
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// WebDAVHandler serves the GravityRoot VFS over WebDAV for requests under
+// prefix. Reads and writes go through the same VFS, and so the same cache
+// settings, as the file API.
+func (e *Engine) WebDAVHandler(prefix string) http.Handler {
+	return &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: davFS{e: e},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				e.logger.Debug("webdav request failed",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Error(err))
+			}
+		},
+	}
+}
+
+// currentVFS returns the VFS, which Restart replaces when settings change
+func (e *Engine) currentVFS() (*vfs.VFS, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.vfs == nil {
+		return nil, errors.New("vfs is not running")
+	}
+	return e.vfs, nil
+}
+
+// davFS adapts the VFS to webdav.FileSystem
+type davFS struct {
+	e *Engine
+}
+
+func (d davFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	v, err := d.e.currentVFS()
+	if err != nil {
+		return err
+	}
+	dir, leaf, err := v.StatParent(name)
+	if err != nil {
+		return err
+	}
+	_, err = dir.Mkdir(leaf)
+	return err
+}
+
+func (d davFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	v, err := d.e.currentVFS()
+	if err != nil {
+		return nil, err
+	}
+	h, err := v.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return davFile{Handle: h}, nil
+}
+
+func (d davFS) RemoveAll(ctx context.Context, name string) error {
+	v, err := d.e.currentVFS()
+	if err != nil {
+		return err
+	}
+	node, err := v.Stat(name)
+	if err != nil {
+		return err
+	}
+	return node.RemoveAll()
+}
+
+func (d davFS) Rename(ctx context.Context, oldName, newName string) error {
+	v, err := d.e.currentVFS()
+	if err != nil {
+		return err
+	}
+	return v.Rename(oldName, newName)
+}
+
+func (d davFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	v, err := d.e.currentVFS()
+	if err != nil {
+		return nil, err
+	}
+	fi, err := v.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return davFileInfo{FileInfo: fi}, nil
+}
+
+// davFile is an open VFS handle whose listings carry content types
+type davFile struct {
+	vfs.Handle
+}
+
+func (f davFile) Readdir(count int) ([]os.FileInfo, error) {
+	fis, err := f.Handle.Readdir(count)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fis {
+		fis[i] = davFileInfo{FileInfo: fis[i]}
+	}
+	return fis, nil
+}
+
+func (f davFile) Stat() (os.FileInfo, error) {
+	fi, err := f.Handle.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return davFileInfo{FileInfo: fi}, nil
+}
+
+// davFileInfo answers content types from the file name, so webdav does not
+// read the start of every file in a listing to sniff them
+type davFileInfo struct {
+	os.FileInfo
+}
+
+func (fi davFileInfo) ContentType(ctx context.Context) (string, error) {
+	if fi.IsDir() {
+		return "inode/directory", nil
+	}
+	if t := mime.TypeByExtension(path.Ext(fi.Name())); t != "" {
+		return t, nil
+	}
+	return fs.MimeTypeFromName(fi.Name()), nil
+}
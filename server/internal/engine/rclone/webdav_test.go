@@ -0,0 +1,77 @@
+package rclone
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"go.uber.org/zap"
+)
+
+func TestWebDAVRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	f, err := fs.NewFs(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeOff
+	e := &Engine{logger: zap.NewNop(), vfs: vfs.New(f, &opt)}
+	defer e.vfs.Shutdown()
+
+	srv := httptest.NewServer(e.WebDAVHandler("/dav"))
+	defer srv.Close()
+	do := func(method, path, body string, headers map[string]string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(data)
+	}
+
+	if code, _ := do("MKCOL", "/dav/movies", "", nil); code != http.StatusCreated {
+		t.Fatalf("MKCOL = %d", code)
+	}
+	if code, _ := do("PUT", "/dav/movies/clip.mp4", "0123456789", nil); code != http.StatusCreated {
+		t.Fatalf("PUT = %d", code)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "movies", "clip.mp4")); err != nil || string(data) != "0123456789" {
+		t.Fatalf("uploaded file = %q, %v", data, err)
+	}
+
+	code, body := do("PROPFIND", "/dav/movies", "", map[string]string{"Depth": "1"})
+	if code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND = %d", code)
+	}
+	for _, want := range []string{"/dav/movies/clip.mp4", "<D:getcontentlength>10</D:getcontentlength>", "video/mp4", "<D:collection"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("PROPFIND response lacks %q:\n%s", want, body)
+		}
+	}
+
+	if code, body := do("GET", "/dav/movies/clip.mp4", "", nil); code != http.StatusOK || body != "0123456789" {
+		t.Errorf("GET = %d %q", code, body)
+	}
+	if code, _ := do("DELETE", "/dav/movies", "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE = %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "movies")); !os.IsNotExist(err) {
+		t.Errorf("folder still on disk: %v", err)
+	}
+}
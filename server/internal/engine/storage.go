@@ -2,7 +2,9 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net/http"
 	"time"
 
 	"gravity/internal/model"
@@ -30,6 +32,8 @@ type Remote struct {
 	Name      string `json:"name"`
 	Type      string `json:"type"`
 	Connected bool   `json:"connected"`
+	// Last known quota, absent until fetched or if the backend has none
+	About *model.RemoteUsage `json:"about,omitempty"`
 }
 
 type UploadProgress struct {
@@ -53,6 +57,48 @@ type UploadOptions struct {
 	DeleteAfter bool
 	TrackingID  string // Download ID to use for progress tracking callbacks
 	JobID       int64  // Custom job ID to use (if 0, one will be generated)
+	Force       bool   // Re-send files even if the destination looks up to date
+}
+
+// SyncOptions describes one run of a sync job. Src and Dst are rclone
+// paths: "remote:path" or a local folder.
+type SyncOptions struct {
+	TrackingID string // Routes progress callbacks; the run is cancelled with Cancel(TrackingID)
+	Mode       string // copy, move or sync
+	Src        string
+	Dst        string
+	DryRun     bool
+
+	Include []string
+	Exclude []string
+	MinSize string
+	MaxSize string
+	MinAge  string
+	MaxAge  string
+
+	OnProgress func(UploadProgress)
+}
+
+// SyncStats summarises a finished sync run
+type SyncStats struct {
+	Bytes     int64
+	Transfers int64
+	Checks    int64
+	Deletes   int64
+	Errors    int64
+}
+
+// VerifyResult is the outcome of comparing an upload with its local source
+type VerifyResult struct {
+	Method     string   `json:"method"` // Weakest check used: a hash name, "download", "modtime" or "size"
+	Files      int      `json:"files"`
+	Missing    []string `json:"missing,omitempty"`
+	Mismatched []string `json:"mismatched,omitempty"`
+}
+
+// OK reports whether every file was found on the remote and matched
+func (r *VerifyResult) OK() bool {
+	return len(r.Missing) == 0 && len(r.Mismatched) == 0
 }
 
 type GlobalStats struct {
@@ -78,11 +124,59 @@ type StorageEngine interface {
 	Mkdir(ctx context.Context, virtualPath string) error
 	Delete(ctx context.Context, virtualPath string) error
 	Rename(ctx context.Context, virtualPath, newName string) error
+	// MovePath moves a file or folder to dst, creating its parent folders
+	MovePath(ctx context.Context, src, dst string) error
+	// Put writes in to virtualPath, replacing any existing file
+	Put(ctx context.Context, virtualPath string, in io.Reader) error
 
 	// Data
 	Open(ctx context.Context, virtualPath string) (ReadSeekCloser, error)
 }
 
+// WebDAVServer is implemented by engines that can serve their VFS over WebDAV
+type WebDAVServer interface {
+	WebDAVHandler(prefix string) http.Handler
+}
+
+// RecursiveLister is implemented by engines that can list a whole tree in
+// one pass, using the backend's recursive listing where it has one
+type RecursiveLister interface {
+	// ListR calls fn with batches of the files and folders below
+	// virtualPath, in no particular order
+	ListR(ctx context.Context, virtualPath string, fn func([]FileInfo) error) error
+}
+
+// TreeRemover is implemented by engines that can delete a folder along with
+// everything in it. Delete only removes files and empty folders.
+type TreeRemover interface {
+	RemoveAll(ctx context.Context, virtualPath string) error
+}
+
+// LocalRootLister is implemented by engines that serve local folders, such as
+// the download dir, next to their remotes
+type LocalRootLister interface {
+	// LocalRoots returns the folders by the name they are served under
+	LocalRoots() map[string]string
+}
+
+// Hasher is implemented by engines that can read file hashes from backends
+type Hasher interface {
+	// HashTypes returns the names of the hashes the backend of remote
+	// supports, such as "md5"
+	HashTypes(ctx context.Context, remote string) ([]string, error)
+	// Hash returns the hash named ht of the file at virtualPath
+	Hash(ctx context.Context, virtualPath, ht string) (string, error)
+}
+
+// ErrUsageUnsupported is returned by UsageReader for backends without a
+// quota, such as most object stores
+var ErrUsageUnsupported = errors.New("backend does not report usage")
+
+// UsageReader is implemented by engines that can read the quota of a remote
+type UsageReader interface {
+	About(ctx context.Context, remote string) (*model.RemoteUsage, error)
+}
+
 type UploadEngine interface {
 	StorageEngine
 
@@ -96,6 +190,13 @@ type UploadEngine interface {
 	Copy(ctx context.Context, srcPath, dstPath string) (string, error)
 	Move(ctx context.Context, srcPath, dstPath string) (string, error)
 
+	// Verify compares a local file or folder with its uploaded copy at dst
+	Verify(ctx context.Context, src, dst string) (*VerifyResult, error)
+
+	// Sync runs a copy, move or sync between two folders and blocks until it
+	// is done. Stats are returned even when the run fails.
+	Sync(ctx context.Context, opts SyncOptions) (*SyncStats, error)
+
 	// Status
 	Status(ctx context.Context, jobID string) (*UploadStatus, error)
 	GetGlobalStats(ctx context.Context) (*GlobalStats, error)
@@ -112,6 +213,9 @@ type UploadEngine interface {
 	CreateRemote(ctx context.Context, name, rtype string, config map[string]string) error
 	DeleteRemote(ctx context.Context, name string) error
 	TestRemote(ctx context.Context, name string) error
+	// PublicLink creates, or with unlink removes, a link made by the backend
+	// itself for sharing virtualPath
+	PublicLink(ctx context.Context, virtualPath string, expire time.Duration, unlink bool) (string, error)
 
 	// Config
 	Configure(ctx context.Context, settings *model.Settings) error
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gravity/internal/model"
+)
+
+// TemplateVariables lists the variables that can be used in download
+// directories and upload destinations, e.g.
+// "gdrive:/Media/{category}/{year}/{month}" or "{provider}/{torrent_name}"
+var TemplateVariables = []string{
+	"filename", "name", "ext", "category", "provider", "torrent_name",
+	"hash", "host", "date", "year", "month", "day", "id",
+}
+
+// Substituted for variables the download has no value for, so the path
+// keeps its depth
+const unknownTemplateValue = "unknown"
+
+// TemplatePreview shows how a path template expands for one download
+type TemplatePreview struct {
+	Template  string            `json:"template" example:"gdrive:/Media/{category}/{year}"`
+	Path      string            `json:"path" example:"gdrive:/Media/Movies/2026"`
+	Variables map[string]string `json:"variables"`
+	Unknown   []string          `json:"unknown,omitempty"`
+}
+
+var templateVarRegex = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// TemplateVars returns the template variables of d. category is filled in
+// by the OptionResolver, which knows the configured categories.
+func TemplateVars(d *model.Download) map[string]string {
+	created := d.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(d.Filename), ".")
+	vars := map[string]string{
+		"filename":     d.Filename,
+		"name":         strings.TrimSuffix(d.Filename, filepath.Ext(d.Filename)),
+		"ext":          strings.ToLower(ext),
+		"provider":     d.Provider,
+		"torrent_name": d.Filename,
+		"hash":         strings.ToLower(d.MagnetHash),
+		"date":         created.Format("2006-01-02"),
+		"year":         created.Format("2006"),
+		"month":        created.Format("01"),
+		"day":          created.Format("02"),
+		"id":           d.ID,
+	}
+	if u, err := url.Parse(d.URL); err == nil {
+		vars["host"] = u.Hostname()
+	}
+	return vars
+}
+
+// ExpandTemplate replaces the {variables} in tmpl. Values cannot add path
+// separators; variables without a value become "unknown" and unrecognized
+// names are left as they are.
+func ExpandTemplate(tmpl string, vars map[string]string) string {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+	return templateVarRegex.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := m[1 : len(m)-1]
+		if !slices.Contains(TemplateVariables, name) {
+			return m
+		}
+		v := strings.TrimSpace(vars[name])
+		if v == "" {
+			return unknownTemplateValue
+		}
+		return strings.NewReplacer("/", "_", `\`, "_").Replace(v)
+	})
+}
+
+// UnknownTemplateVars returns the {names} in tmpl that are not template
+// variables
+func UnknownTemplateVars(tmpl string) []string {
+	var unknown []string
+	for _, m := range templateVarRegex.FindAllStringSubmatch(tmpl, -1) {
+		if !slices.Contains(TemplateVariables, m[1]) && !slices.Contains(unknown, m[1]) {
+			unknown = append(unknown, m[1])
+		}
+	}
+	return unknown
+}
+
+// TemplateRoot returns the part of tmpl before its first variable, cut
+// back to a whole directory. It is the directory to use where no download
+// is at hand, such as disk usage stats.
+func TemplateRoot(tmpl string) string {
+	i := strings.Index(tmpl, "{")
+	if i < 0 {
+		return tmpl
+	}
+	root := tmpl[:i]
+	if j := strings.LastIndexAny(root, `/\:`); j >= 0 {
+		if root[j] == ':' {
+			return root[:j+1]
+		}
+		return filepath.Clean(root[:j+1])
+	}
+	return ""
+}
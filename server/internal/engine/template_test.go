@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"gravity/internal/model"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	d := &model.Download{
+		ID:        "d_1234abcd",
+		URL:       "https://cdn.example.com/files/Movie.2008.MKV?token=x",
+		Filename:  "Movie.2008.MKV",
+		Provider:  "direct",
+		CreatedAt: time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC),
+	}
+	settings := model.DefaultSettings()
+	settings.Automation.Categories = []model.Category{{Name: "Movies", Extensions: []string{".mkv"}}}
+	r := NewOptionResolver(settings)
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"gdrive:/Media/{category}/{year}/{month}/{filename}", "gdrive:/Media/Movies/2026/03/Movie.2008.MKV"},
+		{"{provider}/{host}/{name}.{ext}", "direct/cdn.example.com/Movie.2008.mkv"},
+		{"s3:bucket/{hash}/{date}", "s3:bucket/unknown/2026-03-07"},
+		{"gdrive:/{notavar}/{id}", "gdrive:/{notavar}/d_1234abcd"},
+		{"gdrive:/plain", "gdrive:/plain"},
+	}
+	for _, tt := range tests {
+		if got := r.ExpandPath(tt.tmpl, d); got != tt.want {
+			t.Errorf("ExpandPath(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	d.Filename = "a/b.txt"
+	if got := r.ExpandPath("/data/{filename}", d); got != "/data/a_b.txt" {
+		t.Errorf("values must not add path separators: %q", got)
+	}
+}
+
+func TestTemplateRoot(t *testing.T) {
+	tests := map[string]string{
+		"/downloads":                   "/downloads",
+		"/downloads/{category}/{year}": "/downloads",
+		"/data/tv-{year}":              "/data",
+		"gdrive:{provider}":            "gdrive:",
+		"{provider}/x":                 "",
+	}
+	for tmpl, want := range tests {
+		if got := TemplateRoot(tmpl); got != want {
+			t.Errorf("TemplateRoot(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+}
@@ -10,6 +10,8 @@ const (
 	CodeInvalidTransition ErrorCode = "INVALID_TRANSITION"
 	CodeInternalError     ErrorCode = "INTERNAL_ERROR"
 	CodeInvalidOperation  ErrorCode = "INVALID_OPERATION"
+	CodeUnauthorized      ErrorCode = "UNAUTHORIZED"
+	CodeGone              ErrorCode = "GONE"
 )
 
 type AppError struct {
@@ -74,8 +74,13 @@ func (b *Bus) PublishProgress(e ProgressEvent) {
 
 	// Also publish to unified subscribers (SSE)
 	eventType := DownloadProgress
-	if e.Type == "upload" {
+	switch e.Type {
+	case "upload":
 		eventType = UploadProgress
+	case "sync":
+		eventType = SyncProgress
+	case "file":
+		eventType = FileUploadProgress
 	}
 	b.publishUnified(Event{
 		Type:      eventType,
@@ -28,6 +28,7 @@ const (
 	DownloadResumed   EventType = "download.resumed"
 	DownloadCompleted EventType = "download.completed"
 	DownloadError     EventType = "download.error"
+	DownloadDeleted   EventType = "download.deleted"
 
 	// Upload lifecycle events
 	UploadStarted   EventType = "upload.started"
@@ -35,6 +36,26 @@ const (
 	UploadCompleted EventType = "upload.completed"
 	UploadError     EventType = "upload.error"
 
+	// Sync job events
+	SyncStarted   EventType = "sync.started"
+	SyncProgress  EventType = "sync.progress"
+	SyncCompleted EventType = "sync.completed"
+	SyncError     EventType = "sync.error"
+
+	// File manager upload events
+	FileUploadProgress EventType = "file.progress"
+	FileUploaded       EventType = "file.uploaded"
+	FileUploadError    EventType = "file.error"
+
+	// Search indexing events
+	IndexStarted   EventType = "index.started"
+	IndexProgress  EventType = "index.progress"
+	IndexCompleted EventType = "index.completed"
+	IndexError     EventType = "index.error"
+
+	// Saved search alerts
+	SavedSearchMatched EventType = "search.matched"
+
 	// System events
 	SettingsUpdated EventType = "settings.updated"
 	StatsUpdate     EventType = "stats"
@@ -46,7 +67,7 @@ const ETAUnknown = -1
 // ProgressEvent represents a high-frequency progress update
 type ProgressEvent struct {
 	ID         string `json:"id"`
-	Type       string `json:"type"` // "download" or "upload"
+	Type       string `json:"type"` // "download", "upload", "sync" or "file"
 	Downloaded int64  `json:"downloaded,omitempty"`
 	Uploaded   int64  `json:"uploaded,omitempty"`
 	Size       int64  `json:"size"`
@@ -2,6 +2,7 @@ package model
 
 import (
 	"gravity/internal/errors"
+	"slices"
 	"time"
 )
 
@@ -25,23 +26,52 @@ const (
 type UploadStatus string
 
 const (
-	UploadStatusIdle     UploadStatus = "idle"
-	UploadStatusRunning  UploadStatus = "running"
-	UploadStatusComplete UploadStatus = "complete"
-	UploadStatusError    UploadStatus = "error"
+	UploadStatusIdle      UploadStatus = "idle"
+	UploadStatusQueued    UploadStatus = "queued"
+	UploadStatusRunning   UploadStatus = "running"
+	UploadStatusPaused    UploadStatus = "paused"
+	UploadStatusVerifying UploadStatus = "verifying" // Comparing the remote copy with the local one
+	UploadStatusComplete  UploadStatus = "complete"
+	UploadStatusError     UploadStatus = "error"
+	UploadStatusMismatch  UploadStatus = "mismatch" // Uploaded, but the remote copy differs
+	UploadStatusCancelled UploadStatus = "cancelled"
 )
 
+// Pending reports whether an upload in this state may still succeed
+// without being retried by hand
+func (s UploadStatus) Pending() bool {
+	return s == UploadStatusQueued || s == UploadStatusRunning || s == UploadStatusPaused || s == UploadStatusVerifying
+}
+
+// Failed reports whether an upload in this state needs a retry to succeed
+func (s UploadStatus) Failed() bool {
+	return s == UploadStatusError || s == UploadStatusMismatch || s == UploadStatusCancelled
+}
+
 type ExecutionMode string
 
 const (
 	ExecutionModeDirect      ExecutionMode = "direct"       // Standard URL -> engine.Add()
 	ExecutionModeMagnet      ExecutionMode = "magnet"       // Magnet/torrent -> engine.AddMagnetWithSelection()
 	ExecutionModeDebridFiles ExecutionMode = "debrid-files" // Cached debrid -> parallel file downloads
+	ExecutionModeRemote      ExecutionMode = "remote"       // rclone remote or sftp/ftp source -> native engine copy
+)
+
+const (
+	ProxyTypeAll       = "all"
+	ProxyTypeDownloads = "downloads"
+	ProxyTypeUploads   = "uploads"
+	ProxyTypeMagnets   = "magnets"
+	ProxyTypeProviders = "providers"
 )
 
 type Proxy struct {
 	URL  string `json:"url"`
-	Type string `json:"type" enums:"all,downloads,uploads,magnets"`
+	Type string `json:"type" enums:"all,downloads,uploads,magnets,providers"`
+
+	// Only route these hosts (e.g. "*.example.com") or, for uploads, remote
+	// names through the proxy. Empty means any.
+	Hosts []string `json:"hosts,omitempty" example:"*.example.com"`
 }
 
 type Download struct {
@@ -56,7 +86,9 @@ type Download struct {
 	Filename      string         `json:"filename" binding:"required"`
 	Dir           string         `json:"dir" binding:"required"`
 	Destination   string         `json:"destination,omitempty"`
-	UploadStatus  UploadStatus   `json:"uploadStatus,omitempty" enums:"idle,running,complete,error"`
+	Destinations  []string       `json:"destinations,omitempty" gorm:"serializer:json"`
+	UploadStatus  UploadStatus   `json:"uploadStatus,omitempty" enums:"idle,queued,running,paused,verifying,complete,error,mismatch,cancelled"`
+	Uploads       []UploadTarget `json:"uploads,omitempty" gorm:"serializer:json"`
 	Size          int64          `json:"size" example:"10485760" binding:"required"`
 	Proxies       []Proxy        `json:"proxies" gorm:"serializer:json"`
 
@@ -65,10 +97,17 @@ type Download struct {
 	ConnectTimeout   *int    `json:"connectTimeout,omitempty"`
 	MaxTries         *int    `json:"maxTries,omitempty"`
 
+	// Write straight into Destination instead of staging on local disk.
+	// nil until resolved against UploadSettings.StreamToDestination.
+	StreamToDestination *bool `json:"streamToDestination,omitempty"`
+
+	// Number of destinations that must succeed before the download counts as
+	// complete. nil = UploadSettings.Quorum, 0 = all of them.
+	UploadQuorum *int `json:"uploadQuorum,omitempty"`
+
 	RemoveLocal *bool             `json:"removeLocal,omitempty"`
 	Downloaded  int64             `json:"downloaded" example:"5242880" binding:"required"`
 	EngineID    string            `json:"-" gorm:"column:engine_id;index"`
-	UploadJobID string            `json:"-" gorm:"column:upload_job_id;index"`
 	Headers     map[string]string `json:"headers,omitempty" gorm:"serializer:json"`
 	FileModTime *time.Time        `json:"fileModTime,omitempty"`
 	CreatedAt   time.Time         `json:"createdAt"`
@@ -100,6 +139,9 @@ type Download struct {
 	Seeders        int    `json:"seeders" gorm:"-"`
 	Peers          int    `json:"peers" gorm:"-"`
 	PeerDetails    []Peer `json:"peerDetails" gorm:"-"`
+
+	// Warnings about requested options the engine will not honor
+	Warnings []string `json:"warnings,omitempty" gorm:"-"`
 }
 
 func (d *Download) Validate() error {
@@ -115,6 +157,76 @@ func (d *Download) Validate() error {
 	return nil
 }
 
+// AllDestinations returns Destination followed by the other Destinations,
+// skipping blanks and duplicates
+func (d *Download) AllDestinations() []string {
+	return compactDestinations(append([]string{d.Destination}, d.Destinations...))
+}
+
+// SetDestinations stores dests with the first one as the primary
+// Destination. Destinations is only kept when there is more than one.
+func (d *Download) SetDestinations(dests []string) {
+	all := compactDestinations(dests)
+	d.Destination, d.Destinations = "", nil
+	if len(all) > 0 {
+		d.Destination = all[0]
+	}
+	if len(all) > 1 {
+		d.Destinations = all
+	}
+}
+
+// UploadQuorumMet reports whether enough uploads succeeded for the download
+// to count as complete. A quorum of 0, or one above the number of targets,
+// requires all of them.
+func (d *Download) UploadQuorumMet(quorum int) bool {
+	if len(d.Uploads) == 0 {
+		return false
+	}
+	if quorum <= 0 || quorum > len(d.Uploads) {
+		quorum = len(d.Uploads)
+	}
+	done := 0
+	for _, u := range d.Uploads {
+		if u.Status == UploadStatusComplete {
+			done++
+		}
+	}
+	return done >= quorum
+}
+
+// UploadsPending reports whether any upload target is still queued,
+// running or paused
+func (d *Download) UploadsPending() bool {
+	for _, u := range d.Uploads {
+		if u.Status.Pending() {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadsMismatched reports whether any destination failed verification.
+// The local copy is kept for those so they can be uploaded again.
+func (d *Download) UploadsMismatched() bool {
+	for _, u := range d.Uploads {
+		if u.Status == UploadStatusMismatch {
+			return true
+		}
+	}
+	return false
+}
+
+// WritesToDestination reports whether the engine copies straight into
+// Destination, leaving nothing on local disk for the upload step. Downloads
+// fanned out to several destinations are always staged locally.
+func (d *Download) WritesToDestination() bool {
+	if d.Destination == "" || len(d.AllDestinations()) > 1 {
+		return false
+	}
+	return d.ExecutionMode == ExecutionModeRemote || (d.StreamToDestination != nil && *d.StreamToDestination)
+}
+
 // TransitionTo updates the download status if the transition is valid
 func (d *Download) TransitionTo(status DownloadStatus) error {
 	if err := ValidateTransition(d.Status, status); err != nil {
@@ -125,6 +237,32 @@ func (d *Download) TransitionTo(status DownloadStatus) error {
 	return nil
 }
 
+func compactDestinations(dests []string) []string {
+	var out []string
+	for _, dst := range dests {
+		if dst != "" && !slices.Contains(out, dst) {
+			out = append(out, dst)
+		}
+	}
+	return out
+}
+
+// UploadTarget is the upload of a download to one of its destinations.
+// Each target runs as its own upload job.
+type UploadTarget struct {
+	Destination string       `json:"destination" example:"gdrive:movies"`
+	Status      UploadStatus `json:"status" enums:"idle,queued,running,paused,verifying,complete,error,mismatch,cancelled"`
+	JobID       string       `json:"jobId,omitempty" example:"u_a1b2c3d4"`
+	Uploaded    int64        `json:"uploaded"`
+	Size        int64        `json:"size"`
+	Speed       int64        `json:"speed"`
+	Error       string       `json:"error,omitempty"`
+	RetryCount  int          `json:"retryCount"`
+	VerifiedBy  string       `json:"verifiedBy,omitempty" example:"md5"` // How the remote copy was checked
+	StartedAt   *time.Time   `json:"startedAt,omitempty"`
+	CompletedAt *time.Time   `json:"completedAt,omitempty"`
+}
+
 // Peer represents a network peer in a BitTorrent swarm
 type Peer struct {
 	IP            string `json:"ip" validate:"required" binding:"required"`
@@ -0,0 +1,29 @@
+package model
+
+// DuplicateGroup is a set of indexed files with the same name and size.
+// Verified groups also share a backend hash.
+type DuplicateGroup struct {
+	Name     string `json:"name" example:"movie.mkv"`
+	Size     int64  `json:"size" example:"4294967296"`
+	Hash     string `json:"hash,omitempty" example:"md5:9e107d9d372bb6826bd81d3542a419d6"`
+	Verified bool   `json:"verified"`
+	// Hashes of some files are being computed; the group may split once done
+	Hashing bool `json:"hashing,omitempty"`
+	// Space freed by keeping only one of the files
+	Reclaimable int64         `json:"reclaimable" example:"8589934592"`
+	Files       []IndexedFile `json:"files"`
+}
+
+// DuplicateResolution keeps one file of a group and removes the others
+type DuplicateResolution struct {
+	Keep   string   `json:"keep" validate:"required" example:"/gdrive/movie.mkv"`
+	Remove []string `json:"remove" validate:"required,min=1" example:"/onedrive/movie.mkv"`
+	// Hash of a verified group; files whose cached hash differs are kept
+	Hash string `json:"hash,omitempty" example:"md5:9e107d9d372bb6826bd81d3542a419d6"`
+}
+
+// DuplicateRemoval is the outcome of removing one duplicate
+type DuplicateRemoval struct {
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"`
+}
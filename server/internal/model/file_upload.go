@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// ConflictPolicy decides what a file manager upload does when the target
+// file already exists
+type ConflictPolicy string
+
+const (
+	ConflictFail      ConflictPolicy = "fail"      // Reject the upload
+	ConflictOverwrite ConflictPolicy = "overwrite" // Replace the existing file
+	ConflictRename    ConflictPolicy = "rename"    // Store as "name (1).ext"
+)
+
+func (p ConflictPolicy) Valid() bool {
+	switch p {
+	case ConflictFail, ConflictOverwrite, ConflictRename:
+		return true
+	}
+	return false
+}
+
+// FileUploadSession is a resumable upload of one file. Chunks are appended
+// in order until Offset reaches Size, then the file is written to Path.
+type FileUploadSession struct {
+	ID        string         `json:"id" example:"fu_a1b2c3d4"`
+	Path      string         `json:"path" example:"/gdrive/movies/file.mkv"` // Final path once Complete
+	Size      int64          `json:"size" example:"1073741824"`
+	Offset    int64          `json:"offset" example:"52428800"` // Bytes received so far
+	Conflict  ConflictPolicy `json:"conflict" enums:"fail,overwrite,rename"`
+	Complete  bool           `json:"complete"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// RemoteUsage is the quota of a remote as its backend reports it. Sizes are
+// in bytes; a field is nil when the backend does not report it.
+type RemoteUsage struct {
+	Remote    string    `json:"remote" example:"gdrive"`
+	Total     *int64    `json:"total,omitempty" example:"16106127360"`
+	Used      *int64    `json:"used,omitempty" example:"8589934592"`
+	Free      *int64    `json:"free,omitempty" example:"7516192768"`
+	Trashed   *int64    `json:"trashed,omitempty" example:"104857600"`
+	Objects   *int64    `json:"objects,omitempty" example:"1234"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FreeSpace returns the free space of the remote, worked out from the total
+// and used space if the backend does not report it. It returns false when
+// it is unknown, as on backends without a quota.
+func (u *RemoteUsage) FreeSpace() (int64, bool) {
+	switch {
+	case u == nil:
+		return 0, false
+	case u.Free != nil:
+		return *u.Free, true
+	case u.Total != nil && u.Used != nil:
+		return max(*u.Total-*u.Used, 0), true
+	}
+	return 0, false
+}
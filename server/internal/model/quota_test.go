@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestRemoteUsageFreeSpace(t *testing.T) {
+	n := func(v int64) *int64 { return &v }
+	tests := []struct {
+		name  string
+		usage *RemoteUsage
+		free  int64
+		known bool
+	}{
+		{"unknown", nil, 0, false},
+		{"no quota", &RemoteUsage{Used: n(10)}, 0, false},
+		{"reported", &RemoteUsage{Total: n(100), Used: n(10), Free: n(50)}, 50, true},
+		{"from total", &RemoteUsage{Total: n(100), Used: n(30)}, 70, true},
+		{"over quota", &RemoteUsage{Total: n(100), Used: n(130)}, 0, true},
+	}
+	for _, tt := range tests {
+		free, known := tt.usage.FreeSpace()
+		if free != tt.free || known != tt.known {
+			t.Errorf("%s: got %d, %v", tt.name, free, known)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"gravity/internal/errors"
+)
+
+// SavedSearchAction is queued for each new match of a saved search
+type SavedSearchAction string
+
+const (
+	SavedSearchNotify   SavedSearchAction = ""         // Only publish an event
+	SavedSearchCopy     SavedSearchAction = "copy"     // Copy matches to a remote folder
+	SavedSearchDownload SavedSearchAction = "download" // Copy matches into the download dir
+)
+
+// SavedSearch is a named search that alerts when an index run adds files
+// matching it. Relative times in the query, such as after:7d, are taken
+// from the time of each run.
+type SavedSearch struct {
+	ID      string            `json:"id" example:"ss_a1b2c3d4" gorm:"primaryKey"`
+	Name    string            `json:"name" example:"New 4K movies" gorm:"uniqueIndex"`
+	Query   string            `json:"query" example:"2160p ext:mkv,mp4 size:>10G"`
+	Enabled bool              `json:"enabled"`
+	Action  SavedSearchAction `json:"action,omitempty" enums:"copy,download"`
+	// Folder matches are copied to: a remote path for copy, or a folder
+	// in the download dir for download
+	Destination string `json:"destination,omitempty" example:"/gdrive/Movies"`
+
+	LastMatchAt *time.Time `json:"lastMatchAt,omitempty"`
+	MatchCount  int64      `json:"matchCount"` // New files matched so far
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func (s *SavedSearch) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New(errors.CodeValidationFailed, "name is required")
+	}
+	q, err := ParseSearchQuery(s.Query, time.Now())
+	if err != nil {
+		return err
+	}
+	if q.Empty() {
+		return errors.New(errors.CodeValidationFailed, "query is required")
+	}
+
+	switch s.Action {
+	case SavedSearchNotify:
+	case SavedSearchCopy:
+		if !strings.HasPrefix(s.Destination, "/") || strings.Trim(s.Destination, "/") == "" {
+			return errors.New(errors.CodeValidationFailed, "copy needs a destination such as /gdrive/Movies")
+		}
+	case SavedSearchDownload:
+	default:
+		return errors.New(errors.CodeValidationFailed, "action must be copy or download")
+	}
+	return nil
+}
+
+// Target returns where the action copies the file at virtualPath: into
+// Destination, which for download is below the download dir's local root.
+// It returns "" if the search only notifies.
+func (s *SavedSearch) Target(virtualPath string) string {
+	name := path.Base(virtualPath)
+	switch s.Action {
+	case SavedSearchCopy:
+		return path.Join(s.Destination, name)
+	case SavedSearchDownload:
+		// Cleaned from the root first so it cannot leave the download dir
+		return path.Join("/", LocalRootDownloads, path.Clean("/"+s.Destination), name)
+	}
+	return ""
+}
+
+// SavedSearchMatch is the payload of a saved search alert
+type SavedSearchMatch struct {
+	SearchID string        `json:"searchId"`
+	Name     string        `json:"name"`
+	Remote   string        `json:"remote"`
+	Total    int           `json:"total"`
+	Files    []IndexedFile `json:"files"` // The first matches
+	Jobs     []string      `json:"jobs,omitempty"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import "testing"
+
+func TestSavedSearchValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		ss   SavedSearch
+		ok   bool
+	}{
+		{"notify", SavedSearch{Name: "4k", Query: "2160p ext:mkv"}, true},
+		{"no name", SavedSearch{Query: "2160p"}, false},
+		{"empty query", SavedSearch{Name: "x", Query: "  "}, false},
+		{"bad filter", SavedSearch{Name: "x", Query: "size:big"}, false},
+		{"copy", SavedSearch{Name: "x", Query: "ext:mkv", Action: SavedSearchCopy, Destination: "/gdrive/Movies"}, true},
+		{"copy to root", SavedSearch{Name: "x", Query: "ext:mkv", Action: SavedSearchCopy, Destination: "/"}, false},
+		{"download", SavedSearch{Name: "x", Query: "ext:mkv", Action: SavedSearchDownload}, true},
+		{"unknown action", SavedSearch{Name: "x", Query: "ext:mkv", Action: "move"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.ss.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+	}
+}
+
+func TestSavedSearchTarget(t *testing.T) {
+	cp := SavedSearch{Action: SavedSearchCopy, Destination: "/gdrive/Movies"}
+	if got := cp.Target("/onedrive/in/a.mkv"); got != "/gdrive/Movies/a.mkv" {
+		t.Errorf("copy target = %q", got)
+	}
+	download := SavedSearch{Action: SavedSearchDownload, Destination: "../../gdrive"}
+	if got := download.Target("/onedrive/a.mkv"); got != "/"+LocalRootDownloads+"/gdrive/a.mkv" {
+		t.Errorf("download target = %q", got)
+	}
+	if got := (&SavedSearch{}).Target("/onedrive/a.mkv"); got != "" {
+		t.Errorf("notify target = %q", got)
+	}
+}
@@ -2,13 +2,55 @@ package model
 
 import "time"
 
+// Where an indexed file was found
+const (
+	IndexSourceRemote   = "remote"   // A cloud remote
+	IndexSourceLocal    = "local"    // The download dir or another local root
+	IndexSourceDownload = "download" // The download history
+)
+
 type IndexedFile struct {
 	ID            string    `json:"id" gorm:"primaryKey"`
-	Remote        string    `json:"remote" gorm:"index"`
+	Source        string    `json:"source" gorm:"index;default:remote"`
+	Remote        string    `json:"remote" gorm:"index"` // Empty for downloads
 	Path          string    `json:"path" gorm:"index"`
 	Name          string    `json:"name" gorm:"column:filename;index"`
+	Ext           string    `json:"ext" gorm:"index"` // Lowercase, without the dot
 	Size          int64     `json:"size"`
 	ModTime       time.Time `json:"modTime"`
 	IsDir         bool      `json:"isDir"`
 	LastIndexedAt time.Time `json:"lastIndexedAt"`
+	// When the row was added, which saved search alerts look at
+	FirstIndexedAt time.Time `json:"firstIndexedAt" gorm:"index"`
+	// Hash cached by the duplicate finder as "type:value", cleared when the
+	// file changes
+	Hash string `json:"hash,omitempty"`
+
+	// Downloads only
+	DownloadID string `json:"downloadId,omitempty" gorm:"index"`
+	URL        string `json:"url,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+}
+
+// Unchanged reports whether f still matches the indexed row o. Times are
+// compared to the microsecond, the precision postgres keeps.
+func (f *IndexedFile) Unchanged(o *IndexedFile) bool {
+	return f.Size == o.Size && f.IsDir == o.IsDir && f.Name == o.Name && f.Ext == o.Ext && f.Source == o.Source &&
+		f.ModTime.Truncate(time.Microsecond).Equal(o.ModTime.Truncate(time.Microsecond))
+}
+
+// IndexRun is the state of an indexing run of a remote. It is saved after
+// each top-level folder so an interrupted run resumes where it stopped;
+// rows it has seen are stamped with StartedAt.
+type IndexRun struct {
+	Remote    string    `json:"remote" gorm:"primaryKey"`
+	StartedAt time.Time `json:"startedAt"`
+	// Top-level folders that are fully indexed
+	Done      []string  `json:"done" gorm:"serializer:json"`
+	Scanned   int64     `json:"scanned"`
+	Added     int64     `json:"added"`
+	Updated   int64     `json:"updated"`
+	Removed   int64     `json:"removed"`
+	Resumed   bool      `json:"resumed" gorm:"-"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,344 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gravity/internal/errors"
+)
+
+// Sort orders of a search
+const (
+	SearchSortRelevance = "relevance"
+	SearchSortName      = "name"
+	SearchSortSize      = "size"
+	SearchSortModified  = "modified"
+	SearchSortPath      = "path"
+)
+
+// SearchQuery is a parsed search. Free text is matched on the tokens of file
+// names and paths; the other fields filter the matches.
+//
+// The query language is words, "quoted phrases" and -excluded words mixed
+// with filters:
+//
+//	source:local           remote, local or download; comma-separate for several
+//	remote:gdrive          on a remote; repeat or comma-separate for several
+//	ext:mkv,mp4            by extension
+//	size:>1G size:<=500M   by size; size:1G..4G for a range
+//	after:2024-01-01       modified on or after a date, or within an age
+//	before:7d              such as 12h, 7d or 2w
+//	path:/gdrive/TV        below a folder
+//	is:dir is:file         folders or files only
+//	sort:size-desc         relevance, name, size, modified or path, with
+//	                       -asc or -desc
+//	fuzzy:on               match words with typos
+type SearchQuery struct {
+	Terms      []string   `json:"terms,omitempty"`   // Words and phrases, all of which must match
+	Exclude    []string   `json:"exclude,omitempty"` // Words none of which may match
+	Sources    []string   `json:"sources,omitempty"`
+	Remotes    []string   `json:"remotes,omitempty"`
+	Exts       []string   `json:"exts,omitempty"`
+	MinSize    int64      `json:"minSize,omitempty"`
+	MaxSize    *int64     `json:"maxSize,omitempty"` // nil for no limit
+	After      *time.Time `json:"after,omitempty"`
+	Before     *time.Time `json:"before,omitempty"`
+	PathPrefix string     `json:"pathPrefix,omitempty"`
+	IsDir      *bool      `json:"isDir,omitempty"`
+	Sort       string     `json:"sort,omitempty"`
+	Desc       bool       `json:"desc,omitempty"`
+	Fuzzy      bool       `json:"fuzzy,omitempty"`
+}
+
+// SearchResult is one page of search results
+type SearchResult struct {
+	Files  []IndexedFile `json:"files"`
+	Total  int           `json:"total"`
+	Facets *SearchFacets `json:"facets,omitempty"`
+	// Fuzzy is set when no exact match was found and the results are typo
+	// tolerant matches
+	Fuzzy bool `json:"fuzzy"`
+}
+
+// SearchFacets counts all matches of a search by source, remote and
+// extension
+type SearchFacets struct {
+	Sources []FacetCount `json:"sources"`
+	Remotes []FacetCount `json:"remotes"`
+	Exts    []FacetCount `json:"exts"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// ParseSearchQuery parses q. Ages in after: and before: are relative to now.
+func ParseSearchQuery(q string, now time.Time) (*SearchQuery, error) {
+	sq := &SearchQuery{}
+	for _, field := range splitQuery(q) {
+		if strings.HasPrefix(field, `"`) {
+			if phrase := strings.Trim(field, `"`); len(SearchTokens(phrase)) > 0 {
+				sq.Terms = append(sq.Terms, phrase)
+			}
+			continue
+		}
+		if strings.HasPrefix(field, "-") && len(field) > 1 {
+			sq.Exclude = append(sq.Exclude, field[1:])
+			continue
+		}
+
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			if len(SearchTokens(field)) > 0 {
+				sq.Terms = append(sq.Terms, field)
+			}
+			continue
+		}
+		if err := sq.setFilter(strings.ToLower(key), value, now); err != nil {
+			return nil, err
+		}
+	}
+	return sq, nil
+}
+
+func (sq *SearchQuery) setFilter(key, value string, now time.Time) error {
+	switch key {
+	case "source":
+		for _, source := range splitList(value) {
+			sq.Sources = append(sq.Sources, strings.ToLower(source))
+		}
+	case "remote":
+		sq.Remotes = append(sq.Remotes, splitList(value)...)
+	case "ext":
+		for _, ext := range splitList(value) {
+			sq.Exts = append(sq.Exts, strings.ToLower(strings.TrimPrefix(ext, ".")))
+		}
+	case "size":
+		return sq.setSize(value)
+	case "after", "before":
+		t, err := parseSearchTime(value, now)
+		if err != nil {
+			return errors.New(errors.CodeValidationFailed, fmt.Sprintf("invalid %s: %q (use a date such as 2024-01-31 or an age such as 7d)", key, value))
+		}
+		if key == "after" {
+			sq.After = &t
+		} else {
+			sq.Before = &t
+		}
+	case "path":
+		sq.PathPrefix = path.Clean("/" + strings.Trim(value, `"`))
+	case "is":
+		var isDir bool
+		switch strings.ToLower(value) {
+		case "dir", "folder":
+			isDir = true
+		case "file":
+		default:
+			return errors.New(errors.CodeValidationFailed, "is: must be dir or file")
+		}
+		sq.IsDir = &isDir
+	case "sort":
+		return sq.SetSort(value)
+	case "fuzzy":
+		switch strings.ToLower(value) {
+		case "on", "true", "1":
+			sq.Fuzzy = true
+		case "off", "false", "0":
+			sq.Fuzzy = false
+		default:
+			return errors.New(errors.CodeValidationFailed, "fuzzy: must be on or off")
+		}
+	default:
+		// Not a filter, for example a time such as 10:30
+		sq.Terms = append(sq.Terms, key+":"+value)
+	}
+	return nil
+}
+
+// SetSort sets the order from a field name with an optional -asc or -desc.
+// Size and modified sort largest and newest first unless told otherwise.
+func (sq *SearchQuery) SetSort(value string) error {
+	field, dir, _ := strings.Cut(strings.ToLower(value), "-")
+	switch field {
+	case SearchSortRelevance, SearchSortName, SearchSortPath:
+		sq.Desc = false
+	case SearchSortSize, SearchSortModified:
+		sq.Desc = true
+	default:
+		return errors.New(errors.CodeValidationFailed, "sort must be relevance, name, size, modified or path")
+	}
+	switch dir {
+	case "":
+	case "asc":
+		sq.Desc = false
+	case "desc":
+		sq.Desc = true
+	default:
+		return errors.New(errors.CodeValidationFailed, "sort direction must be asc or desc")
+	}
+	sq.Sort = field
+	return nil
+}
+
+func (sq *SearchQuery) setSize(value string) error {
+	invalid := errors.New(errors.CodeValidationFailed, fmt.Sprintf("invalid size: %q (e.g. >1G, <=500M or 1G..4G)", value))
+
+	if lo, hi, ok := strings.Cut(value, ".."); ok {
+		var err error
+		if lo != "" {
+			if sq.MinSize, err = parseSearchSize(lo); err != nil {
+				return invalid
+			}
+		}
+		if hi != "" {
+			n, err := parseSearchSize(hi)
+			if err != nil {
+				return invalid
+			}
+			sq.MaxSize = &n
+		}
+		return nil
+	}
+
+	op := value[:len(value)-len(strings.TrimLeft(value, "<>="))]
+	n, err := parseSearchSize(value[len(op):])
+	if err != nil {
+		return invalid
+	}
+	switch op {
+	case ">":
+		sq.MinSize = n + 1
+	case ">=":
+		sq.MinSize = n
+	case "<":
+		n = max(n-1, 0)
+		sq.MaxSize = &n
+	case "<=":
+		sq.MaxSize = &n
+	case "", "=":
+		sq.MinSize, sq.MaxSize = n, &n
+	default:
+		return invalid
+	}
+	return nil
+}
+
+// Empty reports whether sq neither matches text nor filters anything
+func (sq *SearchQuery) Empty() bool {
+	return len(sq.Terms) == 0 && len(sq.Exclude) == 0 && len(sq.Sources) == 0 && len(sq.Remotes) == 0 &&
+		len(sq.Exts) == 0 && sq.MinSize == 0 && sq.MaxSize == nil && sq.After == nil &&
+		sq.Before == nil && sq.PathPrefix == "" && sq.IsDir == nil
+}
+
+// SearchTokens splits s into the lowercase words search matches on. Dots,
+// underscores, brackets and other punctuation separate words, so
+// "Some.Show.S01E02.1080p" is some, show, s01e02 and 1080p.
+func SearchTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// FileExt returns the lowercase extension of name without the dot, or ""
+func FileExt(name string) string {
+	ext := path.Ext(name)
+	if ext == name || len(ext) < 2 {
+		return ""
+	}
+	return strings.ToLower(ext[1:])
+}
+
+// splitQuery splits q on spaces, keeping quoted phrases, including a
+// quoted value after a filter key, together
+func splitQuery(q string) []string {
+	var fields []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseSearchSize parses a byte count with an optional binary K, M, G or T
+// suffix, as in 1.5G
+func parseSearchSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	mult := 1.0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * mult), nil
+}
+
+// parseSearchTime parses a date, an RFC 3339 time or an age in hours (h),
+// days (d) or weeks (w) before now
+func parseSearchTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, now.Location()); err == nil {
+		return t, nil
+	}
+	if len(s) < 2 {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	switch s[len(s)-1] {
+	case 'h':
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case 'd':
+		return now.AddDate(0, 0, -n), nil
+	case 'w':
+		return now.AddDate(0, 0, -7*n), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchTokens(t *testing.T) {
+	got := SearchTokens("Some.Show_[S01E02].1080p")
+	want := []string{"some", "show", "s01e02", "1080p"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchTokens = %v, want %v", got, want)
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	q, err := ParseSearchQuery(`show "some show" -sample remote:gdrive,onedrive ext:.MKV size:>1G after:7d path:/gdrive/TV/ is:file sort:size-asc 10:30`, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isFile := false
+	after := now.AddDate(0, 0, -7)
+	want := &SearchQuery{
+		Terms:      []string{"show", "some show", "10:30"},
+		Exclude:    []string{"sample"},
+		Remotes:    []string{"gdrive", "onedrive"},
+		Exts:       []string{"mkv"},
+		MinSize:    1<<30 + 1,
+		After:      &after,
+		PathPrefix: "/gdrive/TV",
+		IsDir:      &isFile,
+		Sort:       SearchSortSize,
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("ParseSearchQuery = %+v, want %+v", q, want)
+	}
+
+	q, _ = ParseSearchQuery("size:500M..2G sort:modified", now)
+	if q.MinSize != 500<<20 || q.MaxSize == nil || *q.MaxSize != 2<<30 || !q.Desc {
+		t.Errorf("range and default order: got %+v", q)
+	}
+
+	// Empty files are a filter of their own, not the absence of one
+	for _, empty := range []string{"size:0", "size:<1"} {
+		q, _ = ParseSearchQuery(empty, now)
+		if q.MaxSize == nil || *q.MaxSize != 0 || q.Empty() {
+			t.Errorf("%s: max size %v, empty %v", empty, q.MaxSize, q.Empty())
+		}
+	}
+
+	for _, bad := range []string{"size:big", "after:yesterday", "is:link", "sort:color", "sort:name-up"} {
+		if _, err := ParseSearchQuery(bad, now); err == nil {
+			t.Errorf("ParseSearchQuery(%q) should fail", bad)
+		}
+	}
+}
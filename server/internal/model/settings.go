@@ -2,9 +2,12 @@ package model
 
 import (
 	"gravity/internal/errors"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -18,6 +21,7 @@ type Settings struct {
 	Advanced   AdvancedSettings   `gorm:"serializer:json" json:"advanced"`
 	Automation AutomationSettings `gorm:"serializer:json" json:"automation"`
 	Search     SearchSettings     `gorm:"serializer:json" json:"search"`
+	Trash      TrashSettings      `gorm:"serializer:json" json:"trash"`
 	UpdatedAt  time.Time          `json:"updatedAt"`
 }
 
@@ -25,9 +29,51 @@ func (s *Settings) Validate() error {
 	if err := s.Download.Validate(); err != nil {
 		return err
 	}
+	if err := s.Network.Validate(); err != nil {
+		return err
+	}
+	if err := s.Upload.Validate(); err != nil {
+		return err
+	}
+	if err := s.Automation.Validate(); err != nil {
+		return err
+	}
+	if err := s.Vfs.Validate(); err != nil {
+		return err
+	}
+	if err := s.Trash.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
+// TrashSettings controls where deleted files go. Deletes on a remote move
+// the item to a .gravity-trash folder on the same remote, so no data is
+// transferred.
+type TrashSettings struct {
+	Enabled    bool   `json:"enabled"`
+	MaxAgeDays int    `json:"maxAgeDays" validate:"min=0" example:"30"` // 0 = keep until purged
+	MaxSize    string `json:"maxSize" example:"50G"`                    // Oldest items are purged beyond this; empty = unlimited
+	// Remotes whose deletes are permanent, such as ones without server-side
+	// moves. "local" covers the download dir.
+	BypassRemotes []string `json:"bypassRemotes" example:"s3"`
+}
+
+func (s *TrashSettings) Validate() error {
+	if s.MaxAgeDays < 0 {
+		return errors.New(errors.CodeValidationFailed, "trash maxAgeDays must not be negative")
+	}
+	if s.MaxSize != "" && s.MaxSize != "0" && !isValidBandwidth(s.MaxSize) {
+		return errors.New(errors.CodeValidationFailed, "invalid trash maxSize format (e.g. 50G)")
+	}
+	return nil
+}
+
+// Bypass reports whether deletes on remote skip the trash
+func (s *TrashSettings) Bypass(remote string) bool {
+	return !s.Enabled || slices.Contains(s.BypassRemotes, remote)
+}
+
 type SearchSettings struct {
 	Configs []RemoteIndexConfig `json:"configs"`
 }
@@ -61,6 +107,10 @@ type DownloadSettings struct {
 	CheckCertificate       bool   `json:"checkCertificate"`
 	AutoResume             bool   `json:"autoResume"`
 
+	// Reject downloads whose per-download options the target engine would
+	// silently drop, instead of accepting them with warnings
+	StrictOptions bool `json:"strictOptions"`
+
 	// Professional Enhancements
 	PreAllocateSpace bool   `json:"preAllocateSpace"`        // Prevent disk fragmentation
 	DiskCache        string `json:"diskCache" example:"32M"` // Reduce disk I/O overhead
@@ -101,6 +151,94 @@ type UploadSettings struct {
 	UploadBandwidth  string `json:"uploadBandwidth" example:"0"` // Limit specifically for uploads
 	MaxRetryAttempts int    `json:"maxRetryAttempts" validate:"min=0"`
 	ChunkSize        string `json:"chunkSize" example:"64M"`
+
+	// Stream HTTP and debrid downloads straight into the destination remote
+	// instead of staging them in DownloadDir first
+	StreamToDestination bool `json:"streamToDestination"`
+
+	// Extra remotes auto-uploads are replicated to alongside DefaultRemote
+	DefaultRemotes []string `json:"defaultRemotes" example:"gdrive:backup,s3:archive"`
+	// Destinations that must succeed before a download counts as complete
+	// and its local copy may be removed. 0 = all of them.
+	Quorum int `json:"quorum" validate:"min=0"`
+
+	// Compare each upload with the local copy before it counts as complete
+	// and before the local copy may be removed
+	VerifyUploads bool `json:"verifyUploads"`
+
+	// Per-remote tuning, matched on the destination remote name
+	RemoteProfiles []RemoteUploadProfile `json:"remoteProfiles"`
+
+	// What to do with an upload larger than the free space of a remote
+	// that reports its quota
+	QuotaCheck string `json:"quotaCheck" enums:"refuse,warn,off" default:"refuse"`
+}
+
+// Upload quota checks
+const (
+	QuotaCheckRefuse = "refuse" // Keep the upload queued for a while, then refuse it
+	QuotaCheckWarn   = "warn"   // Log a warning and upload anyway
+	QuotaCheckOff    = "off"
+)
+
+// RemoteUploadProfile tunes uploads to a single remote. Zero values fall
+// back to the global upload settings and the backend defaults.
+type RemoteUploadProfile struct {
+	Remote            string `json:"remote" example:"gdrive"`
+	ChunkSize         string `json:"chunkSize,omitempty" example:"128M"`
+	UploadConcurrency int    `json:"uploadConcurrency,omitempty" validate:"min=0" example:"4"` // Chunks of one file sent in parallel
+	Bandwidth         string `json:"bandwidth,omitempty" example:"10M"`                        // Cap for each upload job to this remote
+	Transfers         int    `json:"transfers,omitempty" validate:"min=0" example:"4"`         // Files sent in parallel
+}
+
+func (s *UploadSettings) Validate() error {
+	if s.UploadBandwidth != "" && s.UploadBandwidth != "0" && !isValidBandwidth(s.UploadBandwidth) {
+		return errors.New(errors.CodeValidationFailed, "invalid uploadBandwidth format (e.g. 10M, 500K)")
+	}
+	if s.ChunkSize != "" && !isValidBandwidth(s.ChunkSize) {
+		return errors.New(errors.CodeValidationFailed, "invalid chunkSize format (e.g. 64M)")
+	}
+	switch s.QuotaCheck {
+	case "", QuotaCheckRefuse, QuotaCheckWarn, QuotaCheckOff:
+	default:
+		return errors.New(errors.CodeValidationFailed, "quotaCheck must be refuse, warn or off")
+	}
+	for _, p := range s.RemoteProfiles {
+		if p.Remote == "" {
+			return errors.New(errors.CodeValidationFailed, "remote profile is missing a remote name")
+		}
+		if p.ChunkSize != "" && !isValidBandwidth(p.ChunkSize) {
+			return errors.New(errors.CodeValidationFailed, "invalid chunkSize format for remote "+p.Remote)
+		}
+		if p.Bandwidth != "" && p.Bandwidth != "0" && !isValidBandwidth(p.Bandwidth) {
+			return errors.New(errors.CodeValidationFailed, "invalid bandwidth format for remote "+p.Remote)
+		}
+		if p.UploadConcurrency < 0 || p.Transfers < 0 {
+			return errors.New(errors.CodeValidationFailed, "uploadConcurrency and transfers must not be negative for remote "+p.Remote)
+		}
+	}
+	return nil
+}
+
+// ProfileFor returns the upload profile for remote with ChunkSize defaulted
+// from the global setting
+func (s *UploadSettings) ProfileFor(remote string) RemoteUploadProfile {
+	p := RemoteUploadProfile{Remote: remote}
+	for _, rp := range s.RemoteProfiles {
+		if strings.TrimSuffix(rp.Remote, ":") == remote {
+			p = rp
+			break
+		}
+	}
+	if p.ChunkSize == "" {
+		p.ChunkSize = s.ChunkSize
+	}
+	return p
+}
+
+// DefaultDestinations returns DefaultRemote followed by DefaultRemotes
+func (s UploadSettings) DefaultDestinations() []string {
+	return compactDestinations(append([]string{s.DefaultRemote}, s.DefaultRemotes...))
 }
 
 type ProxyConfig struct {
@@ -111,6 +249,11 @@ type ProxyConfig struct {
 type NetworkSettings struct {
 	Proxies []Proxy `json:"proxies" gorm:"serializer:json"`
 
+	// Proxy pool rotation and health checks
+	ProxyStrategy      string `json:"proxyStrategy" enums:"failover,round-robin"`
+	ProxyCheckInterval int    `json:"proxyCheckInterval" example:"60"` // Seconds
+	ProxyCheckURL      string `json:"proxyCheckUrl" example:"https://www.google.com/generate_204"`
+
 	DNSOverHTTPS string `json:"dnsOverHttps" example:"https://cloudflare-dns.com/dns-query"`
 
 	// Professional Enhancements
@@ -118,6 +261,34 @@ type NetworkSettings struct {
 	TCPPortRange     string `json:"tcpPortRange" example:"6881-6999"`
 }
 
+func (s *NetworkSettings) Validate() error {
+	switch s.ProxyStrategy {
+	case "", "failover", "round-robin":
+	default:
+		return errors.New(errors.CodeValidationFailed, "proxyStrategy must be failover or round-robin")
+	}
+	if s.ProxyCheckInterval < 0 {
+		return errors.New(errors.CodeValidationFailed, "proxyCheckInterval must not be negative")
+	}
+	for _, p := range s.Proxies {
+		u, err := url.Parse(p.URL)
+		if err != nil || u.Host == "" {
+			return errors.New(errors.CodeValidationFailed, "invalid proxy url: "+p.URL)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return errors.New(errors.CodeValidationFailed, "unsupported proxy scheme: "+u.Scheme)
+		}
+		switch p.Type {
+		case "", ProxyTypeAll, ProxyTypeDownloads, ProxyTypeUploads, ProxyTypeMagnets, ProxyTypeProviders:
+		default:
+			return errors.New(errors.CodeValidationFailed, "invalid proxy type: "+p.Type)
+		}
+	}
+	return nil
+}
+
 type TorrentSettings struct {
 	SeedRatio  string `json:"seedRatio" example:"1.0"`
 	SeedTime   int    `json:"seedTime" example:"60"` // Minutes
@@ -141,6 +312,44 @@ type VfsSettings struct {
 	DirCacheTime       string `json:"dirCacheTime" example:"5m"`
 	PollInterval       string `json:"pollInterval" example:"1m"`
 	ReadChunkStreams   int    `json:"readChunkStreams" validate:"min=0"`
+	// Largest selection one archive download may hold; empty = 50G, "0" =
+	// unlimited
+	ArchiveMaxSize string `json:"archiveMaxSize" example:"50G"`
+
+	// Extra local folders shown in the file manager. The download dir is
+	// always shown as "local".
+	LocalRoots []LocalRoot `json:"localRoots"`
+}
+
+// LocalRootDownloads is the name of the download dir in the file manager
+const LocalRootDownloads = "local"
+
+// LocalRoot is a local folder shown in the file manager next to the remotes
+type LocalRoot struct {
+	Name string `json:"name" example:"media"`
+	Path string `json:"path" example:"/mnt/media"`
+}
+
+var localRootNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (s *VfsSettings) Validate() error {
+	if s.ArchiveMaxSize != "" && s.ArchiveMaxSize != "0" && !isValidBandwidth(s.ArchiveMaxSize) {
+		return errors.New(errors.CodeValidationFailed, "invalid archiveMaxSize format (e.g. 50G)")
+	}
+	seen := map[string]bool{LocalRootDownloads: true}
+	for _, r := range s.LocalRoots {
+		if !localRootNameRegex.MatchString(r.Name) {
+			return errors.New(errors.CodeValidationFailed, "local root names may only contain letters, digits, - and _: "+r.Name)
+		}
+		if seen[r.Name] {
+			return errors.New(errors.CodeValidationFailed, "duplicate or reserved local root name: "+r.Name)
+		}
+		seen[r.Name] = true
+		if !filepath.IsAbs(r.Path) {
+			return errors.New(errors.CodeValidationFailed, "local root path must be absolute: "+r.Path)
+		}
+	}
+	return nil
 }
 
 type AutomationSettings struct {
@@ -156,6 +365,20 @@ type AutomationSettings struct {
 	Categories []Category `json:"categories"`
 }
 
+func (s *AutomationSettings) Validate() error {
+	for _, c := range s.Categories {
+		if c.MaxDownloadSpeed != "" && c.MaxDownloadSpeed != "0" && !isValidBandwidth(c.MaxDownloadSpeed) {
+			return errors.New(errors.CodeValidationFailed, "invalid maxDownloadSpeed format for category "+c.Name)
+		}
+	}
+	for _, r := range s.Rules {
+		if r.UploadLimit != "" && r.UploadLimit != "0" && !isValidBandwidth(r.UploadLimit) {
+			return errors.New(errors.CodeValidationFailed, "invalid uploadLimit format for rule "+r.Label)
+		}
+	}
+	return nil
+}
+
 type Category struct {
 	ID         string   `json:"id" example:"cat_1"`
 	Name       string   `json:"name" example:"Movies"`
@@ -163,6 +386,28 @@ type Category struct {
 	Extensions []string `json:"extensions" example:"mp4,mkv,avi"`
 	Icon       string   `json:"icon" example:"video"`
 	IsDefault  bool     `json:"isDefault"`
+
+	// Shared speed cap for all downloads in this category (native engine)
+	MaxDownloadSpeed string `json:"maxDownloadSpeed,omitempty" example:"0"`
+}
+
+// CategoryFor returns the category whose extensions include filename's,
+// falling back to the default category. It returns nil if neither exists.
+func (s *AutomationSettings) CategoryFor(filename string) *Category {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	var def *Category
+	for i := range s.Categories {
+		c := &s.Categories[i]
+		for _, e := range c.Extensions {
+			if ext != "" && strings.ToLower(strings.TrimPrefix(e, ".")) == ext {
+				return c
+			}
+		}
+		if c.IsDefault && def == nil {
+			def = c
+		}
+	}
+	return def
 }
 
 type ScheduleRule struct {
@@ -178,6 +423,60 @@ type ScheduleRule struct {
 	UploadLimit   string `json:"uploadLimit" example:"100K"`
 }
 
+// ActiveRule returns the first enabled schedule rule whose window contains
+// now, or nil if scheduling is off or no rule matches. Windows whose end is
+// before their start run past midnight into the next day.
+func (s *AutomationSettings) ActiveRule(now time.Time) *ScheduleRule {
+	if !s.ScheduleEnabled {
+		return nil
+	}
+	minute := now.Hour()*60 + now.Minute()
+	today := int(now.Weekday())
+	yesterday := (today + 6) % 7
+
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		if !r.Enabled {
+			continue
+		}
+		start, okStart := parseClock(r.StartTime)
+		end, okEnd := parseClock(r.EndTime)
+		if !okStart || !okEnd {
+			continue
+		}
+
+		switch {
+		case start == end:
+			if r.onDay(today) {
+				return r
+			}
+		case start < end:
+			if r.onDay(today) && minute >= start && minute < end {
+				return r
+			}
+		default:
+			if (r.onDay(today) && minute >= start) || (r.onDay(yesterday) && minute < end) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// onDay reports whether the rule applies on weekday. No days means every day.
+func (r *ScheduleRule) onDay(weekday int) bool {
+	return len(r.Days) == 0 || slices.Contains(r.Days, weekday)
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
 type AdvancedSettings struct {
 	LogLevel     string `json:"logLevel" enums:"debug,info,warn,error"`
 	DebugMode    bool   `json:"debugMode"`
@@ -209,8 +508,12 @@ func DefaultSettings() *Settings {
 			ConcurrentUploads: 1,
 			MaxRetryAttempts:  3,
 			ChunkSize:         "64M",
+			QuotaCheck:        QuotaCheckRefuse,
+		},
+		Network: NetworkSettings{
+			ProxyStrategy:      "failover",
+			ProxyCheckInterval: 60,
 		},
-		Network: NetworkSettings{},
 		Torrent: TorrentSettings{
 			SeedRatio:  "1.0",
 			SeedTime:   1440,
@@ -234,5 +537,9 @@ func DefaultSettings() *Settings {
 			LogLevel:     "info",
 			SaveInterval: 60,
 		},
+		Trash: TrashSettings{
+			Enabled:    true,
+			MaxAgeDays: 30,
+		},
 	}
 }
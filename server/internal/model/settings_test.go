@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActiveRule(t *testing.T) {
+	s := AutomationSettings{
+		ScheduleEnabled: true,
+		Rules: []ScheduleRule{
+			{ID: "work", Enabled: true, Days: []int{1, 2, 3, 4, 5}, StartTime: "09:00", EndTime: "17:00", UploadLimit: "100K"},
+			{ID: "night", Enabled: true, Days: []int{5}, StartTime: "22:00", EndTime: "06:00", UploadLimit: "0"},
+			{ID: "off", Enabled: false, StartTime: "00:00", EndTime: "00:00"},
+		},
+	}
+
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"Monday work hours", at(1, 10, 0), "work"},
+		{"Window end is exclusive", at(1, 17, 0), ""},
+		{"Saturday is not a work day", at(6, 10, 0), ""},
+		{"Friday night", at(5, 23, 30), "night"},
+		{"Overnight window runs into Saturday", at(6, 5, 59), "night"},
+		{"Overnight window does not start on Saturday", at(6, 23, 0), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if r := s.ActiveRule(tt.now); r != nil {
+				got = r.ID
+			}
+			if got != tt.want {
+				t.Errorf("ActiveRule() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	s.ScheduleEnabled = false
+	if r := s.ActiveRule(at(1, 10, 0)); r != nil {
+		t.Errorf("ActiveRule() with scheduling disabled = %q, want nil", r.ID)
+	}
+}
+
+func TestUploadProfileFor(t *testing.T) {
+	s := UploadSettings{
+		ChunkSize:      "64M",
+		RemoteProfiles: []RemoteUploadProfile{{Remote: "s3:", UploadConcurrency: 8, Transfers: 2}},
+	}
+
+	p := s.ProfileFor("s3")
+	if p.UploadConcurrency != 8 || p.Transfers != 2 || p.ChunkSize != "64M" {
+		t.Errorf("ProfileFor(s3) = %+v", p)
+	}
+	if p := s.ProfileFor("gdrive"); p.Remote != "gdrive" || p.ChunkSize != "64M" || p.Transfers != 0 {
+		t.Errorf("ProfileFor(gdrive) = %+v", p)
+	}
+}
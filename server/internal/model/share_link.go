@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ShareLink gives people without an API key access to one file through the
+// public /s/{token} route
+type ShareLink struct {
+	ID           string     `json:"id" example:"sh_a1b2c3d4" gorm:"primaryKey"`
+	Token        string     `json:"token" example:"q7Yz3kM1bV9xW2cR8tN5pL0sD4fH6jA1" gorm:"uniqueIndex"`
+	URL          string     `json:"url" example:"/s/q7Yz3kM1bV9xW2cR8tN5pL0sD4fH6jA1" gorm:"-"`
+	Path         string     `json:"path" example:"/gdrive/movies/file.mkv"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	PasswordHash string     `json:"-"`
+	HasPassword  bool       `json:"hasPassword"`
+	MaxDownloads int        `json:"maxDownloads,omitempty" example:"10"` // 0 = unlimited
+	Downloads    int        `json:"downloads"`
+	NativeURL    string     `json:"nativeUrl,omitempty"` // Link made by the backend; the public route redirects to it
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+func (l *ShareLink) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// Exhausted reports whether the download limit has been reached
+func (l *ShareLink) Exhausted() bool {
+	return l.MaxDownloads > 0 && l.Downloads >= l.MaxDownloads
+}
@@ -0,0 +1,308 @@
+package model
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gravity/internal/errors"
+)
+
+// SecretMask replaces secret values in API responses. Sending it back in an
+// update keeps the stored value.
+const SecretMask = "********"
+
+const (
+	AuthTypeNone   = "none"
+	AuthTypeBasic  = "basic"
+	AuthTypeBearer = "bearer"
+)
+
+// SiteProfile holds credentials and request defaults applied to every
+// request for the hosts it matches
+type SiteProfile struct {
+	ID      string   `json:"id" gorm:"primaryKey" example:"sp_a1b2c3d4"`
+	Name    string   `json:"name" example:"My file host"`
+	Hosts   []string `json:"hosts" gorm:"serializer:json" example:"*.example.com"`
+	Enabled bool     `json:"enabled"`
+
+	AuthType string `json:"authType" enums:"none,basic,bearer"`
+	Username string `json:"username,omitempty"`
+
+	// Secrets, sealed into SealedSecrets at rest and masked in responses
+	Password string            `json:"password,omitempty" gorm:"-"`
+	Token    string            `json:"token,omitempty" gorm:"-"`
+	Headers  map[string]string `json:"headers,omitempty" gorm:"-"`
+	Cookies  []Cookie          `json:"cookies,omitempty" gorm:"-"`
+
+	UserAgent      string `json:"userAgent,omitempty"`
+	Referer        string `json:"referer,omitempty"`
+	MaxConnections int    `json:"maxConnections,omitempty" example:"4"` // Per-server connection cap
+
+	SealedSecrets string `json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ProfileSecrets is the part of a profile that is encrypted at rest
+type ProfileSecrets struct {
+	Password string            `json:"password,omitempty"`
+	Token    string            `json:"token,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Cookies  []Cookie          `json:"cookies,omitempty"`
+}
+
+type Cookie struct {
+	Domain            string     `json:"domain" example:".example.com"`
+	IncludeSubdomains bool       `json:"includeSubdomains"`
+	Path              string     `json:"path" example:"/"`
+	Secure            bool       `json:"secure"`
+	HTTPOnly          bool       `json:"httpOnly"`
+	Expires           *time.Time `json:"expires,omitempty"` // nil = session cookie
+	Name              string     `json:"name"`
+	Value             string     `json:"value"`
+}
+
+func (p *SiteProfile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New(errors.CodeValidationFailed, "name is required")
+	}
+	if len(p.Hosts) == 0 {
+		return errors.New(errors.CodeValidationFailed, "at least one host pattern is required")
+	}
+	for _, h := range p.Hosts {
+		if _, err := path.Match(strings.ToLower(h), ""); err != nil {
+			return errors.New(errors.CodeValidationFailed, "invalid host pattern: "+h)
+		}
+	}
+	switch p.AuthType {
+	case "", AuthTypeNone, AuthTypeBasic, AuthTypeBearer:
+	default:
+		return errors.New(errors.CodeValidationFailed, "authType must be none, basic or bearer")
+	}
+	if p.MaxConnections < 0 || p.MaxConnections > 16 {
+		return errors.New(errors.CodeValidationFailed, "maxConnections must be 0-16")
+	}
+	return nil
+}
+
+func (p *SiteProfile) Secrets() ProfileSecrets {
+	return ProfileSecrets{Password: p.Password, Token: p.Token, Headers: p.Headers, Cookies: p.Cookies}
+}
+
+func (p *SiteProfile) SetSecrets(s ProfileSecrets) {
+	p.Password, p.Token, p.Headers, p.Cookies = s.Password, s.Token, s.Headers, s.Cookies
+}
+
+// Matches reports whether the profile applies to host. Patterns are exact
+// hosts or globs like "*.example.com".
+func (p *SiteProfile) Matches(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range p.Hosts {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == host {
+			return true
+		}
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy safe to send to clients
+func (p *SiteProfile) Redacted() *SiteProfile {
+	out := *p
+	if out.Password != "" {
+		out.Password = SecretMask
+	}
+	if out.Token != "" {
+		out.Token = SecretMask
+	}
+	if len(p.Headers) > 0 {
+		out.Headers = make(map[string]string, len(p.Headers))
+		for k := range p.Headers {
+			out.Headers[k] = SecretMask
+		}
+	}
+	if len(p.Cookies) > 0 {
+		out.Cookies = make([]Cookie, len(p.Cookies))
+		for i, c := range p.Cookies {
+			c.Value = SecretMask
+			out.Cookies[i] = c
+		}
+	}
+	return &out
+}
+
+// MergeSecrets keeps stored secrets where the update left them empty or
+// sent back the mask
+func (p *SiteProfile) MergeSecrets(stored ProfileSecrets) {
+	if p.Password == "" || p.Password == SecretMask {
+		p.Password = stored.Password
+	}
+	if p.Token == "" || p.Token == SecretMask {
+		p.Token = stored.Token
+	}
+	if p.Headers == nil {
+		p.Headers = stored.Headers
+	} else {
+		for k, v := range p.Headers {
+			if v == SecretMask {
+				p.Headers[k] = stored.Headers[k]
+			}
+		}
+	}
+	if p.Cookies == nil {
+		p.Cookies = stored.Cookies
+	}
+}
+
+// RequestHeaders returns the headers the profile adds to a request for u
+func (p *SiteProfile) RequestHeaders(u *url.URL) map[string]string {
+	h := make(map[string]string, len(p.Headers)+4)
+	for k, v := range p.Headers {
+		h[k] = v
+	}
+
+	switch p.AuthType {
+	case AuthTypeBasic:
+		if p.Username != "" || p.Password != "" {
+			h["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(p.Username+":"+p.Password))
+		}
+	case AuthTypeBearer:
+		if p.Token != "" {
+			h["Authorization"] = "Bearer " + p.Token
+		}
+	}
+
+	if p.UserAgent != "" {
+		h["User-Agent"] = p.UserAgent
+	}
+	if p.Referer != "" {
+		h["Referer"] = p.Referer
+	}
+
+	if cookies := p.CookiesFor(u, time.Now()); len(cookies) > 0 {
+		parts := make([]string, len(cookies))
+		for i, c := range cookies {
+			parts[i] = c.Name + "=" + c.Value
+		}
+		h["Cookie"] = strings.Join(parts, "; ")
+	}
+	return h
+}
+
+// CookiesFor returns the unexpired cookies that would be sent to u
+func (p *SiteProfile) CookiesFor(u *url.URL, now time.Time) []Cookie {
+	if u == nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	reqPath := u.EscapedPath()
+	if reqPath == "" {
+		reqPath = "/"
+	}
+
+	var out []Cookie
+	for _, c := range p.Cookies {
+		if c.Expires != nil && now.After(*c.Expires) {
+			continue
+		}
+		if c.Secure && u.Scheme != "https" {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		if host != domain && !(c.IncludeSubdomains && strings.HasSuffix(host, "."+domain)) {
+			continue
+		}
+		if c.Path != "" && !strings.HasPrefix(reqPath, c.Path) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// ParseNetscapeCookies reads a cookies.txt file as written by browsers,
+// curl and yt-dlp
+func ParseNetscapeCookies(r io.Reader) ([]Cookie, error) {
+	var cookies []Cookie
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(text, "#HttpOnly_") {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields, got %d", line, len(fields))
+		}
+
+		c := Cookie{
+			Domain:            fields[0],
+			IncludeSubdomains: strings.EqualFold(fields[1], "TRUE"),
+			Path:              fields[2],
+			Secure:            strings.EqualFold(fields[3], "TRUE"),
+			HTTPOnly:          httpOnly,
+			Name:              fields[5],
+			Value:             fields[6],
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %q", line, fields[4])
+		}
+		if expires > 0 {
+			t := time.Unix(expires, 0)
+			c.Expires = &t
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, scanner.Err()
+}
+
+// WriteNetscapeCookies writes cookies in cookies.txt format
+func WriteNetscapeCookies(w io.Writer, cookies []Cookie) error {
+	if _, err := io.WriteString(w, "# Netscape HTTP Cookie File\n"); err != nil {
+		return err
+	}
+	for _, c := range cookies {
+		prefix := ""
+		if c.HTTPOnly {
+			prefix = "#HttpOnly_"
+		}
+		var expires int64
+		if c.Expires != nil {
+			expires = c.Expires.Unix()
+		}
+		_, err := fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			prefix, c.Domain, boolField(c.IncludeSubdomains), c.Path, boolField(c.Secure), expires, c.Name, c.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolField(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const cookiesTxt = `# Netscape HTTP Cookie File
+.example.com	TRUE	/	TRUE	0	session	abc
+#HttpOnly_files.example.com	FALSE	/dl	FALSE	1	expired	old
+other.org	FALSE	/	FALSE	0	id	xyz
+`
+
+func TestParseNetscapeCookiesRoundTrip(t *testing.T) {
+	cookies, err := ParseNetscapeCookies(strings.NewReader(cookiesTxt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 3 {
+		t.Fatalf("got %d cookies", len(cookies))
+	}
+	if !cookies[1].HTTPOnly || cookies[1].Expires == nil {
+		t.Errorf("httpOnly cookie parsed as %+v", cookies[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNetscapeCookies(&buf, cookies); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseNetscapeCookies(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(cookies) {
+		t.Fatalf("round trip: got %d cookies", len(again))
+	}
+	if c := again[1]; c.Name != "expired" || !c.HTTPOnly || c.Path != "/dl" || !c.Expires.Equal(*cookies[1].Expires) {
+		t.Errorf("round trip mismatch: %+v", c)
+	}
+
+	if _, err := ParseNetscapeCookies(strings.NewReader("bad line\n")); err == nil {
+		t.Error("expected error for malformed line")
+	}
+}
+
+func TestSiteProfileRequestHeaders(t *testing.T) {
+	cookies, _ := ParseNetscapeCookies(strings.NewReader(cookiesTxt))
+	p := &SiteProfile{
+		Hosts:    []string{"*.example.com"},
+		AuthType: AuthTypeBearer,
+		Token:    "t0k",
+		Cookies:  cookies,
+	}
+
+	if !p.Matches("files.example.com") || p.Matches("example.org") {
+		t.Error("host matching")
+	}
+
+	u, _ := url.Parse("https://files.example.com/dl/a.zip")
+	h := p.RequestHeaders(u)
+	if h["Authorization"] != "Bearer t0k" {
+		t.Errorf("authorization: %q", h["Authorization"])
+	}
+	// The expired and foreign cookies must not be sent
+	if h["Cookie"] != "session=abc" {
+		t.Errorf("cookie: %q", h["Cookie"])
+	}
+
+	// Secure cookies stay off plain http
+	u, _ = url.Parse("http://files.example.com/")
+	if got := p.CookiesFor(u, time.Now()); len(got) != 0 {
+		t.Errorf("secure cookie sent over http: %+v", got)
+	}
+}
+
+func TestSiteProfileRedactAndMerge(t *testing.T) {
+	stored := &SiteProfile{Password: "secret", Headers: map[string]string{"X-Key": "k"}}
+	r := stored.Redacted()
+	if r.Password != SecretMask || r.Headers["X-Key"] != SecretMask || stored.Password != "secret" {
+		t.Fatalf("redaction: %+v", r)
+	}
+
+	r.MergeSecrets(stored.Secrets())
+	if r.Password != "secret" || r.Headers["X-Key"] != "k" {
+		t.Errorf("merge: %+v", r)
+	}
+}
@@ -11,7 +11,7 @@ var ValidTransitions = map[DownloadStatus][]DownloadStatus{
 	StatusPaused:     {StatusWaiting, StatusError, StatusActive}, // Added StatusActive for Resume from Allocating/Resolving if needed? No, usually goes to Waiting/Active via Resume
 	StatusUploading:  {StatusComplete, StatusError, StatusWaiting},
 	StatusComplete:   {StatusWaiting, StatusUploading}, // Added StatusUploading for auto-upload
-	StatusError:      {StatusWaiting},                  // For retry
+	StatusError:      {StatusWaiting, StatusUploading}, // For retry
 	StatusProcessing: {StatusActive, StatusError, StatusWaiting},
 	"":               {StatusWaiting}, // Initial state
 }
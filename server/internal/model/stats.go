@@ -33,4 +33,6 @@ type Stats struct {
 	Tasks   TaskCounts  `json:"tasks" validate:"required"`
 	Usage   UsageStats  `json:"usage" validate:"required"`
 	System  SystemStats `json:"system" validate:"required"`
+	// Last known quota of each remote that reports one
+	Remotes []RemoteUsage `json:"remotes"`
 }
\ No newline at end of file
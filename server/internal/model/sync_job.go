@@ -0,0 +1,223 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gravity/internal/errors"
+	"gravity/internal/utils"
+)
+
+type SyncMode string
+
+const (
+	SyncModeCopy SyncMode = "copy" // Add new and changed files to the destination
+	SyncModeMove SyncMode = "move" // Like copy, then delete them from the source
+	SyncModeSync SyncMode = "sync" // Make the destination identical, deleting extra files
+)
+
+type SyncRunStatus string
+
+const (
+	SyncRunRunning   SyncRunStatus = "running"
+	SyncRunComplete  SyncRunStatus = "complete"
+	SyncRunError     SyncRunStatus = "error"
+	SyncRunCancelled SyncRunStatus = "cancelled"
+)
+
+// SyncJob is a named, scheduled copy, move or sync between two rclone paths.
+// Either end may be a local folder or a remote.
+type SyncJob struct {
+	ID          string      `json:"id" example:"sj_a1b2c3d4" gorm:"primaryKey"`
+	Name        string      `json:"name" example:"Photos backup" gorm:"uniqueIndex"`
+	Mode        SyncMode    `json:"mode" enums:"copy,move,sync"`
+	Source      string      `json:"source" example:"/data/photos"`
+	Destination string      `json:"destination" example:"gdrive:backup/photos"`
+	Schedule    string      `json:"schedule,omitempty" example:"0 3 * * *"` // Cron expression
+	IntervalMin int         `json:"intervalMin,omitempty" example:"60"`     // Used when Schedule is empty
+	Enabled     bool        `json:"enabled"`
+	DryRun      bool        `json:"dryRun"` // Report what would change without changing anything
+	Filters     SyncFilters `json:"filters" gorm:"serializer:json"`
+
+	LastRunAt  *time.Time    `json:"lastRunAt,omitempty"`
+	LastStatus SyncRunStatus `json:"lastStatus,omitempty" enums:"running,complete,error,cancelled"`
+	NextRunAt  *time.Time    `json:"nextRunAt,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+}
+
+func (j *SyncJob) Validate() error {
+	if strings.TrimSpace(j.Name) == "" {
+		return errors.New(errors.CodeValidationFailed, "name is required")
+	}
+	switch j.Mode {
+	case SyncModeCopy, SyncModeMove, SyncModeSync:
+	default:
+		return errors.New(errors.CodeValidationFailed, "mode must be copy, move or sync")
+	}
+	if j.Source == "" || j.Destination == "" {
+		return errors.New(errors.CodeValidationFailed, "source and destination are required")
+	}
+	if j.Source == j.Destination {
+		return errors.New(errors.CodeValidationFailed, "source and destination must differ")
+	}
+	if j.IntervalMin < 0 {
+		return errors.New(errors.CodeValidationFailed, "intervalMin must not be negative")
+	}
+	if j.Schedule != "" {
+		if _, err := utils.ParseCron(j.Schedule); err != nil {
+			return errors.New(errors.CodeValidationFailed, "invalid schedule: "+err.Error())
+		}
+	}
+	return j.Filters.Validate()
+}
+
+// Age filters are rclone durations such as "90m", "7d" or "1w2d"
+var (
+	ageRegex     = regexp.MustCompile(`^(\d+(\.\d+)?(ms|s|m|h|d|w|M|y))+$`)
+	ageTermRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)(ms|s|m|h|d|w|M|y)`)
+)
+
+var ageUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"M":  30 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+func (f *SyncFilters) Validate() error {
+	for _, v := range []string{f.MinSize, f.MaxSize} {
+		if v != "" && !isValidBandwidth(v) {
+			return errors.New(errors.CodeValidationFailed, "invalid size filter: "+v)
+		}
+	}
+
+	minAge, err := parseAge(f.MinAge)
+	if err != nil {
+		return errors.New(errors.CodeValidationFailed, "invalid minAge (e.g. 1h, 7d): "+f.MinAge)
+	}
+	maxAge, err := parseAge(f.MaxAge)
+	if err != nil {
+		return errors.New(errors.CodeValidationFailed, "invalid maxAge (e.g. 1h, 7d): "+f.MaxAge)
+	}
+	if minAge > 0 && maxAge > 0 && minAge >= maxAge {
+		return errors.New(errors.CodeValidationFailed, "minAge must be less than maxAge")
+	}
+
+	for _, patterns := range [][]string{f.Include, f.Exclude} {
+		for _, p := range patterns {
+			if err := validatePattern(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseAge returns the duration of an age filter, 0 if it is empty
+func parseAge(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if !ageRegex.MatchString(v) {
+		return 0, fmt.Errorf("invalid age %q", v)
+	}
+	var total time.Duration
+	for _, m := range ageTermRegex.FindAllStringSubmatch(v, -1) {
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(n * float64(ageUnits[m[2]]))
+	}
+	return total, nil
+}
+
+// validatePattern rejects include and exclude patterns rclone would fail on
+// once the job runs
+func validatePattern(p string) error {
+	if strings.TrimSpace(p) == "" {
+		return errors.New(errors.CodeValidationFailed, "filter patterns must not be empty")
+	}
+	if strings.ContainsAny(p, "\r\n") {
+		return errors.New(errors.CodeValidationFailed, "filter patterns must be on one line: "+p)
+	}
+	var braces, brackets int
+	for _, c := range p {
+		switch c {
+		case '{':
+			braces++
+		case '}':
+			braces--
+		case '[':
+			brackets++
+		case ']':
+			brackets--
+		}
+		if braces < 0 || brackets < 0 {
+			break
+		}
+	}
+	if braces != 0 || brackets != 0 {
+		return errors.New(errors.CodeValidationFailed, "unbalanced brackets in filter pattern: "+p)
+	}
+	return nil
+}
+
+// NextRun returns when the job should run next after t, or nil if it only
+// runs by hand
+func (j *SyncJob) NextRun(t time.Time) *time.Time {
+	if !j.Enabled {
+		return nil
+	}
+	var next time.Time
+	switch {
+	case j.Schedule != "":
+		c, err := utils.ParseCron(j.Schedule)
+		if err != nil {
+			return nil
+		}
+		next = c.Next(t)
+	case j.IntervalMin > 0:
+		next = t.Add(time.Duration(j.IntervalMin) * time.Minute)
+	}
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// SyncFilters limits which files a sync job touches. Patterns use rclone's
+// glob syntax; ages are durations such as "7d" and sizes look like "100M".
+type SyncFilters struct {
+	Include []string `json:"include,omitempty" example:"*.jpg,*.png"`
+	Exclude []string `json:"exclude,omitempty" example:".thumbnails/**"`
+	MinSize string   `json:"minSize,omitempty" example:"1K"`
+	MaxSize string   `json:"maxSize,omitempty" example:"10G"`
+	MinAge  string   `json:"minAge,omitempty" example:"1h"`  // Skip files modified more recently
+	MaxAge  string   `json:"maxAge,omitempty" example:"30d"` // Skip files modified longer ago
+}
+
+// SyncRun is one execution of a sync job
+type SyncRun struct {
+	ID          string        `json:"id" example:"sr_a1b2c3d4" gorm:"primaryKey"`
+	JobID       string        `json:"jobId" example:"sj_a1b2c3d4" gorm:"index"`
+	Status      SyncRunStatus `json:"status" enums:"running,complete,error,cancelled"`
+	Trigger     string        `json:"trigger" enums:"schedule,manual"`
+	DryRun      bool          `json:"dryRun"`
+	Bytes       int64         `json:"bytes"`
+	Transfers   int64         `json:"transfers"` // Files copied or moved
+	Checks      int64         `json:"checks"`    // Files compared and found up to date
+	Deletes     int64         `json:"deletes"`
+	Errors      int64         `json:"errors"`
+	Error       string        `json:"error,omitempty"`
+	StartedAt   time.Time     `json:"startedAt"`
+	CompletedAt *time.Time    `json:"completedAt,omitempty"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncFiltersValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		f    SyncFilters
+		ok   bool
+	}{
+		{"empty", SyncFilters{}, true},
+		{"ages", SyncFilters{MinAge: "1h", MaxAge: "1w2d"}, true},
+		{"bad min age", SyncFilters{MinAge: "yesterday"}, false},
+		{"bad max age", SyncFilters{MaxAge: "30"}, false},
+		{"min above max", SyncFilters{MinAge: "30d", MaxAge: "1d"}, false},
+		{"patterns", SyncFilters{Include: []string{"*.{jpg,png}"}, Exclude: []string{"[._]*"}}, true},
+		{"blank pattern", SyncFilters{Include: []string{" "}}, false},
+		{"open brace", SyncFilters{Exclude: []string{"*.{jpg,png"}}, false},
+		{"closing bracket first", SyncFilters{Exclude: []string{"]a["}}, false},
+		{"multi-line", SyncFilters{Include: []string{"a\nb"}}, false},
+		{"bad size", SyncFilters{MinSize: "big"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.f.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	if d, err := parseAge("1w2d"); err != nil || d != 9*24*time.Hour {
+		t.Errorf("parseAge(1w2d) = %v, %v", d, err)
+	}
+	if d, err := parseAge("1.5h"); err != nil || d != 90*time.Minute {
+		t.Errorf("parseAge(1.5h) = %v, %v", d, err)
+	}
+}
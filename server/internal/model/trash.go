@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// TrashItem is a deleted file or folder that can still be restored. Items
+// deleted in the file manager live in the VFS; files of deleted downloads
+// live on the local disk.
+type TrashItem struct {
+	ID           string    `json:"id" example:"tr_a1b2c3d4" gorm:"primaryKey"`
+	Name         string    `json:"name" example:"file.mkv"`
+	OriginalPath string    `json:"originalPath" example:"/gdrive/movies/file.mkv"`
+	TrashPath    string    `json:"trashPath" example:"/gdrive/.gravity-trash/tr_a1b2c3d4/file.mkv"`
+	Local        bool      `json:"local"` // Paths are on the local disk rather than in the VFS
+	Remote       string    `json:"remote" example:"gdrive"`
+	Size         int64     `json:"size"`
+	IsDir        bool      `json:"isDir"`
+	DownloadID   string    `json:"downloadId,omitempty" example:"d_a1b2c3d4"` // Set when deleted with a download
+	TrashedAt    time.Time `json:"trashedAt" gorm:"index"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// UploadJob is an entry in the persistent upload queue: the upload of one
+// download to one of its destinations. Jobs outlive restarts, so completed
+// destinations are never uploaded twice.
+type UploadJob struct {
+	ID          string       `json:"id" example:"u_a1b2c3d4" gorm:"primaryKey"`
+	DownloadID  string       `json:"downloadId" example:"d_a1b2c3d4" gorm:"index"`
+	Index       int          `json:"index"` // Position in Download.Uploads
+	Source      string       `json:"source" example:"/downloads/file.zip"`
+	Destination string       `json:"destination" example:"gdrive:movies"`
+	Status      UploadStatus `json:"status" gorm:"index" enums:"queued,running,paused,verifying,complete,error,mismatch,cancelled"`
+	Attempts    int          `json:"attempts"`
+	SpaceChecks int          `json:"spaceChecks,omitempty"` // Times it waited for space on the remote
+	NextRetryAt *time.Time   `json:"nextRetryAt,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Size        int64        `json:"size"`
+	Uploaded    int64        `json:"uploaded"`
+	Force       bool         `json:"force"`                              // Re-send files the destination already appears to have
+	VerifiedBy  string       `json:"verifiedBy,omitempty" example:"md5"` // How the remote copy was checked
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+	StartedAt   *time.Time   `json:"startedAt,omitempty"`
+	CompletedAt *time.Time   `json:"completedAt,omitempty"`
+
+	// Not saved in DB
+	Speed int64 `json:"speed" gorm:"-"`
+}
@@ -0,0 +1,199 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"gravity/internal/logger"
+	"gravity/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// Dialer opens outgoing connections the way NetworkSettings asks for:
+// names are resolved through the DoH endpoint (falling back to the system
+// resolver) and sockets are bound to the configured interface or source IP.
+type Dialer struct {
+	mu       sync.RWMutex
+	bind     string // interface name or source IP, empty for any
+	resolver *dohResolver
+	logger   *zap.Logger
+}
+
+var (
+	defaultDialer     *Dialer
+	defaultDialerOnce sync.Once
+)
+
+// DefaultDialer returns the process-wide dialer shared by the engines and API
+// clients
+func DefaultDialer() *Dialer {
+	defaultDialerOnce.Do(func() {
+		defaultDialer = NewDialer()
+	})
+	return defaultDialer
+}
+
+// Configure applies settings to the shared proxy pool and dialer
+func Configure(s *model.Settings) {
+	Default().Configure(s)
+	DefaultDialer().Configure(s)
+}
+
+func NewDialer() *Dialer {
+	return &Dialer{logger: logger.Component("NETWORK")}
+}
+
+func (d *Dialer) Configure(s *model.Settings) {
+	if s == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.bind = s.Network.InterfaceBinding
+
+	endpoint := s.Network.DNSOverHTTPS
+	if endpoint == "" {
+		d.resolver = nil
+	} else if d.resolver == nil || d.resolver.endpoint != endpoint {
+		// The DoH endpoint itself is reached over the bound interface too
+		d.resolver = newDoHResolver(endpoint, d.dialDirect)
+	}
+}
+
+// Active reports whether the dialer changes anything over a plain net.Dialer
+func (d *Dialer) Active() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.bind != "" || d.resolver != nil
+}
+
+// BindIP returns the source address for the configured binding, or nil when
+// unbound. An interface that is missing or has no address is an error, so
+// traffic never silently leaves through another NIC.
+func (d *Dialer) BindIP(ipv6 bool) (net.IP, error) {
+	d.mu.RLock()
+	bind := d.bind
+	d.mu.RUnlock()
+
+	if bind == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(bind); ip != nil {
+		return ip, nil
+	}
+
+	iface, err := net.InterfaceByName(bind)
+	if err != nil {
+		return nil, fmt.Errorf("interface binding: %w", err)
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("interface binding: %s is down", bind)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface binding: %w", err)
+	}
+
+	var fallback net.IP
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok || ipn.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipn.IP.To4() == nil) == ipv6 {
+			return ipn.IP, nil
+		}
+		if fallback == nil {
+			fallback = ipn.IP
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("interface binding: %s has no usable address", bind)
+}
+
+// LookupIP resolves host through DoH when configured, else the system resolver
+func (d *Dialer) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	d.mu.RLock()
+	r := d.resolver
+	d.mu.RUnlock()
+
+	if r != nil {
+		ips, err := r.LookupIP(ctx, host)
+		if err == nil {
+			return ips, nil
+		}
+		d.logger.Debug("doh lookup failed, using system resolver", zap.String("host", host), zap.Error(err))
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, nil
+}
+
+// DialContext resolves addr and connects from the bound address, trying each
+// resolved IP in turn
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := d.LookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, ip := range ips {
+		conn, err := d.dialDirect(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Dial satisfies proxy.Dialer
+func (d *Dialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// dialDirect connects to addr as given, only applying the binding
+func (d *Dialer) dialDirect(ctx context.Context, network, addr string) (net.Conn, error) {
+	nd := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	host, _, _ := net.SplitHostPort(addr)
+	ip := net.ParseIP(host)
+	local, err := d.BindIP(ip != nil && ip.To4() == nil)
+	if err != nil {
+		return nil, err
+	}
+	if local != nil {
+		switch network {
+		case "udp", "udp4", "udp6":
+			nd.LocalAddr = &net.UDPAddr{IP: local}
+		default:
+			nd.LocalAddr = &net.TCPAddr{IP: local}
+		}
+	}
+	return nd.DialContext(ctx, network, addr)
+}
@@ -0,0 +1,144 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	minCacheTTL = 30 * time.Second
+	maxCacheTTL = time.Hour
+)
+
+type dnsEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// dohResolver resolves names over DNS-over-HTTPS (RFC 8484) and caches the
+// answers for their TTL
+type dohResolver struct {
+	endpoint string
+	client   *http.Client
+
+	mu    sync.Mutex
+	cache map[string]dnsEntry
+}
+
+func newDoHResolver(endpoint string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *dohResolver {
+	return &dohResolver{
+		endpoint: endpoint,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dial, ForceAttemptHTTP2: true},
+		},
+		cache: make(map[string]dnsEntry),
+	}
+}
+
+func (r *dohResolver) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	r.mu.Lock()
+	if e, ok := r.cache[host]; ok && time.Now().Before(e.expires) {
+		r.mu.Unlock()
+		return e.ips, nil
+	}
+	r.mu.Unlock()
+
+	var (
+		ips []net.IP
+		ttl = maxCacheTTL
+	)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		found, t, err := r.query(ctx, host, qtype)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, found...)
+		if len(found) > 0 && t < ttl {
+			ttl = t
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("doh: no addresses for %s", host)
+	}
+
+	r.mu.Lock()
+	r.cache[host] = dnsEntry{ips: ips, expires: time.Now().Add(max(ttl, minCacheTTL))}
+	r.mu.Unlock()
+
+	return ips, nil
+}
+
+func (r *dohResolver) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]net.IP, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("doh: %s returned %s", r.endpoint, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var answer dnsmessage.Message
+	if err := answer.Unpack(body); err != nil {
+		return nil, 0, fmt.Errorf("doh: bad response: %w", err)
+	}
+	if answer.RCode != dnsmessage.RCodeSuccess && answer.RCode != dnsmessage.RCodeNameError {
+		return nil, 0, fmt.Errorf("doh: %s for %s", answer.RCode, host)
+	}
+
+	var (
+		ips []net.IP
+		ttl = maxCacheTTL
+	)
+	for _, rr := range answer.Answers {
+		switch b := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(b.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(b.AAAA[:]))
+		default:
+			continue
+		}
+		if t := time.Duration(rr.Header.TTL) * time.Second; t < ttl {
+			ttl = t
+		}
+	}
+	return ips, ttl, nil
+}
@@ -0,0 +1,57 @@
+package network
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDoHResolverCachesAnswers(t *testing.T) {
+	queries := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var q dnsmessage.Message
+		if err := q.Unpack(body); err != nil {
+			t.Fatalf("bad query: %v", err)
+		}
+		queries++
+
+		resp := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: q.ID, Response: true},
+			Questions: q.Questions,
+		}
+		if q.Questions[0].Type == dnsmessage.TypeA {
+			resp.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 7}},
+			}}
+		}
+		packed, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	defer srv.Close()
+
+	var d net.Dialer
+	r := newDoHResolver(srv.URL, d.DialContext)
+
+	for range 2 {
+		ips, err := r.LookupIP(context.Background(), "example.com")
+		if err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+		if len(ips) != 1 || !ips[0].Equal(net.IPv4(10, 0, 0, 7)) {
+			t.Fatalf("unexpected answer: %v", ips)
+		}
+	}
+
+	// A + AAAA for the first lookup, then served from cache
+	if queries != 2 {
+		t.Errorf("expected 2 queries, got %d", queries)
+	}
+}
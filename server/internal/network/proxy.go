@@ -0,0 +1,379 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gravity/internal/logger"
+	"gravity/internal/model"
+
+	"go.uber.org/zap"
+)
+
+const (
+	StrategyFailover   = "failover"
+	StrategyRoundRobin = "round-robin"
+
+	DefaultCheckInterval = 60 * time.Second
+	checkTimeout         = 10 * time.Second
+)
+
+// ProxyStatus is the health of one proxy in the pool
+type ProxyStatus struct {
+	URL         string     `json:"url"`
+	Type        string     `json:"type"`
+	Hosts       []string   `json:"hosts,omitempty"`
+	Healthy     bool       `json:"healthy"`
+	LatencyMs   int64      `json:"latencyMs"`
+	LastChecked *time.Time `json:"lastChecked,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type proxyEntry struct {
+	model.Proxy
+	healthy     bool
+	latency     time.Duration
+	lastChecked time.Time
+	lastErr     string
+}
+
+// ProxyPool routes traffic through the configured proxies by purpose and
+// host rules, rotating or failing over between them, and takes proxies that
+// fail health checks out of rotation.
+type ProxyPool struct {
+	mu       sync.RWMutex
+	entries  []*proxyEntry
+	strategy string
+	checkURL string
+	interval time.Duration
+	counter  atomic.Uint64
+
+	wake   chan struct{}
+	logger *zap.Logger
+}
+
+var (
+	defaultPool     *ProxyPool
+	defaultPoolOnce sync.Once
+)
+
+// Default returns the process-wide pool shared by the engines and API clients
+func Default() *ProxyPool {
+	defaultPoolOnce.Do(func() {
+		defaultPool = NewProxyPool()
+	})
+	return defaultPool
+}
+
+func NewProxyPool() *ProxyPool {
+	return &ProxyPool{
+		strategy: StrategyFailover,
+		interval: DefaultCheckInterval,
+		wake:     make(chan struct{}, 1),
+		logger:   logger.Component("NETWORK"),
+	}
+}
+
+// Configure replaces the pool from settings. Health of proxies that are
+// still configured is kept.
+func (p *ProxyPool) Configure(s *model.Settings) {
+	if s == nil {
+		return
+	}
+	ns := s.Network
+
+	p.mu.Lock()
+	known := make(map[string]*proxyEntry, len(p.entries))
+	for _, e := range p.entries {
+		known[e.URL] = e
+	}
+
+	p.entries = p.entries[:0:0]
+	for _, px := range ns.Proxies {
+		if px.URL == "" {
+			continue
+		}
+		e := &proxyEntry{Proxy: px, healthy: true}
+		if old, ok := known[px.URL]; ok {
+			e.healthy, e.latency, e.lastChecked, e.lastErr = old.healthy, old.latency, old.lastChecked, old.lastErr
+		}
+		p.entries = append(p.entries, e)
+	}
+
+	p.strategy = ns.ProxyStrategy
+	if p.strategy == "" {
+		p.strategy = StrategyFailover
+	}
+	p.checkURL = ns.ProxyCheckURL
+	p.interval = DefaultCheckInterval
+	if ns.ProxyCheckInterval > 0 {
+		p.interval = time.Duration(ns.ProxyCheckInterval) * time.Second
+	}
+	p.mu.Unlock()
+
+	// Check new proxies right away
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Select returns the proxy URL to use for the given purpose and target (a
+// URL, host or remote name), or "" to connect directly.
+func (p *ProxyPool) Select(purpose, target string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	healthy := func(e *proxyEntry) bool { return e.healthy }
+	return p.pick(p.entries, purpose, target, healthy)
+}
+
+// SelectFrom picks from an explicit list (e.g. per-download proxies) using
+// the same rules, trusting the pool's health data for proxies it knows.
+func (p *ProxyPool) SelectFrom(proxies []model.Proxy, purpose, target string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	health := make(map[string]bool, len(p.entries))
+	for _, e := range p.entries {
+		health[e.URL] = e.healthy
+	}
+
+	entries := make([]*proxyEntry, 0, len(proxies))
+	for _, px := range proxies {
+		if px.URL != "" {
+			entries = append(entries, &proxyEntry{Proxy: px})
+		}
+	}
+	healthy := func(e *proxyEntry) bool {
+		ok, known := health[e.URL]
+		return ok || !known
+	}
+	return p.pick(entries, purpose, target, healthy)
+}
+
+// SelectFor uses the per-download proxies when any are set, else the pool
+func (p *ProxyPool) SelectFor(proxies []model.Proxy, purpose, target string) string {
+	if len(proxies) > 0 {
+		return p.SelectFrom(proxies, purpose, target)
+	}
+	return p.Select(purpose, target)
+}
+
+func (p *ProxyPool) pick(entries []*proxyEntry, purpose, target string, healthy func(*proxyEntry) bool) string {
+	host := hostOf(target)
+
+	// Host rules win over catch-all proxies
+	var specific, generic []*proxyEntry
+	for _, e := range entries {
+		if e.Type != "" && e.Type != model.ProxyTypeAll && e.Type != purpose {
+			continue
+		}
+		if len(e.Hosts) == 0 {
+			generic = append(generic, e)
+		} else if matchHost(e.Hosts, host) {
+			specific = append(specific, e)
+		}
+	}
+	candidates := specific
+	if len(candidates) == 0 {
+		candidates = generic
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	var alive []*proxyEntry
+	for _, e := range candidates {
+		if healthy(e) {
+			alive = append(alive, e)
+		}
+	}
+	// Everything is down: keep using the proxies rather than silently
+	// leaking traffic onto a direct connection
+	if len(alive) == 0 {
+		return candidates[0].URL
+	}
+
+	if p.strategy == StrategyRoundRobin {
+		n := p.counter.Add(1) - 1
+		return alive[n%uint64(len(alive))].URL
+	}
+	return alive[0].URL
+}
+
+// Status returns the health of every proxy in the pool
+func (p *ProxyPool) Status() []ProxyStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make([]ProxyStatus, 0, len(p.entries))
+	for _, e := range p.entries {
+		st := ProxyStatus{
+			URL:       redact(e.URL),
+			Type:      e.Type,
+			Hosts:     e.Hosts,
+			Healthy:   e.healthy,
+			LatencyMs: e.latency.Milliseconds(),
+			Error:     e.lastErr,
+		}
+		if !e.lastChecked.IsZero() {
+			t := e.lastChecked
+			st.LastChecked = &t
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// Run health-checks the pool until ctx is cancelled
+func (p *ProxyPool) Run(ctx context.Context) {
+	for {
+		p.checkAll(ctx)
+
+		p.mu.RLock()
+		interval := p.interval
+		p.mu.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (p *ProxyPool) checkAll(ctx context.Context) {
+	p.mu.RLock()
+	entries := make([]*proxyEntry, len(p.entries))
+	copy(entries, p.entries)
+	checkURL := p.checkURL
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		wg.Add(1)
+		go func(e *proxyEntry) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := checkProxy(ctx, e.URL, checkURL)
+			latency := time.Since(start)
+
+			p.mu.Lock()
+			wasHealthy := e.healthy
+			e.healthy = err == nil
+			e.latency = latency
+			e.lastChecked = time.Now()
+			e.lastErr = ""
+			if err != nil {
+				e.lastErr = err.Error()
+			}
+			p.mu.Unlock()
+
+			if wasHealthy && err != nil {
+				p.logger.Warn("proxy taken out of rotation", zap.String("proxy", redact(e.URL)), zap.Error(err))
+			} else if !wasHealthy && err == nil {
+				p.logger.Info("proxy back in rotation", zap.String("proxy", redact(e.URL)))
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// checkProxy fetches checkURL through the proxy, or just connects to the
+// proxy when no check URL is configured
+func checkProxy(ctx context.Context, proxyURL, checkURL string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	if checkURL == "" {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", hostPort(u))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(u)}}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusProxyAuthRequired || resp.StatusCode >= 500 {
+		return fmt.Errorf("check returned %s", resp.Status)
+	}
+	return nil
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func hostOf(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	// Bare host[:port] or rclone remote name
+	target = strings.TrimSuffix(target, ":")
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		target = h
+	}
+	return strings.ToLower(target)
+}
+
+func matchHost(patterns []string, host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == host {
+			return true
+		}
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Redacted()
+	}
+	return rawURL
+}
@@ -0,0 +1,75 @@
+package network
+
+import (
+	"testing"
+
+	"gravity/internal/model"
+)
+
+func newTestPool(strategy string, proxies ...model.Proxy) *ProxyPool {
+	p := NewProxyPool()
+	p.Configure(&model.Settings{Network: model.NetworkSettings{
+		Proxies:       proxies,
+		ProxyStrategy: strategy,
+	}})
+	return p
+}
+
+func TestProxyPoolSelectByType(t *testing.T) {
+	p := newTestPool(StrategyFailover,
+		model.Proxy{URL: "http://dl:8080", Type: model.ProxyTypeDownloads},
+		model.Proxy{URL: "socks5://mag:1080", Type: model.ProxyTypeMagnets},
+	)
+
+	if got := p.Select(model.ProxyTypeDownloads, "https://example.com/f"); got != "http://dl:8080" {
+		t.Errorf("downloads: got %q", got)
+	}
+	if got := p.Select(model.ProxyTypeMagnets, ""); got != "socks5://mag:1080" {
+		t.Errorf("magnets: got %q", got)
+	}
+	if got := p.Select(model.ProxyTypeUploads, "gdrive"); got != "" {
+		t.Errorf("uploads should connect directly, got %q", got)
+	}
+}
+
+func TestProxyPoolHostRules(t *testing.T) {
+	p := newTestPool(StrategyFailover,
+		model.Proxy{URL: "http://any:8080", Type: model.ProxyTypeAll},
+		model.Proxy{URL: "http://cdn:8080", Type: model.ProxyTypeAll, Hosts: []string{"*.example.com"}},
+	)
+
+	if got := p.Select(model.ProxyTypeDownloads, "https://files.example.com/a.zip"); got != "http://cdn:8080" {
+		t.Errorf("host rule: got %q", got)
+	}
+	if got := p.Select(model.ProxyTypeDownloads, "https://other.org/a.zip"); got != "http://any:8080" {
+		t.Errorf("catch-all: got %q", got)
+	}
+}
+
+func TestProxyPoolFailoverAndRoundRobin(t *testing.T) {
+	p := newTestPool(StrategyFailover,
+		model.Proxy{URL: "http://a:8080"},
+		model.Proxy{URL: "http://b:8080"},
+	)
+	p.entries[0].healthy = false
+
+	if got := p.Select(model.ProxyTypeDownloads, ""); got != "http://b:8080" {
+		t.Errorf("failover: got %q", got)
+	}
+
+	// All down: stay on the pool instead of going direct
+	p.entries[1].healthy = false
+	if got := p.Select(model.ProxyTypeDownloads, ""); got != "http://a:8080" {
+		t.Errorf("all down: got %q", got)
+	}
+
+	rr := newTestPool(StrategyRoundRobin,
+		model.Proxy{URL: "http://a:8080"},
+		model.Proxy{URL: "http://b:8080"},
+	)
+	first := rr.Select(model.ProxyTypeDownloads, "")
+	second := rr.Select(model.ProxyTypeDownloads, "")
+	if first == second {
+		t.Errorf("round-robin returned %q twice", first)
+	}
+}
@@ -141,7 +141,7 @@ func (p *AllDebridProvider) Resolve(ctx context.Context, rawURL string, headers
 	}
 
 	// Fetch ModTime
-	if meta, err := provider.FetchMetadata(ctx, p.client, result.Data.Link); err == nil {
+	if meta, err := provider.FetchMetadata(ctx, p.client, result.Data.Link, nil); err == nil {
 		res.ModTime = meta.ModTime
 	}
 
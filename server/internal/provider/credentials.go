@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"maps"
+	"sync/atomic"
+)
+
+// SiteCredentials supplies the headers and cookies of the site profile
+// matching a URL
+type SiteCredentials interface {
+	HeadersFor(rawURL string) map[string]string
+	// WriteCookieFile writes the matching cookies to a Netscape cookies.txt
+	// file. path is empty when no profile has cookies for rawURL.
+	WriteCookieFile(rawURL string) (path string, cleanup func(), err error)
+}
+
+type credentialsHolder struct{ SiteCredentials }
+
+var credentials atomic.Pointer[credentialsHolder]
+
+// SetCredentials installs the site profile source used by all providers
+func SetCredentials(c SiteCredentials) {
+	credentials.Store(&credentialsHolder{c})
+}
+
+// SiteHeaders returns headers with the matching site profile's headers added.
+// Headers already present win over the profile.
+func SiteHeaders(rawURL string, headers map[string]string) map[string]string {
+	h := credentials.Load()
+	if h == nil || h.SiteCredentials == nil {
+		return headers
+	}
+	site := h.HeadersFor(rawURL)
+	if len(site) == 0 {
+		return headers
+	}
+	out := make(map[string]string, len(site)+len(headers))
+	maps.Copy(out, site)
+	maps.Copy(out, headers)
+	return out
+}
+
+// SiteCookieFile writes the matching site profile's cookies for tools that
+// take a cookies.txt file. cleanup is always safe to call.
+func SiteCookieFile(rawURL string) (string, func(), error) {
+	h := credentials.Load()
+	if h == nil || h.SiteCredentials == nil {
+		return "", func() {}, nil
+	}
+	return h.WriteCookieFile(rawURL)
+}
@@ -119,7 +119,7 @@ func (p *DebridLinkProvider) Resolve(ctx context.Context, rawURL string, headers
 	}
 
 	// Fetch ModTime
-	if meta, err := provider.FetchMetadata(ctx, p.client, result.Value.DownloadLink); err == nil {
+	if meta, err := provider.FetchMetadata(ctx, p.client, result.Value.DownloadLink, nil); err == nil {
 		res.ModTime = meta.ModTime
 	}
 
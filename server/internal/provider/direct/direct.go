@@ -44,7 +44,8 @@ func (p *DirectProvider) Priority() int {
 }
 
 func (p *DirectProvider) Resolve(ctx context.Context, rawURL string, headers map[string]string) (*provider.ResolveResult, error) {
-	c := client.New(ctx, "", client.WithTimeout(30*time.Second))
+	c := client.New(ctx, "", client.WithTimeout(30*time.Second),
+		client.WithProxyFor(model.ProxyTypeDownloads, rawURL))
 	if strings.HasPrefix(rawURL, "magnet:") {
 		return &provider.ResolveResult{
 			URL:  rawURL,
@@ -52,7 +53,7 @@ func (p *DirectProvider) Resolve(ctx context.Context, rawURL string, headers map
 		}, nil
 	}
 
-	return provider.FetchMetadata(ctx, c, rawURL)
+	return provider.FetchMetadata(ctx, c, rawURL, headers)
 }
 
 func (p *DirectProvider) Test(ctx context.Context) (*model.AccountInfo, error) {
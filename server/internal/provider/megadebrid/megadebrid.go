@@ -255,7 +255,7 @@ func (p *MegaDebridProvider) Resolve(ctx context.Context, rawURL string, headers
 	}
 
 	// Fetch ModTime
-	if meta, err := provider.FetchMetadata(ctx, p.client, result.DebridLink); err == nil {
+	if meta, err := provider.FetchMetadata(ctx, p.client, result.DebridLink, nil); err == nil {
 		res.ModTime = meta.ModTime
 	}
 
@@ -179,7 +179,7 @@ func (p *PremiumizeProvider) Resolve(ctx context.Context, rawURL string, headers
 	}
 
 	// Fetch ModTime
-	if meta, err := provider.FetchMetadata(ctx, p.client, result.Location); err == nil {
+	if meta, err := provider.FetchMetadata(ctx, p.client, result.Location, nil); err == nil {
 		res.ModTime = meta.ModTime
 	}
 
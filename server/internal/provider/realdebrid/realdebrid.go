@@ -163,7 +163,7 @@ func (p *RealDebridProvider) Resolve(ctx context.Context, rawURL string, headers
 	}
 
 	// Fetch ModTime
-	if meta, err := provider.FetchMetadata(ctx, p.client, result.Link); err == nil {
+	if meta, err := provider.FetchMetadata(ctx, p.client, result.Link, nil); err == nil {
 		res.ModTime = meta.ModTime
 	}
 
@@ -0,0 +1,65 @@
+package remote
+
+import (
+	"context"
+
+	"gravity/internal/engine/rclone"
+	"gravity/internal/model"
+	"gravity/internal/provider"
+)
+
+// RemoteProvider resolves downloads whose source is a file or folder on an
+// rclone remote ("gdrive:movies/file.mkv") or an sftp/ftp URL
+type RemoteProvider struct {
+}
+
+func New() *RemoteProvider {
+	return &RemoteProvider{}
+}
+
+func (p *RemoteProvider) Name() string        { return "remote" }
+func (p *RemoteProvider) DisplayName() string { return "Cloud Remote" }
+func (p *RemoteProvider) Type() model.ProviderType {
+	return model.ProviderTypeDirect
+}
+
+func (p *RemoteProvider) ConfigSchema() []provider.ConfigField {
+	return []provider.ConfigField{}
+}
+
+func (p *RemoteProvider) Configure(ctx context.Context, config map[string]string) error {
+	return nil
+}
+
+func (p *RemoteProvider) IsConfigured() bool {
+	return true
+}
+
+func (p *RemoteProvider) Supports(rawURL string) bool {
+	return rclone.IsRemoteSource(rawURL)
+}
+
+func (p *RemoteProvider) Priority() int {
+	return 1
+}
+
+func (p *RemoteProvider) Resolve(ctx context.Context, rawURL string, headers map[string]string) (*provider.ResolveResult, error) {
+	info, err := rclone.StatSource(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &provider.ResolveResult{
+		URL:           rawURL,
+		Name:          info.Name,
+		Size:          info.Size,
+		ModTime:       info.ModTime,
+		ExecutionMode: model.ExecutionModeRemote,
+	}, nil
+}
+
+func (p *RemoteProvider) Test(ctx context.Context) (*model.AccountInfo, error) {
+	return &model.AccountInfo{
+		Username:  "Local",
+		IsPremium: false,
+	}, nil
+}
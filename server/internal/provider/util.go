@@ -15,10 +15,15 @@ import (
 	"github.com/rclone/rclone/lib/rest"
 )
 
-func FetchMetadata(ctx context.Context, c *client.Client, rawURL string) (*ResolveResult, error) {
+// FetchMetadata reads the filename, size and modification time of rawURL,
+// sending headers plus those of the matching site profile
+func FetchMetadata(ctx context.Context, c *client.Client, rawURL string, headers map[string]string) (*ResolveResult, error) {
+	headers = SiteHeaders(rawURL, headers)
+
 	headOpts := rest.Opts{
-		Method:  "HEAD",
-		RootURL: rawURL,
+		Method:       "HEAD",
+		RootURL:      rawURL,
+		ExtraHeaders: headers,
 	}
 
 	resp, err := c.Call(ctx, &headOpts)
@@ -31,11 +36,14 @@ func FetchMetadata(ctx context.Context, c *client.Client, rawURL string) (*Resol
 	}
 
 	getOpts := rest.Opts{
-		Method:  "GET",
-		RootURL: rawURL,
-		ExtraHeaders: map[string]string{
-			"Range": "bytes=0-0",
-		},
+		Method:       "GET",
+		RootURL:      rawURL,
+		ExtraHeaders: map[string]string{"Range": "bytes=0-0"},
+	}
+	for k, v := range headers {
+		if _, ok := getOpts.ExtraHeaders[k]; !ok {
+			getOpts.ExtraHeaders[k] = v
+		}
 	}
 
 	resp, err = c.Call(ctx, &getOpts)
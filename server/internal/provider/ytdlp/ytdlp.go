@@ -129,8 +129,22 @@ func (p *YtDlpProvider) Resolve(ctx context.Context, rawURL string, headers map[
 		"-o", "%(title)s.%(ext)s", // Force filename format
 	}
 
+	// Site profile cookies go through a cookie jar file so yt-dlp can scope
+	// them per domain across the redirects and API calls it makes
+	cookieFile, cleanup, err := provider.SiteCookieFile(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write cookie file: %w", err)
+	}
+	defer cleanup()
+	if cookieFile != "" {
+		args = append(args, "--cookies", cookieFile)
+	}
+
 	// Pass input headers to yt-dlp
-	for k, v := range headers {
+	for k, v := range provider.SiteHeaders(rawURL, headers) {
+		if cookieFile != "" && strings.EqualFold(k, "Cookie") {
+			continue
+		}
 		args = append(args, "--add-header", fmt.Sprintf("%s:%s", k, v))
 	}
 
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"time"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/logger"
+	"gravity/internal/store"
+
+	rfs "github.com/rclone/rclone/fs"
+	"go.uber.org/zap"
+)
+
+// DefaultArchiveMaxSize caps the content of one archive download when the
+// archiveMaxSize setting is empty
+const DefaultArchiveMaxSize int64 = 50 << 30
+
+// ArchiveService streams files and folders of the VFS to a client as a
+// single zip or tar, reading each file as it is written without staging
+type ArchiveService struct {
+	storage      engine.StorageEngine
+	settingsRepo *store.SettingsRepo
+	logger       *zap.Logger
+}
+
+// Archive is the list of entries of an archive, ready to be written
+type Archive struct {
+	Entries []ArchiveEntry
+	Size    int64 // Total size of the files
+}
+
+type ArchiveEntry struct {
+	Path    string // Virtual path to read from
+	Name    string // Name inside the archive
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+func NewArchiveService(storage engine.StorageEngine, settingsRepo *store.SettingsRepo) *ArchiveService {
+	return &ArchiveService{
+		storage:      storage,
+		settingsRepo: settingsRepo,
+		logger:       logger.Component("ARCHIVE"),
+	}
+}
+
+// MaxSize returns the archive limit of the settings
+func (s *ArchiveService) MaxSize(ctx context.Context) int64 {
+	if s.settingsRepo == nil {
+		return DefaultArchiveMaxSize
+	}
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil || settings.Vfs.ArchiveMaxSize == "" {
+		return DefaultArchiveMaxSize
+	}
+	var size rfs.SizeSuffix
+	if err := size.Set(settings.Vfs.ArchiveMaxSize); err != nil {
+		return DefaultArchiveMaxSize
+	}
+	if size <= 0 {
+		return math.MaxInt64
+	}
+	return int64(size)
+}
+
+// Prepare walks paths, recursing into folders, and fails if their content is
+// larger than maxSize or the archive limit of the settings, whichever is
+// smaller. maxSize 0 applies the settings alone. Each path is stored under
+// its base name; a name selected twice gets a number.
+func (s *ArchiveService) Prepare(ctx context.Context, paths []string, maxSize int64) (*Archive, error) {
+	if limit := s.MaxSize(ctx); maxSize <= 0 || maxSize > limit {
+		maxSize = limit
+	}
+	a := &Archive{}
+	used := make(map[string]bool)
+
+	for _, p := range paths {
+		info, err := s.storage.Stat(ctx, p)
+		if err != nil {
+			return nil, apperrors.NewNotFound("file", p)
+		}
+
+		base := info.Name
+		if base == "" || base == "/" {
+			base = "root"
+		}
+		name := base
+		for i := 1; used[name]; i++ {
+			name = numberedName(base, i)
+		}
+		used[name] = true
+
+		if err := s.add(ctx, a, p, name, info, maxSize); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func (s *ArchiveService) add(ctx context.Context, a *Archive, p, name string, info *engine.FileInfo, maxSize int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.Entries = append(a.Entries, ArchiveEntry{
+		Path:    p,
+		Name:    name,
+		Size:    info.Size,
+		ModTime: info.ModTime,
+		IsDir:   info.IsDir,
+	})
+	if !info.IsDir {
+		a.Size += info.Size
+		if a.Size > maxSize {
+			return apperrors.New(apperrors.CodeValidationFailed,
+				fmt.Sprintf("selection is larger than the archive limit of %d bytes", maxSize))
+		}
+		return nil
+	}
+
+	children, err := s.storage.List(ctx, p)
+	if err != nil {
+		return err
+	}
+	for i := range children {
+		child := &children[i]
+		if err := s.add(ctx, a, child.Path, path.Join(name, child.Name), child, maxSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write streams a to w as a "zip" (stored, not compressed) or "tar". It
+// stops when ctx is cancelled.
+func (s *ArchiveService) Write(ctx context.Context, w io.Writer, a *Archive, format string) error {
+	var err error
+	switch format {
+	case "tar":
+		err = s.writeTar(ctx, w, a)
+	default:
+		err = s.writeZip(ctx, w, a)
+	}
+	if err != nil && ctx.Err() == nil {
+		s.logger.Warn("archive download failed", zap.Error(err))
+	}
+	return err
+}
+
+func (s *ArchiveService) writeZip(ctx context.Context, w io.Writer, a *Archive) error {
+	zw := zip.NewWriter(w)
+	for _, e := range a.Entries {
+		hdr := &zip.FileHeader{
+			Name:     e.Name,
+			Method:   zip.Store,
+			Modified: e.ModTime,
+		}
+		if e.IsDir {
+			hdr.Name += "/"
+			hdr.SetMode(fs.ModeDir | 0755)
+		} else {
+			hdr.SetMode(0644)
+		}
+
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if !e.IsDir {
+			if err := s.copyFile(ctx, fw, e); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+func (s *ArchiveService) writeTar(ctx context.Context, w io.Writer, a *Archive) error {
+	tw := tar.NewWriter(w)
+	for _, e := range a.Entries {
+		hdr := &tar.Header{
+			Name:    e.Name,
+			ModTime: e.ModTime,
+			Mode:    0644,
+			Size:    e.Size,
+			Format:  tar.FormatPAX,
+		}
+		if e.IsDir {
+			hdr.Name += "/"
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0755
+			hdr.Size = 0
+		} else {
+			hdr.Typeflag = tar.TypeReg
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !e.IsDir {
+			if err := s.copyFile(ctx, tw, e); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+// copyFile writes exactly the size recorded for e, since tar headers are
+// written before the data
+func (s *ArchiveService) copyFile(ctx context.Context, w io.Writer, e ArchiveEntry) error {
+	rc, err := s.storage.Open(ctx, e.Path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", e.Path, err)
+	}
+	defer rc.Close()
+
+	if _, err := io.CopyN(w, &ctxReader{ctx: ctx, r: rc}, e.Size); err != nil {
+		return fmt.Errorf("read %s: %w", e.Path, err)
+	}
+	return nil
+}
+
+// ctxReader fails reads once ctx is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(b)
+}
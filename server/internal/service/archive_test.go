@@ -0,0 +1,211 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"gravity/internal/store"
+)
+
+func newTestArchiveStorage() *memStorage {
+	m := newMemStorage()
+	m.files["/gdrive/photos/a.jpg"] = []byte("aaaa")
+	m.files["/gdrive/photos/trip/b.jpg"] = []byte("bb")
+	m.files["/gdrive/notes.txt"] = []byte("notes")
+	m.files["/onedrive/notes.txt"] = []byte("other notes")
+	m.dirs["/gdrive/photos/empty"] = true
+	return m
+}
+
+func TestArchivePrepare(t *testing.T) {
+	ctx := context.Background()
+	s := NewArchiveService(newTestArchiveStorage(), nil)
+
+	a, err := s.Prepare(ctx, []string{"/gdrive/photos", "/gdrive/notes.txt", "/onedrive/notes.txt"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range a.Entries {
+		names = append(names, e.Name)
+	}
+	want := []string{"photos", "photos/a.jpg", "photos/empty", "photos/trip", "photos/trip/b.jpg", "notes.txt", "notes (1).txt"}
+	if len(names) != len(want) {
+		t.Fatalf("entries = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("entries = %v, want %v", names, want)
+		}
+	}
+	if a.Size != 4+2+5+11 {
+		t.Errorf("size = %d", a.Size)
+	}
+
+	if _, err := s.Prepare(ctx, []string{"/gdrive/photos"}, 5); err == nil {
+		t.Error("selection above maxSize was accepted")
+	}
+	if _, err := s.Prepare(ctx, []string{"/gdrive/missing"}, 0); err == nil {
+		t.Error("missing path was accepted")
+	}
+}
+
+func TestArchiveMaxSizeSetting(t *testing.T) {
+	ctx := context.Background()
+	db := newTestStore(t)
+	setr := store.NewSettingsRepo(db.GetDB())
+	s := NewArchiveService(newTestArchiveStorage(), setr)
+
+	if got := s.MaxSize(ctx); got != DefaultArchiveMaxSize {
+		t.Errorf("MaxSize() = %d without a setting", got)
+	}
+
+	settings, _ := setr.Get(ctx)
+	settings.Vfs.ArchiveMaxSize = "1K"
+	if err := setr.Save(ctx, settings); err != nil {
+		t.Fatal(err)
+	}
+	s.storage.(*memStorage).files["/gdrive/big.bin"] = make([]byte, 2048)
+	if _, err := s.Prepare(ctx, []string{"/gdrive/big.bin"}, 0); err == nil {
+		t.Error("selection above the setting was accepted")
+	}
+	// A request cannot raise the limit of the settings
+	if _, err := s.Prepare(ctx, []string{"/gdrive/big.bin"}, 1<<20); err == nil {
+		t.Error("request raised the archive limit")
+	}
+	if _, err := s.Prepare(ctx, []string{"/gdrive/photos"}, 0); err != nil {
+		t.Errorf("selection below the setting: %v", err)
+	}
+}
+
+func TestArchiveZip(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestArchiveStorage()
+	s := NewArchiveService(storage, nil)
+	a, err := s.Prepare(ctx, []string{"/gdrive/photos", "/gdrive/notes.txt"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Write(ctx, &buf, a, "zip"); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, f := range zr.File {
+		if f.Method != zip.Store {
+			t.Errorf("%s is compressed", f.Name)
+		}
+		if !f.Modified.Equal(storage.modTime) {
+			t.Errorf("%s modified %v, want %v", f.Name, f.Modified, storage.modTime)
+		}
+		if f.FileInfo().IsDir() {
+			got[f.Name] = "dir"
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(data)
+	}
+
+	want := map[string]string{
+		"photos/":           "dir",
+		"photos/a.jpg":      "aaaa",
+		"photos/empty/":     "dir",
+		"photos/trip/":      "dir",
+		"photos/trip/b.jpg": "bb",
+		"notes.txt":         "notes",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("zip holds %v", got)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s = %q, want %q", name, got[name], content)
+		}
+	}
+}
+
+func TestArchiveTar(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestArchiveStorage()
+	s := NewArchiveService(storage, nil)
+	a, err := s.Prepare(ctx, []string{"/gdrive/photos"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Write(ctx, &buf, a, "tar"); err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(&buf)
+	got := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hdr.ModTime.Equal(storage.modTime) {
+			t.Errorf("%s modified %v, want %v", hdr.Name, hdr.ModTime, storage.modTime)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			got[hdr.Name] = "dir"
+			continue
+		}
+		data, _ := io.ReadAll(tr)
+		got[hdr.Name] = string(data)
+	}
+
+	want := map[string]string{
+		"photos/":           "dir",
+		"photos/a.jpg":      "aaaa",
+		"photos/empty/":     "dir",
+		"photos/trip/":      "dir",
+		"photos/trip/b.jpg": "bb",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("tar holds %v", got)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s = %q, want %q", name, got[name], content)
+		}
+	}
+}
+
+func TestArchiveCancel(t *testing.T) {
+	s := NewArchiveService(newTestArchiveStorage(), nil)
+	a, err := s.Prepare(context.Background(), []string{"/gdrive/photos"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, format := range []string{"zip", "tar"} {
+		if err := s.Write(ctx, io.Discard, a, format); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: Write() = %v after cancel", format, err)
+		}
+	}
+	if _, err := s.Prepare(ctx, []string{"/gdrive/photos"}, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Prepare() = %v after cancel", err)
+	}
+}
@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"strconv"
@@ -14,6 +16,7 @@ import (
 	"time"
 
 	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
 	"gravity/internal/event"
 	"gravity/internal/logger"
 	"gravity/internal/model"
@@ -22,6 +25,7 @@ import (
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 const (
@@ -37,6 +41,8 @@ type DownloadService struct {
 	uploadEngine engine.UploadEngine
 	bus          *event.Bus
 	provider     *ProviderService
+	profiles     *SiteProfileService
+	trash        *TrashService
 	logger       *zap.Logger
 
 	// Lifecycle context
@@ -122,7 +128,7 @@ func (pb *progressBuffer) flushAll(ctx context.Context) {
 	}
 }
 
-func NewDownloadService(repo *store.DownloadRepo, settingsRepo *store.SettingsRepo, eng engine.DownloadEngine, ue engine.UploadEngine, bus *event.Bus, provider *ProviderService) *DownloadService {
+func NewDownloadService(repo *store.DownloadRepo, settingsRepo *store.SettingsRepo, eng engine.DownloadEngine, ue engine.UploadEngine, bus *event.Bus, provider *ProviderService, profiles *SiteProfileService, trash *TrashService) *DownloadService {
 	s := &DownloadService{
 		repo:           repo,
 		settingsRepo:   settingsRepo,
@@ -130,6 +136,8 @@ func NewDownloadService(repo *store.DownloadRepo, settingsRepo *store.SettingsRe
 		uploadEngine:   ue,
 		bus:            bus,
 		provider:       provider,
+		profiles:       profiles,
+		trash:          trash,
 		logger:         logger.Component("DOWNLOAD"),
 		progressBuffer: newProgressBuffer(repo),
 		stop:           make(chan struct{}),
@@ -149,6 +157,16 @@ func (s *DownloadService) Create(ctx context.Context, d *model.Download) (*model
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	// Credentials in the URL would be stored, returned and indexed with
+	// the download, so they go to the site profile of the host instead
+	if s.profiles != nil {
+		stripped, err := s.profiles.StoreURLCredentials(ctx, d.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store URL credentials: %w", err)
+		}
+		d.URL = stripped
+	}
+
 	res, providerName, err := s.provider.Resolve(ctx, d.URL, d.Headers, d.TorrentData)
 	if err != nil {
 		s.logger.Warn("failed to resolve URL", zap.String("url", d.URL), zap.Error(err))
@@ -163,6 +181,7 @@ func (s *DownloadService) Create(ctx context.Context, d *model.Download) (*model
 	}
 	d.CreatedAt = time.Now()
 	d.UpdatedAt = time.Now()
+	d.SetDestinations(d.AllDestinations())
 
 	// Do NOT merge global settings here. d.Dir, d.Split, etc., should remain
 	// as provided in the request (zero/nil if using defaults).
@@ -214,6 +233,13 @@ func (s *DownloadService) Create(ctx context.Context, d *model.Download) (*model
 		}
 	}
 
+	settings, _ := s.settingsRepo.Get(ctx)
+	s.resolveStreaming(settings, d)
+
+	if err := s.checkOptions(ctx, d); err != nil {
+		return nil, err
+	}
+
 	if err := s.repo.Create(ctx, d); err != nil {
 		s.logger.Error("failed to save download to DB", zap.String("id", d.ID), zap.Error(err))
 		return nil, err
@@ -256,6 +282,131 @@ func (s *DownloadService) Create(ctx context.Context, d *model.Download) (*model
 	return d, nil
 }
 
+// checkOptions compares the requested per-download overrides with what the
+// target engine honors. Dropped options are either rejected (strict mode) or
+// reported back as warnings on the download.
+func (s *DownloadService) checkOptions(ctx context.Context, d *model.Download) error {
+	opts := engine.FromModel(d)
+	url := d.ResolvedURL
+	if url == "" {
+		url = d.URL
+	}
+
+	caps := s.engine.Capabilities()
+	if r, ok := s.engine.(interface {
+		CapabilitiesFor(url string, opts engine.DownloadOptions) engine.Capabilities
+	}); ok {
+		caps = r.CapabilitiesFor(url, opts)
+	}
+
+	dropped := engine.UnsupportedOptions(opts, caps)
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	settings, _ := s.settingsRepo.Get(ctx)
+	if settings != nil && settings.Download.StrictOptions {
+		return apperrors.New(apperrors.CodeValidationFailed,
+			fmt.Sprintf("%s engine does not support options: %s", caps.Engine, strings.Join(dropped, ", ")))
+	}
+
+	s.logger.Warn("download options not supported by engine",
+		zap.String("engine", caps.Engine),
+		zap.Strings("options", dropped))
+
+	for _, key := range dropped {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("option %q is not supported by the %s engine and will be ignored", key, caps.Engine))
+	}
+	// Fall back to staging on local disk and uploading afterwards
+	if slices.Contains(dropped, engine.OptStreamToDestination) {
+		d.StreamToDestination = new(bool)
+	}
+	return nil
+}
+
+// resolveStreaming decides whether the download is written straight into its
+// destination. Torrents are always staged since pieces arrive out of order.
+func (s *DownloadService) resolveStreaming(settings *model.Settings, d *model.Download) {
+	explicit := d.StreamToDestination != nil
+	stream := explicit && *d.StreamToDestination
+	if !explicit && settings != nil {
+		stream = settings.Upload.StreamToDestination
+	}
+
+	// Streaming needs the destination up front, so take the auto-upload
+	// default now rather than on completion
+	if stream && d.Destination == "" && settings != nil && settings.Upload.AutoUpload {
+		d.SetDestinations(settings.Upload.DefaultDestinations())
+	}
+
+	var reason string
+	switch {
+	case !stream:
+	case d.Destination == "":
+		reason = "it has no destination"
+	case len(d.Destinations) > 1:
+		reason = "it is uploaded to more than one destination"
+	case d.ExecutionMode == model.ExecutionModeMagnet:
+		reason = "torrents are always staged on local disk"
+	}
+	if reason != "" {
+		stream = false
+		if explicit {
+			d.Warnings = append(d.Warnings, "download will not be streamed to its destination: "+reason)
+		}
+	}
+	d.StreamToDestination = &stream
+}
+
+// PreviewPath expands a path template for sample. If sample.ID is set the
+// stored download is used instead; blank sample fields get example values.
+func (s *DownloadService) PreviewPath(ctx context.Context, tmpl string, sample *model.Download) (*engine.TemplatePreview, error) {
+	d := sample
+	if sample.ID != "" {
+		var err error
+		if d, err = s.repo.Get(ctx, sample.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apperrors.NewNotFound("download", sample.ID)
+			}
+			return nil, err
+		}
+	} else {
+		if d.URL == "" {
+			d.URL = "https://example.com/files/Big.Buck.Bunny.2008.1080p.mkv"
+		}
+		if d.Filename == "" {
+			d.Filename = path.Base(strings.SplitN(d.URL, "?", 2)[0])
+		}
+		if d.Provider == "" {
+			d.Provider = "direct"
+		}
+		d.ID = "d_" + uuid.New().String()[:8]
+		d.CreatedAt = time.Now()
+	}
+
+	settings, _ := s.settingsRepo.Get(ctx)
+	preview := engine.NewOptionResolver(settings).Preview(tmpl, d)
+	return &preview, nil
+}
+
+// targetDir is where the engine writes: the expanded destination itself
+// for downloads copied straight to a remote, else the local download path
+func targetDir(d *model.Download, eff engine.EffectiveOptions) string {
+	if d.WritesToDestination() {
+		return eff.Destination
+	}
+	return eff.LocalPath
+}
+
+// applySiteProfile adds the credentials of the matching site profile to the
+// engine options only, so secrets never end up in the downloads table
+func (s *DownloadService) applySiteProfile(d *model.Download, opts *engine.DownloadOptions) {
+	if s.profiles == nil || d.ResolvedURL == "" {
+		return
+	}
+	s.profiles.ApplyOptions(d.ResolvedURL, opts)
+}
+
 // startDebridDownload downloads files via Provider direct links
 func (s *DownloadService) startDebridDownload(ctx context.Context, d *model.Download) {
 	// Resolve options to get the effective directory
@@ -283,9 +434,9 @@ func (s *DownloadService) startDebridDownload(ctx context.Context, d *model.Down
 		// Add to aria2
 		// Prepare execution options
 		execOpts := effectiveOpts.DownloadOptions
-		execOpts.DownloadDir = effectiveOpts.LocalPath // Enforce resolved path
-		execOpts.Filename = file.Path                  // Preserve structure
-		execOpts.ModTime = &resolved.ModTime           // Pass modtime to engine
+		execOpts.DownloadDir = targetDir(d, effectiveOpts) // Enforce resolved path
+		execOpts.Filename = file.Path                      // Preserve structure
+		execOpts.ModTime = &resolved.ModTime               // Pass modtime to engine
 
 		// Use gid:index format so handleProgress can attribute progress to the correct file
 		parentGID := fmt.Sprintf("%016s", d.ID[2:])
@@ -477,8 +628,9 @@ func (s *DownloadService) Retry(ctx context.Context, id string) error {
 	effectiveOpts := resolver.Resolve(engine.FromModel(d))
 
 	execOpts := effectiveOpts.DownloadOptions
-	execOpts.DownloadDir = effectiveOpts.LocalPath
+	execOpts.DownloadDir = targetDir(d, effectiveOpts)
 	execOpts.Filename = d.Filename
+	s.applySiteProfile(d, &execOpts)
 
 	engineID, err := s.engine.Add(ctx, d.ResolvedURL, execOpts)
 	if err != nil {
@@ -561,13 +713,13 @@ func (s *DownloadService) UpdatePriority(ctx context.Context, id string, priorit
 	return nil
 }
 
-func (s *DownloadService) Update(ctx context.Context, id string, filename, destination *string, priority, maxRetries *int) error {
+func (s *DownloadService) Update(ctx context.Context, id string, filename, destination *string, destinations []string, priority, maxRetries *int) error {
 	d, err := s.repo.Get(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	if (filename != nil || destination != nil) && d.Status == model.StatusActive {
+	if (filename != nil || destination != nil || destinations != nil) && d.Status == model.StatusActive {
 		return fmt.Errorf("cannot update filename or destination while download is active")
 	}
 
@@ -577,8 +729,17 @@ func (s *DownloadService) Update(ctx context.Context, id string, filename, desti
 		}
 		d.Filename = *filename
 	}
+	if destinations != nil {
+		d.SetDestinations(destinations)
+	}
 	if destination != nil {
-		d.Destination = *destination
+		// Replaces the primary destination only
+		dests := d.AllDestinations()
+		if len(dests) == 0 {
+			dests = []string{""}
+		}
+		dests[0] = *destination
+		d.SetDestinations(dests)
 	}
 	if priority != nil {
 		d.Priority = *priority
@@ -606,17 +767,14 @@ func (s *DownloadService) Delete(ctx context.Context, id string, deleteFiles boo
 
 	// 1. Resolve paths for deletion before stopping engine
 	var pathsToDelete []string
-	if deleteFiles {
-		// We must resolve the effective directory if it was default
-		// in order to delete the correct path.
-		if d.Dir == "" {
-			settings, _ := s.settingsRepo.Get(ctx)
-			resolver := engine.NewOptionResolver(settings)
-			eff := resolver.Resolve(engine.FromModel(d))
-			pathsToDelete = append(pathsToDelete, eff.LocalPath)
-		} else {
-			pathsToDelete = append(pathsToDelete, d.Dir)
-		}
+	// Nothing was written locally for downloads copied straight to a remote
+	if deleteFiles && !d.WritesToDestination() {
+		// We must resolve the effective directory if it was default or a
+		// template in order to delete the correct path.
+		settings, _ := s.settingsRepo.Get(ctx)
+		resolver := engine.NewOptionResolver(settings)
+		eff := resolver.Resolve(engine.FromModel(d))
+		pathsToDelete = append(pathsToDelete, eff.LocalPath)
 
 		// If active/paused in engine, query it for current path
 		if d.EngineID != "" {
@@ -643,15 +801,19 @@ func (s *DownloadService) Delete(ctx context.Context, id string, deleteFiles boo
 	}
 
 	// 3. Also stop upload if active
-	if d.Status == model.StatusUploading && d.UploadJobID != "" {
-		s.uploadEngine.Cancel(ctx, d.UploadJobID)
+	for _, u := range d.Uploads {
+		if u.Status == model.UploadStatusRunning {
+			s.uploadEngine.Cancel(ctx, u.JobID)
+		}
 	}
 
-	// 4. Delete files from disk
+	// 4. Move files to the trash, or delete them if it is bypassed
 	if deleteFiles {
 		for _, path := range pathsToDelete {
 			if len(path) > 5 { // Safety check
-				os.RemoveAll(path)
+				if err := s.trash.DeleteLocal(ctx, path, d.ID); err != nil {
+					s.logger.Warn("failed to delete download files", zap.String("path", path), zap.Error(err))
+				}
 				os.Remove(path + ".aria2")
 			}
 		}
@@ -665,6 +827,13 @@ func (s *DownloadService) Delete(ctx context.Context, id string, deleteFiles boo
 		return err
 	}
 
+	// Lets the upload queue drop jobs of this download
+	s.bus.PublishLifecycle(event.LifecycleEvent{
+		Type:      event.DownloadDeleted,
+		ID:        id,
+		Timestamp: time.Now(),
+	})
+
 	return nil
 }
 
@@ -894,10 +1063,10 @@ func (s *DownloadService) executeDownload(d *model.Download) {
 	effectiveOpts := resolver.Resolve(engine.FromModel(d))
 
 	execOpts := effectiveOpts.DownloadOptions
-	execOpts.DownloadDir = effectiveOpts.LocalPath // Enforce resolved path
+	execOpts.DownloadDir = targetDir(d, effectiveOpts) // Enforce resolved path
 
 	// Check disk space before submission
-	if d.Size > 0 {
+	if d.Size > 0 && !d.WritesToDestination() {
 		free, err := s.checkDiskSpace(execOpts.DownloadDir)
 		if err == nil && free < uint64(d.Size) {
 			s.logger.Warn("insufficient disk space",
@@ -955,6 +1124,7 @@ func (s *DownloadService) executeDownload(d *model.Download) {
 	execOpts.Size = d.Size
 	execOpts.ID = gid
 	execOpts.ModTime = d.FileModTime
+	s.applySiteProfile(d, &execOpts)
 
 	engineID, err := s.engine.Add(ctx, d.ResolvedURL, execOpts)
 
@@ -1132,7 +1302,7 @@ func (s *DownloadService) updateAggregateProgress(ctx context.Context, d *model.
 	}
 
 	if filesComplete == len(d.Files) && len(d.Files) > 0 && d.Status != model.StatusComplete && d.Status != model.StatusUploading {
-		if d.Destination != "" {
+		if d.Destination != "" && !d.WritesToDestination() {
 			d.TransitionTo(model.StatusUploading)
 		} else {
 			d.TransitionTo(model.StatusComplete)
@@ -1222,7 +1392,7 @@ func (s *DownloadService) handleComplete(engineID string, filePath string) {
 			}
 		}
 
-		if d.Destination != "" {
+		if d.Destination != "" && !d.WritesToDestination() {
 			d.TransitionTo(model.StatusUploading)
 		} else {
 			d.TransitionTo(model.StatusComplete)
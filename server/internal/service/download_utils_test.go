@@ -1,9 +1,16 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
+
+	"gravity/internal/model"
+
+	"github.com/rclone/rclone/fs/fserrors"
 )
 
 func TestIsRetryableError(t *testing.T) {
@@ -52,3 +59,108 @@ func TestCalculateBackoff(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveStreaming(t *testing.T) {
+	s := &DownloadService{}
+	settings := model.DefaultSettings()
+	settings.Upload.StreamToDestination = true
+	settings.Upload.AutoUpload = true
+	settings.Upload.DefaultRemote = "gdrive:incoming"
+
+	d := &model.Download{ExecutionMode: model.ExecutionModeDirect}
+	s.resolveStreaming(settings, d)
+	if !d.WritesToDestination() || d.Destination != "gdrive:incoming" {
+		t.Errorf("default: stream=%v dest=%q", *d.StreamToDestination, d.Destination)
+	}
+
+	yes := true
+	m := &model.Download{ExecutionMode: model.ExecutionModeMagnet, Destination: "gdrive:", StreamToDestination: &yes}
+	s.resolveStreaming(settings, m)
+	if m.WritesToDestination() || len(m.Warnings) != 1 {
+		t.Errorf("magnet should be staged with a warning: %+v", m.Warnings)
+	}
+}
+
+func TestSettleUploads(t *testing.T) {
+	newDownload := func(statuses ...model.UploadStatus) *model.Download {
+		d := &model.Download{Status: model.StatusUploading}
+		for i, st := range statuses {
+			d.Uploads = append(d.Uploads, model.UploadTarget{Destination: fmt.Sprintf("r%d:", i), Status: st, Error: "boom"})
+		}
+		return d
+	}
+	const (
+		running  = model.UploadStatusRunning
+		complete = model.UploadStatusComplete
+		failed   = model.UploadStatusError
+	)
+
+	tests := []struct {
+		name     string
+		statuses []model.UploadStatus
+		quorum   int
+		want     uploadOutcome
+		status   model.DownloadStatus
+	}{
+		{"all required, one running", []model.UploadStatus{complete, running}, 0, uploadsPending, model.StatusUploading},
+		{"all required, all done", []model.UploadStatus{complete, complete}, 0, uploadsComplete, model.StatusComplete},
+		{"all required, one failed", []model.UploadStatus{complete, failed}, 0, uploadsFailed, model.StatusError},
+		{"quorum reached early", []model.UploadStatus{complete, complete, running}, 2, uploadsComplete, model.StatusComplete},
+		{"quorum still possible", []model.UploadStatus{complete, failed, running}, 2, uploadsPending, model.StatusUploading},
+		{"quorum above targets", []model.UploadStatus{complete}, 3, uploadsComplete, model.StatusComplete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDownload(tt.statuses...)
+			if got := settleUploads(d, tt.quorum); got != tt.want || d.Status != tt.status {
+				t.Errorf("settleUploads() = %v (status %s), want %v (status %s)", got, d.Status, tt.want, tt.status)
+			}
+		})
+	}
+}
+
+func TestIsRetryableUploadError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection reset by peer"), true},
+		{errors.New("googleapi: Error 429: rate limit exceeded"), true},
+		{fserrors.RetryError(errors.New("backend busy")), true},
+		{errors.New("stat src: no such file or directory"), false},
+		{fserrors.FatalError(errors.New("quota exceeded")), false},
+		{fmt.Errorf("copy: %w", context.Canceled), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryableUploadError(tt.err); got != tt.want {
+			t.Errorf("isRetryableUploadError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestUploadJobID(t *testing.T) {
+	d := &model.Download{ID: "d_1234abcd", Dir: "/downloads/a", Uploads: []model.UploadTarget{
+		{Destination: "r0:"},
+		{Destination: "r1:", Status: model.UploadStatusError, Error: "boom"},
+	}}
+
+	j := newUploadJob(d, 1)
+	if !strings.HasPrefix(j.ID, "u_") || j.DownloadID != d.ID || j.Index != 1 || j.Destination != "r1:" || j.Source != d.Dir {
+		t.Fatalf("newUploadJob() = %+v", j)
+	}
+	queueUpload(&d.Uploads[1], j.ID)
+	if u := d.Uploads[1]; u.JobID != j.ID || u.Status != model.UploadStatusQueued || u.Error != "" {
+		t.Errorf("queueUpload() left target %+v", u)
+	}
+
+	// A retry hands the target to a new job, so the old one no longer owns it
+	retry := newUploadJob(d, 1)
+	if retry.ID == j.ID {
+		t.Fatal("newUploadJob reused a job ID")
+	}
+	queueUpload(&d.Uploads[1], retry.ID)
+	if d.Uploads[1].JobID == j.ID {
+		t.Error("target still owned by the previous job")
+	}
+}
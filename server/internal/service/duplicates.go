@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+	"gravity/internal/store"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Hash types tried first when confirming duplicates; others shared by all
+// files of a group are used in name order
+var preferredHashes = []string{"md5", "sha1", "sha256"}
+
+// Files waiting to be hashed in the background. Beyond this, files are left
+// for the next report to queue again.
+const duplicateHashQueue = 1000
+
+// DuplicateService finds files stored more than once across remotes and
+// local roots using the search index, and removes the extra copies
+type DuplicateService struct {
+	repo    *store.SearchRepo
+	storage engine.StorageEngine
+	logger  *zap.Logger
+
+	queue   chan hashJob
+	mu      sync.Mutex
+	pending map[string]bool // IDs of queued files
+}
+
+// hashJob is a file to hash with hash type ht
+type hashJob struct {
+	file model.IndexedFile
+	ht   string
+}
+
+func NewDuplicateService(repo *store.SearchRepo, storage engine.StorageEngine) *DuplicateService {
+	return &DuplicateService{
+		repo:    repo,
+		storage: storage,
+		logger:  logger.Component("DUPLICATES"),
+		queue:   make(chan hashJob, duplicateHashQueue),
+		pending: make(map[string]bool),
+	}
+}
+
+// Start hashes the files queued by Find in the background, one at a time,
+// since hashing may read a whole file from its backend
+func (s *DuplicateService) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job := <-s.queue:
+				s.hash(ctx, job)
+			}
+		}
+	}()
+}
+
+// Find returns a page of duplicate groups of files of at least minSize
+// bytes, the most space wasted first. Candidates share a name and size; with
+// verify they are confirmed by a hash type all their backends support.
+// Copies whose hash differs are dropped, so a candidate may yield several
+// groups or none. Hashes not yet cached in the index are computed in the
+// background; until then the group is returned unverified and marked as
+// hashing. The total counts candidates.
+func (s *DuplicateService) Find(ctx context.Context, minSize int64, verify bool, limit, offset int) ([]*model.DuplicateGroup, int, error) {
+	candidates, total, err := s.repo.DuplicateCandidates(ctx, minSize, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hashTypes := make(map[string][]string)
+	groups := []*model.DuplicateGroup{}
+	for _, c := range candidates {
+		files, err := s.repo.DuplicateFiles(ctx, c.Name, c.Size)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(files) < 2 {
+			continue
+		}
+		if !verify {
+			groups = append(groups, newDuplicateGroup(files, ""))
+			continue
+		}
+		groups = append(groups, s.verify(ctx, files, hashTypes)...)
+	}
+	return groups, total, nil
+}
+
+// verify splits files by their cached hashes and queues the files that have
+// none. Without a common hash type, or while any file is unhashed, they stay
+// one unverified group.
+func (s *DuplicateService) verify(ctx context.Context, files []model.IndexedFile, hashTypes map[string][]string) []*model.DuplicateGroup {
+	unverified := newDuplicateGroup(files, "")
+
+	hasher, ok := s.storage.(engine.Hasher)
+	if !ok {
+		return []*model.DuplicateGroup{unverified}
+	}
+	ht := s.commonHash(ctx, hasher, files, hashTypes)
+	if ht == "" {
+		return []*model.DuplicateGroup{unverified}
+	}
+
+	byHash := make(map[string][]model.IndexedFile)
+	var order []string
+	missing := false
+	for _, f := range files {
+		if !strings.HasPrefix(f.Hash, ht+":") {
+			missing = true
+			if s.enqueue(f, ht) {
+				unverified.Hashing = true
+			}
+			continue
+		}
+		if _, ok := byHash[f.Hash]; !ok {
+			order = append(order, f.Hash)
+		}
+		byHash[f.Hash] = append(byHash[f.Hash], f)
+	}
+	if missing {
+		return []*model.DuplicateGroup{unverified}
+	}
+
+	var groups []*model.DuplicateGroup
+	for _, h := range order {
+		if len(byHash[h]) > 1 {
+			groups = append(groups, newDuplicateGroup(byHash[h], h))
+		}
+	}
+	return groups
+}
+
+// commonHash picks a hash type supported by the backends of all files, or ""
+func (s *DuplicateService) commonHash(ctx context.Context, hasher engine.Hasher, files []model.IndexedFile, hashTypes map[string][]string) string {
+	var common []string
+	for i, f := range files {
+		types, ok := hashTypes[f.Remote]
+		if !ok {
+			var err error
+			if types, err = hasher.HashTypes(ctx, f.Remote); err != nil {
+				s.logger.Debug("failed to get hash types", zap.String("remote", f.Remote), zap.Error(err))
+			}
+			hashTypes[f.Remote] = types
+		}
+		if i == 0 {
+			common = slices.Clone(types)
+		} else {
+			common = slices.DeleteFunc(common, func(t string) bool { return !slices.Contains(types, t) })
+		}
+	}
+
+	for _, t := range preferredHashes {
+		if slices.Contains(common, t) {
+			return t
+		}
+	}
+	slices.Sort(common)
+	if len(common) > 0 {
+		return common[0]
+	}
+	return ""
+}
+
+// enqueue queues f to be hashed with ht and reports whether it is queued
+func (s *DuplicateService) enqueue(f model.IndexedFile, ht string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[f.ID] {
+		return true
+	}
+	select {
+	case s.queue <- hashJob{file: f, ht: ht}:
+		s.pending[f.ID] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// hash computes the hash of a queued file and caches it in the index as
+// "type:value". A file that cannot be hashed is queued again by the next
+// report.
+func (s *DuplicateService) hash(ctx context.Context, job hashJob) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, job.file.ID)
+		s.mu.Unlock()
+	}()
+
+	hasher, ok := s.storage.(engine.Hasher)
+	if !ok {
+		return
+	}
+	value, err := hasher.Hash(ctx, job.file.Path, job.ht)
+	if err != nil || value == "" {
+		s.logger.Debug("failed to hash file", zap.String("path", job.file.Path), zap.Error(err))
+		return
+	}
+	if err := s.repo.SetHash(ctx, job.file.ID, job.ht+":"+value); err != nil {
+		s.logger.Warn("failed to cache hash", zap.String("path", job.file.Path), zap.Error(err))
+	}
+}
+
+// Resolve keeps one file of each group and removes the others with remove,
+// the delete of the file operations API. A file is only removed if the index
+// still has it and the file to keep with the same name and size, and with
+// the hash of the group if it was verified, and if it still has the size of
+// the file to keep on its backend.
+func (s *DuplicateService) Resolve(ctx context.Context, resolutions []model.DuplicateResolution, remove func(context.Context, string) error) []model.DuplicateRemoval {
+	var results []model.DuplicateRemoval
+	for _, res := range resolutions {
+		keep, keepErr := s.checkKeep(ctx, res)
+		for _, p := range res.Remove {
+			removal := model.DuplicateRemoval{Path: p}
+			err := keepErr
+			if err == nil {
+				err = s.remove(ctx, res, keep, p, remove)
+			}
+			if err != nil {
+				removal.Error = err.Error()
+			}
+			results = append(results, removal)
+		}
+	}
+	return results
+}
+
+// checkKeep returns the file to keep of res as indexed
+func (s *DuplicateService) checkKeep(ctx context.Context, res model.DuplicateResolution) (*model.IndexedFile, error) {
+	keep, err := s.indexed(ctx, res.Keep)
+	if err != nil {
+		return nil, err
+	}
+	if res.Hash != "" && keep.Hash != res.Hash {
+		return nil, apperrors.New(apperrors.CodeInvalidOperation, "file to keep no longer has the hash of the group: "+res.Keep)
+	}
+	if _, err := s.storage.Stat(ctx, res.Keep); err != nil {
+		return nil, apperrors.New(apperrors.CodeNotFound, "file to keep not found: "+res.Keep)
+	}
+	return keep, nil
+}
+
+func (s *DuplicateService) remove(ctx context.Context, res model.DuplicateResolution, keep *model.IndexedFile, p string, remove func(context.Context, string) error) error {
+	if p == res.Keep {
+		return apperrors.New(apperrors.CodeValidationFailed, "cannot remove the file to keep")
+	}
+
+	file, err := s.indexed(ctx, p)
+	if err != nil {
+		return err
+	}
+	if file.Name != keep.Name || file.Size != keep.Size {
+		return apperrors.New(apperrors.CodeInvalidOperation, "not in the same group as the file to keep")
+	}
+	if res.Hash != "" && file.Hash != res.Hash {
+		return apperrors.New(apperrors.CodeInvalidOperation, "no longer has the hash of the group")
+	}
+
+	info, err := s.storage.Stat(ctx, p)
+	if err != nil {
+		return apperrors.NewNotFound("file", p)
+	}
+	if info.IsDir || info.Size != keep.Size {
+		return apperrors.New(apperrors.CodeInvalidOperation, "not the same size as the file to keep")
+	}
+
+	if err := remove(ctx, p); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTree(ctx, file.Remote, p); err != nil {
+		s.logger.Warn("failed to remove duplicate from index", zap.String("path", p), zap.Error(err))
+	}
+	s.logger.Info("removed duplicate", zap.String("path", p), zap.String("kept", res.Keep))
+	return nil
+}
+
+// indexed returns the indexed file at virtualPath
+func (s *DuplicateService) indexed(ctx context.Context, virtualPath string) (*model.IndexedFile, error) {
+	remote, _ := splitTop(virtualPath)
+	f, err := s.repo.DuplicateFile(ctx, remote, virtualPath)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.New(apperrors.CodeNotFound, "not an indexed file: "+virtualPath)
+	}
+	return f, err
+}
+
+func newDuplicateGroup(files []model.IndexedFile, hash string) *model.DuplicateGroup {
+	return &model.DuplicateGroup{
+		Name:        files[0].Name,
+		Size:        files[0].Size,
+		Hash:        hash,
+		Verified:    hash != "",
+		Reclaimable: files[0].Size * int64(len(files)-1),
+		Files:       files,
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"gravity/internal/model"
+	"gravity/internal/store"
+)
+
+// hashStorage is a memStorage whose remotes support the given hash types.
+// Every hash type is the MD5 of the content.
+type hashStorage struct {
+	*memStorage
+	types  map[string][]string
+	hashed []string
+}
+
+func (h *hashStorage) HashTypes(ctx context.Context, remote string) ([]string, error) {
+	return h.types[remote], nil
+}
+
+func (h *hashStorage) Hash(ctx context.Context, virtualPath, ht string) (string, error) {
+	data, ok := h.get(virtualPath)
+	if !ok {
+		return "", nil
+	}
+	h.hashed = append(h.hashed, virtualPath)
+	sum := md5.Sum([]byte(data))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func newTestDuplicates(t *testing.T, files map[string]string) (*DuplicateService, *hashStorage, *store.SearchRepo) {
+	t.Helper()
+	repo := store.NewSearchRepo(newTestStore(t).GetDB())
+	storage := &hashStorage{memStorage: newMemStorage(), types: map[string][]string{}}
+
+	byRemote := make(map[string][]model.IndexedFile)
+	for p, data := range files {
+		storage.files[p] = []byte(data)
+		remote, _ := splitTop(p)
+		byRemote[remote] = append(byRemote[remote], model.IndexedFile{
+			Source: model.IndexSourceRemote,
+			Path:   p,
+			Name:   path.Base(p),
+			Size:   int64(len(data)),
+		})
+	}
+	for remote, rows := range byRemote {
+		if _, _, err := repo.ApplyFiles(context.Background(), remote, rows, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewDuplicateService(repo, storage), storage, repo
+}
+
+// hashQueued hashes the files Find queued, as the background worker would
+func hashQueued(ctx context.Context, s *DuplicateService) {
+	for len(s.queue) > 0 {
+		s.hash(ctx, <-s.queue)
+	}
+}
+
+func groupPaths(g *model.DuplicateGroup) string {
+	var paths []string
+	for _, f := range g.Files {
+		paths = append(paths, f.Path)
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestDuplicatesGrouping(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestDuplicates(t, map[string]string{
+		"/gdrive/movie.mkv":       "12345678",
+		"/onedrive/old/movie.mkv": "87654321",
+		"/gdrive/show.mkv":        "1234",
+		"/onedrive/show.mkv":      "12345",
+		"/gdrive/a/tiny.txt":      "1",
+		"/gdrive/b/tiny.txt":      "1",
+	})
+
+	groups, total, err := s.Find(ctx, 2, false, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(groups) != 1 {
+		t.Fatalf("%d groups of %d candidates, want 1", len(groups), total)
+	}
+	g := groups[0]
+	if groupPaths(g) != "/gdrive/movie.mkv,/onedrive/old/movie.mkv" || g.Reclaimable != 8 || g.Verified || g.Hashing {
+		t.Errorf("group = %s, reclaimable %d, verified %v, hashing %v", groupPaths(g), g.Reclaimable, g.Verified, g.Hashing)
+	}
+	if groups, _, _ := s.Find(ctx, 0, false, 10, 0); len(groups) != 2 {
+		t.Errorf("%d groups without a minimum size, want 2", len(groups))
+	}
+}
+
+func TestDuplicatesHashSplit(t *testing.T) {
+	ctx := context.Background()
+	s, storage, _ := newTestDuplicates(t, map[string]string{
+		"/gdrive/movie.mkv":   "12345678",
+		"/onedrive/movie.mkv": "12345678",
+		"/local/movie.mkv":    "abcdefgh",
+		"/gdrive/show.mkv":    "1234",
+		"/s3/show.mkv":        "1234",
+	})
+	storage.types["gdrive"] = []string{"md5"}
+	storage.types["onedrive"] = []string{"sha1", "md5"}
+	storage.types["local"] = []string{"md5", "sha1"}
+
+	// Hashing happens in the background, never inside the report
+	groups, _, err := s.Find(ctx, 0, true, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storage.hashed) != 0 {
+		t.Errorf("Find hashed %v itself", storage.hashed)
+	}
+	if len(groups) != 2 || !groups[0].Hashing || groups[0].Verified {
+		t.Fatalf("before hashing: %d groups, first hashing %v", len(groups), groups[0].Hashing)
+	}
+	// s3 has no hash type, so its group is never verified
+	if groups[1].Hashing || groups[1].Verified {
+		t.Errorf("group without a common hash: hashing %v, verified %v", groups[1].Hashing, groups[1].Verified)
+	}
+
+	// Queued files are not queued again while they wait
+	s.Find(ctx, 0, true, 10, 0)
+	if len(s.queue) != 3 {
+		t.Errorf("%d files queued, want 3", len(s.queue))
+	}
+	hashQueued(ctx, s)
+
+	groups, _, _ = s.Find(ctx, 0, true, 10, 0)
+	if len(groups) != 2 {
+		t.Fatalf("after hashing: %d groups, want 2", len(groups))
+	}
+	g := groups[0]
+	if groupPaths(g) != "/gdrive/movie.mkv,/onedrive/movie.mkv" || !g.Verified || g.Hashing || !strings.HasPrefix(g.Hash, "md5:") {
+		t.Errorf("verified group = %s, hash %q, verified %v", groupPaths(g), g.Hash, g.Verified)
+	}
+	if len(storage.hashed) != 3 {
+		t.Errorf("hashed %v, want each file once", storage.hashed)
+	}
+}
+
+func TestDuplicatesResolve(t *testing.T) {
+	ctx := context.Background()
+	s, storage, repo := newTestDuplicates(t, map[string]string{
+		"/gdrive/movie.mkv":        "12345678",
+		"/onedrive/movie.mkv":      "12345678",
+		"/dropbox/movie.mkv":       "12345678",
+		"/box/movie.mkv":           "abcdefgh",
+		"/mega/movie.mkv":          "12345678",
+		"/gdrive/other/clip.mkv":   "12345678",
+		"/gdrive/keep-missing.mkv": "12345678",
+	})
+	for _, remote := range []string{"gdrive", "onedrive", "dropbox", "box", "mega"} {
+		storage.types[remote] = []string{"md5"}
+	}
+	s.Find(ctx, 0, true, 10, 0)
+	hashQueued(ctx, s)
+	groups, _, _ := s.Find(ctx, 0, true, 10, 0)
+	hash := groups[0].Hash
+
+	storage.files["/onedrive/unindexed2.mkv"] = []byte("12345678")
+	delete(storage.files, "/gdrive/keep-missing.mkv")
+	// The file on mega changed size after it was indexed
+	storage.files["/mega/movie.mkv"] = []byte("123456789")
+
+	var removed []string
+	remove := func(ctx context.Context, p string) error {
+		removed = append(removed, p)
+		return storage.Delete(ctx, p)
+	}
+	results := s.Resolve(ctx, []model.DuplicateResolution{
+		{
+			Keep: "/gdrive/movie.mkv",
+			Hash: hash,
+			Remove: []string{
+				"/onedrive/movie.mkv",
+				"/gdrive/movie.mkv",
+				"/box/movie.mkv",
+				"/gdrive/other/clip.mkv",
+				"/onedrive/unindexed2.mkv",
+				"/mega/movie.mkv",
+			},
+		},
+		{Keep: "/gdrive/keep-missing.mkv", Remove: []string{"/dropbox/movie.mkv"}},
+	}, remove)
+
+	wantErr := map[string]string{
+		"/gdrive/movie.mkv":        "cannot remove the file to keep",
+		"/box/movie.mkv":           "hash",
+		"/gdrive/other/clip.mkv":   "same group",
+		"/onedrive/unindexed2.mkv": "not an indexed file",
+		"/mega/movie.mkv":          "same size",
+		"/dropbox/movie.mkv":       "file to keep not found",
+	}
+	if len(results) != 7 {
+		t.Fatalf("%d results, want 7", len(results))
+	}
+	for _, r := range results {
+		want, fails := wantErr[r.Path]
+		switch {
+		case fails && !strings.Contains(r.Error, want):
+			t.Errorf("%s: error %q, want %q", r.Path, r.Error, want)
+		case !fails && r.Error != "":
+			t.Errorf("%s: %s", r.Path, r.Error)
+		}
+	}
+	if strings.Join(removed, ",") != "/onedrive/movie.mkv" {
+		t.Errorf("removed %v, want only /onedrive/movie.mkv", removed)
+	}
+	if _, err := repo.DuplicateFile(ctx, "onedrive", "/onedrive/movie.mkv"); err == nil {
+		t.Error("removed duplicate is still indexed")
+	}
+	if _, ok := storage.get("/dropbox/movie.mkv"); !ok {
+		t.Error("removed a copy whose file to keep is gone")
+	}
+}
@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/event"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// How long an idle resumable upload is kept before its data is discarded
+const fileUploadSessionTTL = 24 * time.Hour
+
+// How often file upload progress is published
+const fileUploadProgressInterval = time.Second
+
+// FileUploadService writes files sent from the file manager into the VFS,
+// either streamed in one request or as a resumable session of chunks that
+// are staged under the data dir until the last one arrives. Each session is
+// saved next to its chunks, so uploads resume across restarts.
+type FileUploadService struct {
+	storage engine.StorageEngine
+	bus     *event.Bus
+	tmpDir  string
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	sessions map[string]*fileUploadSession
+}
+
+type fileUploadSession struct {
+	model.FileUploadSession
+	busy bool // A chunk is being written or the file is being stored
+}
+
+func NewFileUploadService(storage engine.StorageEngine, bus *event.Bus, dataDir string) *FileUploadService {
+	return &FileUploadService{
+		storage:  storage,
+		bus:      bus,
+		tmpDir:   filepath.Join(dataDir, "upload-sessions"),
+		logger:   logger.Component("FILES"),
+		sessions: make(map[string]*fileUploadSession),
+	}
+}
+
+// Start reloads the sessions of a previous process and expires idle ones
+func (s *FileUploadService) Start(ctx context.Context) {
+	if err := os.MkdirAll(s.tmpDir, 0755); err != nil {
+		s.logger.Error("failed to create upload session dir", zap.Error(err))
+	}
+	s.load(time.Now())
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.expire(now)
+			}
+		}
+	}()
+}
+
+// Put streams in to virtualPath. size may be -1 when unknown; it is only
+// used for progress.
+func (s *FileUploadService) Put(ctx context.Context, virtualPath string, in io.Reader, size int64, conflict model.ConflictPolicy) (*engine.FileInfo, error) {
+	target, err := s.resolve(ctx, virtualPath, conflict)
+	if err != nil {
+		return nil, err
+	}
+	return s.store(ctx, "fu_"+uuid.New().String()[:8], target, in, size)
+}
+
+// CreateSession starts a resumable upload of size bytes to virtualPath
+func (s *FileUploadService) CreateSession(ctx context.Context, virtualPath string, size int64, conflict model.ConflictPolicy) (*model.FileUploadSession, error) {
+	if size < 0 {
+		return nil, apperrors.New(apperrors.CodeValidationFailed, "size must not be negative")
+	}
+	// Fail early; the final name is resolved again once all data is in
+	if _, err := s.resolve(ctx, virtualPath, conflict); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sess := &fileUploadSession{FileUploadSession: model.FileUploadSession{
+		ID:        "fu_" + uuid.New().String()[:8],
+		Path:      virtualPath,
+		Size:      size,
+		Conflict:  conflict,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}}
+
+	f, err := os.Create(s.partPath(sess.ID))
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	if err := s.save(&sess.FileUploadSession); err != nil {
+		os.Remove(s.partPath(sess.ID))
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.sessions[sess.ID] = sess
+	s.mu.Unlock()
+
+	result := sess.FileUploadSession
+	return &result, nil
+}
+
+func (s *FileUploadService) GetSession(id string) (*model.FileUploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, apperrors.NewNotFound("upload session", id)
+	}
+	result := sess.FileUploadSession
+	return &result, nil
+}
+
+// WriteChunk appends in to session id at offset, which must be the number of
+// bytes received so far. Bytes received before a dropped connection are
+// kept, so the client resumes from the session's offset. The chunk that
+// completes the file stores it; if that fails, a chunk at the final offset
+// with no data retries it.
+func (s *FileUploadService) WriteChunk(ctx context.Context, id string, offset int64, in io.Reader) (*model.FileUploadSession, error) {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		return nil, apperrors.NewNotFound("upload session", id)
+	case sess.busy:
+		s.mu.Unlock()
+		return nil, apperrors.New(apperrors.CodeInvalidOperation, "a chunk is already being written to this session")
+	case offset != sess.Offset:
+		s.mu.Unlock()
+		return nil, apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("expected offset %d, got %d", sess.Offset, offset))
+	}
+	sess.busy = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		sess.busy = false
+		sess.UpdatedAt = time.Now()
+		s.mu.Unlock()
+	}()
+
+	n, err := s.appendChunk(sess, in)
+	s.mu.Lock()
+	sess.Offset += n
+	sess.UpdatedAt = time.Now()
+	saved := sess.FileUploadSession
+	s.mu.Unlock()
+	if serr := s.save(&saved); serr != nil {
+		s.logger.Warn("failed to save upload session", zap.String("id", id), zap.Error(serr))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sess.Offset == sess.Size {
+		if err := s.finish(ctx, sess); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	result := sess.FileUploadSession
+	s.mu.Unlock()
+	return &result, nil
+}
+
+// AbortSession discards session id and the data received for it
+func (s *FileUploadService) AbortSession(id string) error {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		return apperrors.NewNotFound("upload session", id)
+	}
+	if sess.busy {
+		s.mu.Unlock()
+		return apperrors.New(apperrors.CodeInvalidOperation, "a chunk is being written to this session")
+	}
+	delete(s.sessions, id)
+	s.mu.Unlock()
+
+	os.Remove(s.sessionPath(id))
+	return os.Remove(s.partPath(id))
+}
+
+// appendChunk writes in to the staged data of sess, rejecting data past the
+// declared size
+func (s *FileUploadService) appendChunk(sess *fileUploadSession, in io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.partPath(sess.ID), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	pr := s.newProgressReader(sess.ID, in, sess.Offset, sess.Size)
+	n, err := io.Copy(f, io.LimitReader(pr, sess.Size-sess.Offset))
+	if err != nil {
+		return n, err
+	}
+
+	var extra [1]byte
+	if m, _ := in.Read(extra[:]); m > 0 {
+		// Drop the whole chunk so the client can resend it correctly sized
+		if err := f.Truncate(sess.Offset); err != nil {
+			return n, err
+		}
+		return 0, apperrors.New(apperrors.CodeValidationFailed, fmt.Sprintf("chunk goes past the declared size of %d bytes", sess.Size))
+	}
+	return n, nil
+}
+
+// finish stores the staged data of sess at its final path
+func (s *FileUploadService) finish(ctx context.Context, sess *fileUploadSession) error {
+	target, err := s.resolve(ctx, sess.Path, sess.Conflict)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(s.partPath(sess.ID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := s.store(ctx, sess.ID, target, f, sess.Size); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	sess.Path = target
+	sess.Complete = true
+	delete(s.sessions, sess.ID)
+	s.mu.Unlock()
+
+	s.discard(sess.ID)
+	return nil
+}
+
+// store writes in to target, publishing progress and the outcome as id
+func (s *FileUploadService) store(ctx context.Context, id, target string, in io.Reader, size int64) (*engine.FileInfo, error) {
+	if err := s.storage.Put(ctx, target, s.newProgressReader(id, in, 0, size)); err != nil {
+		s.logger.Warn("file upload failed", zap.String("path", target), zap.Error(err))
+		s.bus.PublishLifecycle(event.LifecycleEvent{
+			Type:      event.FileUploadError,
+			ID:        id,
+			Timestamp: time.Now(),
+			Error:     err.Error(),
+			Data:      map[string]any{"path": target},
+		})
+		return nil, err
+	}
+
+	info, err := s.storage.Stat(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("file uploaded", zap.String("path", target), zap.Int64("size", info.Size))
+	s.bus.PublishProgress(event.ProgressEvent{
+		ID:       id,
+		Type:     "file",
+		Uploaded: info.Size,
+		Size:     info.Size,
+	})
+	s.bus.PublishLifecycle(event.LifecycleEvent{
+		Type:      event.FileUploaded,
+		ID:        id,
+		Timestamp: time.Now(),
+		Data:      info,
+	})
+	return info, nil
+}
+
+// resolve applies the conflict policy to virtualPath and returns the path to
+// write to
+func (s *FileUploadService) resolve(ctx context.Context, virtualPath string, conflict model.ConflictPolicy) (string, error) {
+	if _, err := s.storage.Stat(ctx, virtualPath); err != nil {
+		return virtualPath, nil
+	}
+
+	switch conflict {
+	case model.ConflictOverwrite:
+		return virtualPath, nil
+	case model.ConflictRename:
+		for i := 1; i < 1000; i++ {
+			candidate := numberedName(virtualPath, i)
+			if _, err := s.storage.Stat(ctx, candidate); err != nil {
+				return candidate, nil
+			}
+		}
+		return "", apperrors.New(apperrors.CodeInvalidOperation, "no free name for "+path.Base(virtualPath))
+	default:
+		return "", apperrors.New(apperrors.CodeInvalidOperation, virtualPath+" already exists")
+	}
+}
+
+func (s *FileUploadService) expire(now time.Time) {
+	s.mu.Lock()
+	var expired []string
+	for id, sess := range s.sessions {
+		if !sess.busy && now.Sub(sess.UpdatedAt) > fileUploadSessionTTL {
+			delete(s.sessions, id)
+			expired = append(expired, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range expired {
+		s.discard(id)
+		s.logger.Debug("expired upload session", zap.String("id", id))
+	}
+}
+
+// load restores the sessions saved in the session dir. The offset is taken
+// from the staged data, which may hold part of a chunk whose request was cut
+// by the restart. Anything else in the dir is removed.
+func (s *FileUploadService) load(now time.Time) {
+	entries, err := os.ReadDir(s.tmpDir)
+	if err != nil {
+		s.logger.Warn("failed to read upload sessions", zap.Error(err))
+		return
+	}
+
+	loaded := make(map[string]bool)
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		sess, err := s.loadSession(id)
+		if err != nil || now.Sub(sess.UpdatedAt) > fileUploadSessionTTL {
+			if err != nil {
+				s.logger.Warn("dropping unreadable upload session", zap.String("id", id), zap.Error(err))
+			}
+			s.discard(id)
+			continue
+		}
+		loaded[id] = true
+		s.mu.Lock()
+		s.sessions[id] = &fileUploadSession{FileUploadSession: *sess}
+		s.mu.Unlock()
+	}
+
+	// Data of dropped sessions and half-written session files
+	for _, e := range entries {
+		name := e.Name()
+		if id, ok := strings.CutSuffix(name, ".part"); ok && loaded[id] {
+			continue
+		}
+		if id, ok := strings.CutSuffix(name, ".json"); ok && loaded[id] {
+			continue
+		}
+		os.Remove(filepath.Join(s.tmpDir, name))
+	}
+	if len(loaded) > 0 {
+		s.logger.Info("resumed upload sessions", zap.Int("count", len(loaded)))
+	}
+}
+
+func (s *FileUploadService) loadSession(id string) (*model.FileUploadSession, error) {
+	data, err := os.ReadFile(s.sessionPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var sess model.FileUploadSession
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, err
+	}
+	if sess.ID != id {
+		return nil, fmt.Errorf("session file holds session %q", sess.ID)
+	}
+
+	info, err := os.Stat(s.partPath(id))
+	if err != nil {
+		return nil, err
+	}
+	sess.Offset = info.Size()
+	if sess.Offset > sess.Size {
+		if err := os.Truncate(s.partPath(id), sess.Size); err != nil {
+			return nil, err
+		}
+		sess.Offset = sess.Size
+	}
+	return &sess, nil
+}
+
+// save writes sess next to its staged data, replacing the previous copy
+// atomically
+func (s *FileUploadService) save(sess *model.FileUploadSession) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	tmp := s.sessionPath(sess.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.sessionPath(sess.ID))
+}
+
+// discard removes the staged data and saved state of session id
+func (s *FileUploadService) discard(id string) {
+	for _, p := range []string{s.partPath(id), s.sessionPath(id)} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("failed to remove staged upload", zap.String("id", id), zap.Error(err))
+		}
+	}
+}
+
+func (s *FileUploadService) partPath(id string) string {
+	return filepath.Join(s.tmpDir, id+".part")
+}
+
+func (s *FileUploadService) sessionPath(id string) string {
+	return filepath.Join(s.tmpDir, id+".json")
+}
+
+// numberedName returns p with " (i)" added before its extension
+func numberedName(p string, i int) string {
+	dir, base := path.Split(p)
+	ext := path.Ext(base)
+	if ext == base {
+		// Dotfiles such as ".env" have no extension
+		ext = ""
+	}
+	return dir + fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), i, ext)
+}
+
+// progressReader publishes file upload progress while it is read
+type progressReader struct {
+	r        io.Reader
+	bus      *event.Bus
+	id       string
+	read     int64
+	size     int64
+	lastRead int64
+	lastTime time.Time
+}
+
+func (s *FileUploadService) newProgressReader(id string, r io.Reader, offset, size int64) *progressReader {
+	return &progressReader{r: r, bus: s.bus, id: id, read: offset, size: size, lastRead: offset, lastTime: time.Now()}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+
+	if elapsed := time.Since(p.lastTime); elapsed >= fileUploadProgressInterval {
+		speed := int64(float64(p.read-p.lastRead) / elapsed.Seconds())
+		eta := event.ETAUnknown
+		if speed > 0 && p.size > 0 {
+			eta = int((p.size - p.read) / speed)
+		}
+		p.bus.PublishProgress(event.ProgressEvent{
+			ID:       p.id,
+			Type:     "file",
+			Uploaded: p.read,
+			Size:     p.size,
+			Speed:    speed,
+			ETA:      eta,
+		})
+		p.lastRead = p.read
+		p.lastTime = time.Now()
+	}
+	return n, err
+}
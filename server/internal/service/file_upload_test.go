@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gravity/internal/engine"
+	"gravity/internal/event"
+	"gravity/internal/model"
+)
+
+func TestNumberedName(t *testing.T) {
+	tests := []struct {
+		path string
+		i    int
+		want string
+	}{
+		{"/gdrive/movie.mkv", 1, "/gdrive/movie (1).mkv"},
+		{"/gdrive/archive.tar.gz", 2, "/gdrive/archive.tar (2).gz"},
+		{"/gdrive/README", 1, "/gdrive/README (1)"},
+		{"/gdrive/.env", 3, "/gdrive/.env (3)"},
+		{"file.txt", 1, "file (1).txt"},
+	}
+
+	for _, tt := range tests {
+		if got := numberedName(tt.path, tt.i); got != tt.want {
+			t.Errorf("numberedName(%q, %d) = %q, want %q", tt.path, tt.i, got, tt.want)
+		}
+	}
+}
+
+func newTestFileUploads(t *testing.T, dataDir string, storage engine.StorageEngine, bus *event.Bus) *FileUploadService {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s := NewFileUploadService(storage, bus, dataDir)
+	s.Start(ctx)
+	return s
+}
+
+func TestFileUploadChunks(t *testing.T) {
+	ctx := context.Background()
+	storage := newMemStorage()
+	s := newTestFileUploads(t, t.TempDir(), storage, event.NewBus())
+
+	sess, err := s.CreateSession(ctx, "/gdrive/a.txt", 11, model.ConflictFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteChunk(ctx, sess.ID, 5, strings.NewReader("world")); err == nil {
+		t.Fatal("accepted a chunk ahead of the offset")
+	}
+	if got, err := s.WriteChunk(ctx, sess.ID, 0, strings.NewReader("hello ")); err != nil || got.Offset != 6 || got.Complete {
+		t.Fatalf("first chunk: %+v, %v", got, err)
+	}
+	if _, err := s.WriteChunk(ctx, sess.ID, 0, strings.NewReader("hello ")); err == nil {
+		t.Fatal("accepted a chunk twice")
+	}
+	if _, err := s.WriteChunk(ctx, sess.ID, 6, strings.NewReader("world!")); err == nil {
+		t.Fatal("accepted a chunk past the declared size")
+	}
+	got, err := s.WriteChunk(ctx, sess.ID, 6, strings.NewReader("world"))
+	if err != nil || !got.Complete || got.Path != "/gdrive/a.txt" {
+		t.Fatalf("last chunk: %+v, %v", got, err)
+	}
+	if data, _ := storage.get("/gdrive/a.txt"); data != "hello world" {
+		t.Errorf("stored %q", data)
+	}
+	if _, err := s.GetSession(sess.ID); err == nil {
+		t.Error("completed session is still open")
+	}
+}
+
+func TestFileUploadResume(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	storage := newMemStorage()
+	s := newTestFileUploads(t, dataDir, storage, event.NewBus())
+
+	sess, err := s.CreateSession(ctx, "/gdrive/a.txt", 11, model.ConflictFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteChunk(ctx, sess.ID, 0, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	// A chunk cut short by the restart keeps the bytes that arrived
+	f, err := os.OpenFile(s.partPath(sess.ID), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(" w")
+	f.Close()
+	os.WriteFile(filepath.Join(dataDir, "upload-sessions", "fu_orphan.part"), []byte("x"), 0644)
+
+	restarted := newTestFileUploads(t, dataDir, storage, event.NewBus())
+	got, err := restarted.GetSession(sess.ID)
+	if err != nil || got.Offset != 7 || got.Path != "/gdrive/a.txt" {
+		t.Fatalf("resumed session: %+v, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "upload-sessions", "fu_orphan.part")); !os.IsNotExist(err) {
+		t.Error("staged data without a session was kept")
+	}
+	if got, err := restarted.WriteChunk(ctx, sess.ID, 7, strings.NewReader("orld")); err != nil || !got.Complete {
+		t.Fatalf("resumed chunk: %+v, %v", got, err)
+	}
+	if data, _ := storage.get("/gdrive/a.txt"); data != "hello world" {
+		t.Errorf("stored %q", data)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dataDir, "upload-sessions"))
+	if len(entries) != 0 {
+		t.Errorf("session dir not cleaned up: %v", entries)
+	}
+}
+
+func TestFileUploadConflict(t *testing.T) {
+	ctx := context.Background()
+	storage := newMemStorage()
+	storage.files["/gdrive/a.txt"] = []byte("old")
+	storage.files["/gdrive/a (1).txt"] = []byte("old")
+	s := newTestFileUploads(t, t.TempDir(), storage, event.NewBus())
+
+	if _, err := s.Put(ctx, "/gdrive/a.txt", strings.NewReader("new"), 3, model.ConflictFail); err == nil {
+		t.Error("fail policy replaced an existing file")
+	}
+	if _, err := s.CreateSession(ctx, "/gdrive/a.txt", 3, model.ConflictFail); err == nil {
+		t.Error("fail policy opened a session for an existing file")
+	}
+
+	info, err := s.Put(ctx, "/gdrive/a.txt", strings.NewReader("new"), 3, model.ConflictRename)
+	if err != nil || info.Path != "/gdrive/a (2).txt" {
+		t.Fatalf("rename: %+v, %v", info, err)
+	}
+	if data, _ := storage.get("/gdrive/a.txt"); data != "old" {
+		t.Errorf("rename changed the existing file to %q", data)
+	}
+
+	if _, err := s.Put(ctx, "/gdrive/a.txt", strings.NewReader("new"), 3, model.ConflictOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := storage.get("/gdrive/a.txt"); data != "new" {
+		t.Errorf("overwrite stored %q", data)
+	}
+
+	// The policy is applied again once the last chunk is in
+	sess, err := s.CreateSession(ctx, "/gdrive/b.txt", 3, model.ConflictFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Put(ctx, "/gdrive/b.txt", strings.NewReader("raced"))
+	if _, err := s.WriteChunk(ctx, sess.ID, 0, strings.NewReader("new")); err == nil {
+		t.Error("fail policy replaced a file created during the upload")
+	}
+	if data, _ := storage.get("/gdrive/b.txt"); data != "raced" {
+		t.Errorf("file created during the upload changed to %q", data)
+	}
+}
+
+func TestFileUploadEvents(t *testing.T) {
+	ctx := context.Background()
+	bus := event.NewBus()
+	progress := bus.SubscribeProgress()
+	lifecycle := bus.SubscribeLifecycle()
+	storage := newMemStorage()
+	s := newTestFileUploads(t, t.TempDir(), storage, bus)
+
+	if _, err := s.Put(ctx, "/gdrive/a.txt", strings.NewReader("hello"), 5, model.ConflictFail); err != nil {
+		t.Fatal(err)
+	}
+	if p := <-progress; p.Type != "file" || p.Uploaded != 5 || p.Size != 5 {
+		t.Errorf("progress = %+v", p)
+	}
+	if e := <-lifecycle; e.Type != event.FileUploaded {
+		t.Errorf("lifecycle = %+v", e)
+	}
+
+	storage.err = errors.New("remote is read-only")
+	if _, err := s.Put(ctx, "/gdrive/b.txt", strings.NewReader("hello"), 5, model.ConflictFail); err == nil {
+		t.Fatal("Put succeeded on a failing remote")
+	}
+	if e := <-lifecycle; e.Type != event.FileUploadError || e.Error != "remote is read-only" {
+		t.Errorf("lifecycle = %+v", e)
+	}
+}
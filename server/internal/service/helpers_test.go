@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gravity/internal/config"
+	"gravity/internal/engine"
+	"gravity/internal/model"
+	"gravity/internal/store"
+)
+
+// memStorage is a StorageEngine that keeps files in memory. Folders exist
+// while they hold a file or were made with Mkdir.
+type memStorage struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	dirs    map[string]bool
+	modTime time.Time
+	err     error // Returned by Put when set
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{
+		files:   make(map[string][]byte),
+		dirs:    make(map[string]bool),
+		modTime: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+	}
+}
+
+func (m *memStorage) get(p string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[p]
+	return string(data), ok
+}
+
+func (m *memStorage) isDir(p string) bool {
+	if p == "/" || m.dirs[p] {
+		return true
+	}
+	for f := range m.files {
+		if strings.HasPrefix(f, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memStorage) info(p string) *engine.FileInfo {
+	if data, ok := m.files[p]; ok {
+		return &engine.FileInfo{Path: p, Name: path.Base(p), Size: int64(len(data)), ModTime: m.modTime, Type: engine.FileTypeFile}
+	}
+	if m.isDir(p) {
+		return &engine.FileInfo{Path: p, Name: path.Base(p), ModTime: m.modTime, Type: engine.FileTypeFolder, IsDir: true}
+	}
+	return nil
+}
+
+func (m *memStorage) List(ctx context.Context, virtualPath string) ([]engine.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.isDir(virtualPath) {
+		return nil, os.ErrNotExist
+	}
+	prefix := strings.TrimSuffix(virtualPath, "/") + "/"
+	names := make(map[string]bool)
+	add := func(p string) {
+		if rest, ok := strings.CutPrefix(p, prefix); ok && rest != "" {
+			names[prefix+strings.SplitN(rest, "/", 2)[0]] = true
+		}
+	}
+	for f := range m.files {
+		add(f)
+	}
+	for d := range m.dirs {
+		add(d)
+	}
+	var list []engine.FileInfo
+	for p := range names {
+		list = append(list, *m.info(p))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list, nil
+}
+
+func (m *memStorage) Stat(ctx context.Context, virtualPath string) (*engine.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if info := m.info(virtualPath); info != nil {
+		return info, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (m *memStorage) ListRemotes(ctx context.Context) ([]engine.Remote, error) { return nil, nil }
+
+func (m *memStorage) Mkdir(ctx context.Context, virtualPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dirs[virtualPath] = true
+	return nil
+}
+
+// Delete removes a file or an empty folder, like the rclone engine
+func (m *memStorage) Delete(ctx context.Context, virtualPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info := m.info(virtualPath)
+	if info == nil {
+		return os.ErrNotExist
+	}
+	if info.IsDir {
+		for p := range m.files {
+			if strings.HasPrefix(p, virtualPath+"/") {
+				return errors.New("directory not empty")
+			}
+		}
+		for p := range m.dirs {
+			if strings.HasPrefix(p, virtualPath+"/") {
+				return errors.New("directory not empty")
+			}
+		}
+	}
+	delete(m.files, virtualPath)
+	delete(m.dirs, virtualPath)
+	return nil
+}
+
+func (m *memStorage) RemoveAll(ctx context.Context, virtualPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.info(virtualPath) == nil {
+		return os.ErrNotExist
+	}
+	for p := range m.files {
+		if p == virtualPath || strings.HasPrefix(p, virtualPath+"/") {
+			delete(m.files, p)
+		}
+	}
+	for p := range m.dirs {
+		if p == virtualPath || strings.HasPrefix(p, virtualPath+"/") {
+			delete(m.dirs, p)
+		}
+	}
+	return nil
+}
+
+func (m *memStorage) Rename(ctx context.Context, virtualPath, newName string) error {
+	return m.MovePath(ctx, virtualPath, path.Join(path.Dir(virtualPath), newName))
+}
+
+func (m *memStorage) MovePath(ctx context.Context, src, dst string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.info(src) == nil {
+		return os.ErrNotExist
+	}
+	for p, data := range m.files {
+		if p == src || strings.HasPrefix(p, src+"/") {
+			delete(m.files, p)
+			m.files[dst+strings.TrimPrefix(p, src)] = data
+		}
+	}
+	for p := range m.dirs {
+		if p == src || strings.HasPrefix(p, src+"/") {
+			delete(m.dirs, p)
+			m.dirs[dst+strings.TrimPrefix(p, src)] = true
+		}
+	}
+	return nil
+}
+
+func (m *memStorage) Put(ctx context.Context, virtualPath string, in io.Reader) error {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.files[virtualPath] = data
+	return nil
+}
+
+func (m *memStorage) Open(ctx context.Context, virtualPath string) (engine.ReadSeekCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[virtualPath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return nopSeekCloser{bytes.NewReader(data)}, nil
+}
+
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// newTestStore opens a SQLite store in a temporary dir, with default settings
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{DataDir: dir}
+	cfg.Database.Type = "sqlite"
+	cfg.Database.DSN = filepath.Join(dir, "gravity.db")
+	s, err := store.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := store.NewSettingsRepo(s.GetDB()).Save(context.Background(), model.DefaultSettings()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -129,7 +129,7 @@ func (s *ProviderService) Resolve(ctx context.Context, url string, headers map[s
 
 	// 2. Handle Regular URL
 	res, providerName, err := s.resolver.Resolve(ctx, url, headers)
-	if err == nil && res != nil {
+	if err == nil && res != nil && res.ExecutionMode == "" {
 		res.ExecutionMode = model.ExecutionModeDirect
 	}
 	return res, providerName, err
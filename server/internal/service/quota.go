@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gravity/internal/engine"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// How long a remote's usage is trusted before it is read again
+	quotaTTL = 10 * time.Minute
+	// Bound on reading the usage of one remote
+	quotaFetchTimeout = 30 * time.Second
+)
+
+// QuotaService keeps the usage of each remote, read from its backend and
+// refreshed in the background, so listings never wait on a backend
+type QuotaService struct {
+	engine engine.UploadEngine
+	ctx    context.Context
+	logger *zap.Logger
+
+	mu    sync.Mutex
+	cache map[string]*quotaEntry
+}
+
+// quotaEntry is the last read of a remote. usage is nil if the backend has
+// no quota or was never read successfully.
+type quotaEntry struct {
+	usage      *model.RemoteUsage
+	fetchedAt  time.Time
+	refreshing bool // A background read is under way
+}
+
+func NewQuotaService(eng engine.UploadEngine) *QuotaService {
+	return &QuotaService{
+		engine: eng,
+		logger: logger.Component("QUOTA"),
+		cache:  make(map[string]*quotaEntry),
+	}
+}
+
+func (s *QuotaService) Start(ctx context.Context) {
+	s.ctx = ctx
+	if _, ok := s.engine.(engine.UsageReader); !ok {
+		return
+	}
+	go func() {
+		s.refreshAll(ctx)
+		ticker := time.NewTicker(quotaTTL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.refreshAll(ctx)
+			}
+		}
+	}()
+}
+
+// refreshAll reads the usage of every remote and forgets removed ones
+func (s *QuotaService) refreshAll(ctx context.Context) {
+	remotes, err := s.engine.ListRemotes(ctx)
+	if err != nil {
+		s.logger.Warn("failed to list remotes", zap.Error(err))
+		return
+	}
+	names := make([]string, len(remotes))
+	for i, r := range remotes {
+		names[i] = r.Name
+		s.refresh(ctx, r.Name)
+	}
+
+	s.mu.Lock()
+	for name := range s.cache {
+		if !slices.Contains(names, name) {
+			delete(s.cache, name)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// refresh reads the usage of remote from its backend. A failed read keeps
+// the last known usage.
+func (s *QuotaService) refresh(ctx context.Context, remote string) *model.RemoteUsage {
+	reader, ok := s.engine.(engine.UsageReader)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, quotaFetchTimeout)
+	defer cancel()
+	usage, err := reader.About(ctx, remote)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.cache[remote]
+	if entry == nil {
+		entry = &quotaEntry{}
+		s.cache[remote] = entry
+	}
+	entry.fetchedAt = time.Now()
+	entry.refreshing = false
+	switch {
+	case err == nil:
+		entry.usage = usage
+	case errors.Is(err, engine.ErrUsageUnsupported):
+		entry.usage = nil
+	default:
+		s.logger.Debug("failed to read remote usage", zap.String("remote", remote), zap.Error(err))
+	}
+	return entry.usage
+}
+
+// Cached returns the last known usage of remote without reading it
+func (s *QuotaService) Cached(remote string) *model.RemoteUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry := s.cache[remote]; entry != nil {
+		return entry.usage
+	}
+	return nil
+}
+
+// All returns the last known usage of each remote that reports one, by name
+func (s *QuotaService) All() []model.RemoteUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := []model.RemoteUsage{}
+	for _, entry := range s.cache {
+		if entry.usage != nil {
+			all = append(all, *entry.usage)
+		}
+	}
+	slices.SortFunc(all, func(a, b model.RemoteUsage) int { return strings.Compare(a.Remote, b.Remote) })
+	return all
+}
+
+// Usage returns the last known usage of remote without waiting on its
+// backend. A usage older than quotaTTL, or never read, is read again in the
+// background for later calls.
+func (s *QuotaService) Usage(remote string) *model.RemoteUsage {
+	if _, ok := s.engine.(engine.UsageReader); !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.cache[remote]
+	if entry == nil {
+		entry = &quotaEntry{}
+		s.cache[remote] = entry
+	}
+	if !entry.refreshing && time.Since(entry.fetchedAt) >= quotaTTL {
+		entry.refreshing = true
+		go s.refresh(s.context(), remote)
+	}
+	return entry.usage
+}
+
+// Invalidate makes the next Usage of remote read it again, as after an
+// upload to it
+func (s *QuotaService) Invalidate(remote string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry := s.cache[remote]; entry != nil {
+		entry.fetchedAt = time.Time{}
+	}
+}
+
+func (s *QuotaService) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
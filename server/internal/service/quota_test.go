@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gravity/internal/engine"
+	"gravity/internal/model"
+)
+
+// usageEngine is an UploadEngine that only reports remote usage. Reads wait
+// on release when it is set.
+type usageEngine struct {
+	engine.UploadEngine
+	release chan struct{}
+
+	mu    sync.Mutex
+	free  int64
+	reads int
+}
+
+func (e *usageEngine) About(ctx context.Context, remote string) (*model.RemoteUsage, error) {
+	if e.release != nil {
+		<-e.release
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reads++
+	free := e.free
+	return &model.RemoteUsage{Remote: remote, Free: &free}, nil
+}
+
+func (e *usageEngine) OnProgress(func(string, engine.UploadProgress)) {}
+func (e *usageEngine) OnComplete(func(string))                        {}
+func (e *usageEngine) OnError(func(string, error))                    {}
+
+func (e *usageEngine) readCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.reads
+}
+
+// waitForUsage waits for the background read of remote to land
+func waitForUsage(t *testing.T, q *QuotaService, remote string, free int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if got, ok := q.Cached(remote).FreeSpace(); ok && got == free {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("usage of %s never read", remote)
+}
+
+func TestQuotaUsageInBackground(t *testing.T) {
+	e := &usageEngine{release: make(chan struct{}), free: 100}
+	q := NewQuotaService(e)
+
+	// The first call never waits on the backend, nor starts a second read
+	for range 2 {
+		if u := q.Usage("gdrive"); u != nil {
+			t.Fatalf("usage before any read = %+v", u)
+		}
+	}
+	close(e.release)
+	waitForUsage(t, q, "gdrive", 100)
+
+	if free, _ := q.Usage("gdrive").FreeSpace(); free != 100 || e.readCount() != 1 {
+		t.Errorf("fresh usage: %d free after %d reads", free, e.readCount())
+	}
+
+	// A stale usage is served while it is read again
+	e.mu.Lock()
+	e.free = 50
+	e.mu.Unlock()
+	q.Invalidate("gdrive")
+	if free, _ := q.Usage("gdrive").FreeSpace(); free != 100 {
+		t.Errorf("stale usage: %d free, want the last known 100", free)
+	}
+	waitForUsage(t, q, "gdrive", 50)
+}
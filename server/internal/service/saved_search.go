@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/event"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+	"gravity/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// How many matches an alert lists; it always has the total
+	savedSearchAlertFiles = 20
+	// How many matches one alert queues copies for, so a broad search
+	// cannot flood the transfer queue
+	savedSearchMaxActions = 100
+)
+
+// SavedSearchService stores named searches and checks them against the
+// files each index run adds, publishing an alert and queueing the search's
+// action for new matches
+type SavedSearchService struct {
+	repo       *store.SavedSearchRepo
+	searchRepo *store.SearchRepo
+	engine     engine.UploadEngine
+	bus        *event.Bus
+	logger     *zap.Logger
+}
+
+func NewSavedSearchService(repo *store.SavedSearchRepo, searchRepo *store.SearchRepo, eng engine.UploadEngine, bus *event.Bus) *SavedSearchService {
+	return &SavedSearchService{
+		repo:       repo,
+		searchRepo: searchRepo,
+		engine:     eng,
+		bus:        bus,
+		logger:     logger.Component("SAVED_SEARCH"),
+	}
+}
+
+func (s *SavedSearchService) List(ctx context.Context) ([]*model.SavedSearch, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *SavedSearchService) Get(ctx context.Context, id string) (*model.SavedSearch, error) {
+	ss, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.NewNotFound("saved search", id)
+	}
+	return ss, err
+}
+
+func (s *SavedSearchService) Create(ctx context.Context, ss *model.SavedSearch) (*model.SavedSearch, error) {
+	if err := ss.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkName(ctx, ss.Name, ""); err != nil {
+		return nil, err
+	}
+	ss.ID = "ss_" + uuid.New().String()[:8]
+	ss.LastMatchAt = nil
+	ss.MatchCount = 0
+
+	if err := s.repo.Create(ctx, ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// Update replaces the definition of a saved search, keeping its match count
+func (s *SavedSearchService) Update(ctx context.Context, id string, ss *model.SavedSearch) (*model.SavedSearch, error) {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ss.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkName(ctx, ss.Name, id); err != nil {
+		return nil, err
+	}
+
+	ss.ID = existing.ID
+	ss.CreatedAt = existing.CreatedAt
+	ss.LastMatchAt = existing.LastMatchAt
+	ss.MatchCount = existing.MatchCount
+
+	if err := s.repo.Update(ctx, ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+func (s *SavedSearchService) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Check runs the enabled saved searches against the files of remote first
+// indexed at or after since
+func (s *SavedSearchService) Check(ctx context.Context, remote string, since time.Time) {
+	if s == nil {
+		return
+	}
+	searches, err := s.repo.ListEnabled(ctx)
+	if err != nil {
+		s.logger.Warn("failed to load saved searches", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for _, ss := range searches {
+		s.check(ctx, ss, remote, since, now)
+	}
+}
+
+func (s *SavedSearchService) check(ctx context.Context, ss *model.SavedSearch, remote string, since, now time.Time) {
+	q, err := model.ParseSearchQuery(ss.Query, now)
+	if err != nil {
+		s.logger.Warn("invalid saved search", zap.String("id", ss.ID), zap.Error(err))
+		return
+	}
+	limit := savedSearchAlertFiles
+	if ss.Action != model.SavedSearchNotify {
+		limit = savedSearchMaxActions
+	}
+	files, total, err := s.searchRepo.NewMatches(ctx, q, remote, since, limit)
+	if err != nil {
+		s.logger.Warn("failed to run saved search", zap.String("id", ss.ID), zap.String("remote", remote), zap.Error(err))
+		return
+	}
+	if total == 0 {
+		return
+	}
+
+	match := model.SavedSearchMatch{
+		SearchID: ss.ID,
+		Name:     ss.Name,
+		Remote:   remote,
+		Total:    total,
+	}
+	for _, f := range files {
+		target := ss.Target(f.Path)
+		// A copy made by an earlier alert matches again once indexed
+		if target == "" || f.IsDir || target == f.Path {
+			continue
+		}
+		jobID, err := s.engine.Copy(ctx, f.Path, target)
+		if err != nil {
+			s.logger.Warn("failed to queue saved search copy", zap.String("id", ss.ID), zap.String("path", f.Path), zap.Error(err))
+			continue
+		}
+		match.Jobs = append(match.Jobs, jobID)
+	}
+	if ss.Action != model.SavedSearchNotify && total > len(files) {
+		s.logger.Warn("saved search matched more files than one alert copies",
+			zap.String("id", ss.ID), zap.Int("matched", total), zap.Int("copied", len(match.Jobs)))
+	}
+	match.Files = files[:min(len(files), savedSearchAlertFiles)]
+
+	if err := s.repo.RecordMatch(ctx, ss.ID, total, now); err != nil {
+		s.logger.Warn("failed to record saved search match", zap.String("id", ss.ID), zap.Error(err))
+	}
+	s.logger.Info("saved search matched new files", zap.String("id", ss.ID), zap.String("remote", remote), zap.Int("count", total))
+
+	if s.bus != nil {
+		s.bus.PublishLifecycle(event.LifecycleEvent{
+			Type:      event.SavedSearchMatched,
+			ID:        ss.ID,
+			Timestamp: now,
+			Data:      match,
+		})
+	}
+}
+
+func (s *SavedSearchService) checkName(ctx context.Context, name, id string) error {
+	existing, err := s.repo.GetByName(ctx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("a saved search named %q already exists", name))
+	}
+	return nil
+}
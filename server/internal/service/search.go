@@ -9,31 +9,44 @@ import (
 	"time"
 
 	"gravity/internal/engine"
+	"gravity/internal/event"
 	"gravity/internal/logger"
 	"gravity/internal/model"
 	"gravity/internal/store"
 
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// How often indexing progress is published
+const indexProgressInterval = time.Second
+
 type SearchService struct {
 	repo          *store.SearchRepo
 	settingsRepo  *store.SettingsRepo
 	storageEngine engine.StorageEngine
+	downloadRepo  *store.DownloadRepo
+	savedSearches *SavedSearchService
+	bus           *event.Bus
 	mu            sync.Mutex
 	isIndexing    map[string]bool
 	ctx           context.Context
 	logger        *zap.Logger
+
+	localMu sync.Mutex
+	locals  map[string]*localIndex
 }
 
-func NewSearchService(repo *store.SearchRepo, settingsRepo *store.SettingsRepo, storage engine.StorageEngine) *SearchService {
+func NewSearchService(repo *store.SearchRepo, settingsRepo *store.SettingsRepo, storage engine.StorageEngine, bus *event.Bus, downloadRepo *store.DownloadRepo, savedSearches *SavedSearchService) *SearchService {
 	return &SearchService{
 		repo:          repo,
 		settingsRepo:  settingsRepo,
 		storageEngine: storage,
+		downloadRepo:  downloadRepo,
+		savedSearches: savedSearches,
+		bus:           bus,
 		isIndexing:    make(map[string]bool),
 		logger:        logger.Component("SEARCH"),
+		locals:        make(map[string]*localIndex),
 	}
 }
 
@@ -42,6 +55,9 @@ func (s *SearchService) Start(ctx context.Context) {
 		return
 	}
 	s.ctx = ctx
+	s.resumeRuns()
+	s.syncLocalRoots()
+	s.startDownloadIndex()
 	go func() {
 		ticker := time.NewTicker(5 * time.Minute)
 		defer ticker.Stop()
@@ -49,6 +65,7 @@ func (s *SearchService) Start(ctx context.Context) {
 			select {
 			case <-ticker.C:
 				s.checkAutoIndexing()
+				s.syncLocalRoots()
 			case <-s.ctx.Done():
 				return
 			}
@@ -56,6 +73,18 @@ func (s *SearchService) Start(ctx context.Context) {
 	}()
 }
 
+// resumeRuns restarts the runs an earlier process left unfinished
+func (s *SearchService) resumeRuns() {
+	runs, err := s.repo.ListRuns(s.ctx)
+	if err != nil {
+		s.logger.Warn("failed to load interrupted index runs", zap.Error(err))
+		return
+	}
+	for _, run := range runs {
+		go s.IndexRemote(s.ctx, run.Remote)
+	}
+}
+
 func (s *SearchService) checkAutoIndexing() {
 	if s == nil || s.settingsRepo == nil {
 		return
@@ -92,6 +121,12 @@ func (s *SearchService) checkAutoIndexing() {
 	}
 }
 
+// IndexRemote brings the index of remote up to date. Top-level folders are
+// listed one at a time and diffed against the index as listing batches
+// arrive, so existing results stay searchable; rows not seen are removed at
+// the end. A run that fails or is interrupted resumes after the last
+// finished top-level folder. Saved searches are then checked against the
+// files the run added, unless it was the first index of the remote.
 func (s *SearchService) IndexRemote(ctx context.Context, remote string) error {
 	s.mu.Lock()
 	if s.isIndexing[remote] {
@@ -101,118 +136,259 @@ func (s *SearchService) IndexRemote(ctx context.Context, remote string) error {
 	s.isIndexing[remote] = true
 	s.mu.Unlock()
 
-	s.logger.Info("starting remote indexing", zap.String("remote", remote))
-
 	defer func() {
 		s.mu.Lock()
 		delete(s.isIndexing, remote)
 		s.mu.Unlock()
 	}()
 
+	// Everything is new to the first index; alerting on it is noise
+	initial := s.configFor(ctx, remote).LastIndexedAt == nil
 	s.updateConfigStatus(ctx, remote, "indexing", "")
 
-	// Fetch current config for filtering
-	configs, err := s.GetConfigs(ctx)
-	var config model.RemoteIndexConfig
+	run, err := s.repo.GetRun(ctx, remote)
 	if err == nil {
-		for _, c := range configs {
-			if c.Remote == remote {
-				config = c
-				break
-			}
+		run.Resumed = true
+		s.logger.Info("resuming remote indexing", zap.String("remote", remote), zap.Strings("done", run.Done))
+	} else {
+		run = &model.IndexRun{Remote: remote, StartedAt: time.Now()}
+		s.logger.Info("starting remote indexing", zap.String("remote", remote))
+	}
+	s.publishIndex(event.IndexStarted, run, nil)
+
+	job := &indexJob{
+		run:    run,
+		source: s.sourceOf(remote),
+		filter: newIndexFilter(s.configFor(ctx, remote)),
+	}
+	if err := s.indexRun(ctx, job); err != nil {
+		s.logger.Error("remote indexing failed", zap.String("remote", remote), zap.Error(err))
+		s.updateConfigStatus(ctx, remote, "error", err.Error())
+		s.publishIndex(event.IndexError, run, err)
+		return err
+	}
+
+	s.logger.Info("remote indexing completed", zap.String("remote", remote),
+		zap.Int64("scanned", run.Scanned), zap.Int64("added", run.Added),
+		zap.Int64("updated", run.Updated), zap.Int64("removed", run.Removed))
+	s.publishIndex(event.IndexCompleted, run, nil)
+	if !initial {
+		s.savedSearches.Check(ctx, remote, run.StartedAt)
+	}
+	return s.updateLastIndexed(ctx, remote)
+}
+
+// indexJob is an indexing run in progress
+type indexJob struct {
+	run          *model.IndexRun
+	source       string
+	filter       *indexFilter
+	lastProgress time.Time
+}
+
+func (s *SearchService) indexRun(ctx context.Context, job *indexJob) error {
+	run := job.run
+	if err := s.repo.SaveRun(ctx, run); err != nil {
+		return err
+	}
+
+	root := "/" + run.Remote
+	top, err := s.storageEngine.List(ctx, root)
+	if err != nil {
+		return err
+	}
+	// The top level is listed again on resume; it is small
+	if err := s.indexBatch(ctx, job, top); err != nil {
+		return err
+	}
+
+	done := make(map[string]bool, len(run.Done))
+	for _, name := range run.Done {
+		done[name] = true
+	}
+	for _, item := range top {
+		if !item.IsDir || done[item.Name] {
+			continue
+		}
+		err := s.listTree(ctx, item.Path, func(batch []engine.FileInfo) error {
+			return s.indexBatch(ctx, job, batch)
+		})
+		if err != nil {
+			return err
+		}
+		run.Done = append(run.Done, item.Name)
+		if err := s.repo.SaveRun(ctx, run); err != nil {
+			return err
 		}
 	}
 
-	files, err := s.listRecursive(ctx, remote, "", config)
+	removed, err := s.repo.DeleteStale(ctx, run.Remote, run.StartedAt)
 	if err != nil {
-		s.logger.Error("failed to list files for indexing", zap.String("remote", remote), zap.Error(err))
-		s.updateConfigStatus(ctx, remote, "error", err.Error())
 		return err
 	}
+	run.Removed = removed
+	return s.repo.DeleteRun(ctx, run.Remote)
+}
 
-	s.logger.Debug("files listed for indexing", zap.String("remote", remote), zap.Int("count", len(files)))
+// indexBatch applies one listing batch to the index and reports progress at
+// most once per indexProgressInterval
+func (s *SearchService) indexBatch(ctx context.Context, job *indexJob, batch []engine.FileInfo) error {
+	run := job.run
+	files := indexedFiles(job.source, job.filter, batch)
+	added, updated, err := s.repo.ApplyFiles(ctx, run.Remote, files, run.StartedAt)
+	run.Scanned += int64(len(batch))
+	run.Added += int64(added)
+	run.Updated += int64(updated)
+	if err != nil {
+		return err
+	}
+	if time.Since(job.lastProgress) >= indexProgressInterval {
+		s.publishIndex(event.IndexProgress, run, nil)
+		job.lastProgress = time.Now()
+	}
+	return nil
+}
 
-	// Convert to model format
-	indexedFiles := make([]model.IndexedFile, len(files))
-	for i, f := range files {
-		indexedFiles[i] = model.IndexedFile{
-			ID:      uuid.New().String(),
-			Remote:  remote,
+// indexedFiles converts the entries of batch that filter keeps to rows
+func indexedFiles(source string, filter *indexFilter, batch []engine.FileInfo) []model.IndexedFile {
+	files := make([]model.IndexedFile, 0, len(batch))
+	for _, f := range batch {
+		if !filter.keep(&f) {
+			continue
+		}
+		var ext string
+		if !f.IsDir {
+			ext = model.FileExt(f.Name)
+		}
+		files = append(files, model.IndexedFile{
+			Source:  source,
 			Path:    f.Path,
 			Name:    f.Name,
+			Ext:     ext,
 			Size:    f.Size,
 			ModTime: f.ModTime,
 			IsDir:   f.IsDir,
-		}
+		})
 	}
+	return files
+}
 
-	if err := s.repo.SaveFiles(ctx, remote, indexedFiles); err != nil {
-		s.logger.Error("failed to save indexed files to DB", zap.String("remote", remote), zap.Error(err))
-		s.updateConfigStatus(ctx, remote, "error", err.Error())
-		return err
+// listTree calls fn with batches of the tree below virtualPath, using the
+// engine's recursive listing when it has one
+func (s *SearchService) listTree(ctx context.Context, virtualPath string, fn func([]engine.FileInfo) error) error {
+	if lister, ok := s.storageEngine.(engine.RecursiveLister); ok {
+		return lister.ListR(ctx, virtualPath, fn)
 	}
 
-	s.logger.Info("remote indexing completed", zap.String("remote", remote), zap.Int("count", len(indexedFiles)))
-	return s.updateLastIndexed(ctx, remote)
-}
-
-func (s *SearchService) listRecursive(ctx context.Context, remote, path string, config model.RemoteIndexConfig) ([]engine.FileInfo, error) {
-	// Root of remote
-	virtualPath := "/" + remote + "/" + path
 	items, err := s.storageEngine.List(ctx, virtualPath)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	var excludeReg *regexp.Regexp
-	if config.ExcludedPatterns != "" {
-		excludeReg, _ = regexp.Compile(config.ExcludedPatterns)
+	if err := fn(items); err != nil {
+		return err
 	}
-
-	var results []engine.FileInfo
 	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if item.IsDir {
-			results = append(results, item)
-			// Subdirectory listing
-			subPath := strings.TrimPrefix(item.Path, "/"+remote+"/")
-			subItems, err := s.listRecursive(ctx, remote, subPath, config)
-			if err == nil {
-				results = append(results, subItems...)
+			if err := s.listTree(ctx, item.Path, fn); err != nil {
+				return err
 			}
-			continue
 		}
+	}
+	return nil
+}
 
-		// Apply Filters for Files
-		if config.MinSizeBytes > 0 && item.Size < config.MinSizeBytes {
-			continue
-		}
+func (s *SearchService) publishIndex(t event.EventType, run *model.IndexRun, err error) {
+	if s.bus == nil {
+		return
+	}
+	e := event.LifecycleEvent{
+		Type:      t,
+		ID:        run.Remote,
+		Timestamp: time.Now(),
+		Data:      *run,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.bus.PublishLifecycle(e)
+}
 
-		if config.IncludedExtensions != "" {
-			exts := strings.Split(strings.ToLower(config.IncludedExtensions), ",")
-			match := false
-			for _, ext := range exts {
-				ext = "." + strings.TrimPrefix(strings.TrimSpace(ext), ".")
-				if strings.HasSuffix(strings.ToLower(item.Name), ext) {
-					match = true
-					break
-				}
-			}
-			if !match {
-				continue
+// configFor returns the index config of remote, or the defaults if it has
+// none
+func (s *SearchService) configFor(ctx context.Context, remote string) model.RemoteIndexConfig {
+	settings, err := s.settingsRepo.Get(ctx)
+	if err == nil && settings != nil {
+		for _, c := range settings.Search.Configs {
+			if c.Remote == remote {
+				return c
 			}
 		}
+	}
+	return model.RemoteIndexConfig{Remote: remote}
+}
 
-		if excludeReg != nil && excludeReg.MatchString(item.Path) {
-			continue
+// sourceOf returns the index source of the files of remote
+func (s *SearchService) sourceOf(remote string) string {
+	if lister, ok := s.storageEngine.(engine.LocalRootLister); ok {
+		if _, ok := lister.LocalRoots()[remote]; ok {
+			return model.IndexSourceLocal
 		}
+	}
+	return model.IndexSourceRemote
+}
+
+// indexFilter holds the file filters of a remote's index config. Folders
+// are always indexed, except for the trash.
+type indexFilter struct {
+	minSize int64
+	exts    []string
+	exclude *regexp.Regexp
+}
 
-		results = append(results, item)
+func newIndexFilter(config model.RemoteIndexConfig) *indexFilter {
+	f := &indexFilter{minSize: config.MinSizeBytes}
+	if config.ExcludedPatterns != "" {
+		f.exclude, _ = regexp.Compile(config.ExcludedPatterns)
 	}
-	return results, nil
+	if config.IncludedExtensions != "" {
+		for _, ext := range strings.Split(strings.ToLower(config.IncludedExtensions), ",") {
+			f.exts = append(f.exts, "."+strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		}
+	}
+	return f
+}
+
+func (f *indexFilter) keep(item *engine.FileInfo) bool {
+	if inTrash(item.Path) {
+		return false
+	}
+	if item.IsDir {
+		return true
+	}
+	if f.minSize > 0 && item.Size < f.minSize {
+		return false
+	}
+	if len(f.exts) > 0 {
+		name := strings.ToLower(item.Name)
+		match := false
+		for _, ext := range f.exts {
+			if strings.HasSuffix(name, ext) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return f.exclude == nil || !f.exclude.MatchString(item.Path)
 }
 
-func (s *SearchService) Search(ctx context.Context, query string, limit, offset int) ([]model.IndexedFile, int, error) {
-	return s.repo.Search(ctx, query, limit, offset)
+func (s *SearchService) Search(ctx context.Context, query *model.SearchQuery, limit, offset int, facets bool) (*model.SearchResult, error) {
+	return s.repo.Search(ctx, query, limit, offset, facets)
 }
 
 func (s *SearchService) GetConfigs(ctx context.Context) ([]model.RemoteIndexConfig, error) {
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gravity/internal/event"
+	"gravity/internal/model"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// startDownloadIndex indexes the download history, then follows download
+// lifecycle events to keep it current
+func (s *SearchService) startDownloadIndex() {
+	if s.downloadRepo == nil || s.bus == nil {
+		return
+	}
+
+	// Subscribe first so no change made during the first pass is missed
+	lifecycle := s.bus.SubscribeLifecycle()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.logger.Error("panic in download index listener", zap.Any("panic", r))
+			}
+			s.bus.UnsubscribeLifecycle(lifecycle)
+		}()
+
+		if err := s.indexDownloads(s.ctx); err != nil {
+			s.logger.Warn("failed to index downloads", zap.Error(err))
+		}
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case ev, ok := <-lifecycle:
+				if !ok {
+					return
+				}
+				s.handleDownloadEvent(ev)
+			}
+		}
+	}()
+}
+
+func (s *SearchService) handleDownloadEvent(ev event.LifecycleEvent) {
+	if !strings.HasPrefix(string(ev.Type), "download.") || ev.ID == "" {
+		return
+	}
+	ctx := s.ctx
+
+	if ev.Type == event.DownloadDeleted {
+		if err := s.repo.DeleteDownload(ctx, ev.ID); err != nil {
+			s.logger.Warn("failed to remove download from index", zap.String("id", ev.ID), zap.Error(err))
+		}
+		return
+	}
+
+	d, err := s.downloadRepo.Get(ctx, ev.ID)
+	if err != nil {
+		return
+	}
+	if err := s.repo.SaveDownload(ctx, d.ID, downloadFiles(d)); err != nil {
+		s.logger.Warn("failed to index download", zap.String("id", d.ID), zap.Error(err))
+	}
+}
+
+// indexDownloads indexes the downloads changed since they were last
+// indexed and drops the ones that were deleted
+func (s *SearchService) indexDownloads(ctx context.Context) error {
+	indexed, err := s.repo.DownloadIndexTimes(ctx)
+	if err != nil {
+		return err
+	}
+
+	const pageSize = 500
+	count := 0
+	for offset := 0; ; offset += pageSize {
+		downloads, _, err := s.downloadRepo.List(ctx, nil, pageSize, offset, true)
+		if err != nil {
+			return err
+		}
+		for _, d := range downloads {
+			if t, ok := indexed[d.ID]; ok && t.Truncate(time.Microsecond).Equal(d.UpdatedAt.Truncate(time.Microsecond)) {
+				continue
+			}
+			if err := s.repo.SaveDownload(ctx, d.ID, downloadFiles(d)); err != nil {
+				return err
+			}
+			count++
+		}
+		if len(downloads) < pageSize {
+			break
+		}
+	}
+
+	if err := s.repo.PruneDownloads(ctx); err != nil {
+		return err
+	}
+	s.logger.Debug("download history indexed", zap.Int("updated", count))
+	return nil
+}
+
+// downloadFiles returns the rows of d: one for the download itself, with
+// its URL and provider, and one per file of a multi-file download. Rows are
+// stamped with the download's update time.
+func downloadFiles(d *model.Download) []model.IndexedFile {
+	name := d.Filename
+	if name == "" {
+		name = path.Base(d.URL)
+	}
+	modTime := d.CreatedAt
+	if d.CompletedAt != nil {
+		modTime = *d.CompletedAt
+	}
+	multi := len(d.Files) > 1
+
+	top := model.IndexedFile{
+		ID:            uuid.New().String(),
+		Source:        model.IndexSourceDownload,
+		Path:          filepath.Join(d.Dir, d.Filename),
+		Name:          name,
+		Size:          d.Size,
+		ModTime:       modTime,
+		IsDir:         multi,
+		LastIndexedAt: d.UpdatedAt,
+		DownloadID:    d.ID,
+		URL:           d.URL,
+		Provider:      d.Provider,
+	}
+	if !multi {
+		top.Ext = model.FileExt(name)
+	}
+	files := []model.IndexedFile{top}
+	if !multi {
+		return files
+	}
+
+	for _, f := range d.Files {
+		fileName := f.Name
+		if fileName == "" {
+			fileName = path.Base(f.Path)
+		}
+		fileModTime := modTime
+		if f.ModTime != nil {
+			fileModTime = *f.ModTime
+		}
+		files = append(files, model.IndexedFile{
+			ID:            uuid.New().String(),
+			Source:        model.IndexSourceDownload,
+			Path:          filepath.Join(d.Dir, f.Path),
+			Name:          fileName,
+			Ext:           model.FileExt(fileName),
+			Size:          f.Size,
+			ModTime:       fileModTime,
+			LastIndexedAt: d.UpdatedAt,
+			DownloadID:    d.ID,
+		})
+	}
+	return files
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gravity/internal/model"
+	"gravity/internal/store"
+)
+
+func TestDownloadFilesSingle(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := &model.Download{
+		ID:        "d_1",
+		URL:       "https://example.com/files/ubuntu.iso",
+		Provider:  "direct",
+		Dir:       "/data/downloads",
+		Size:      100,
+		Files:     []model.DownloadFile{{Name: "ubuntu.iso", Path: "ubuntu.iso", Size: 100}},
+		CreatedAt: updated.Add(-time.Hour),
+		UpdatedAt: updated,
+	}
+
+	// Without a filename yet the name comes from the URL
+	files := downloadFiles(d)
+	if len(files) != 1 {
+		t.Fatalf("%d rows, want 1", len(files))
+	}
+	if files[0].Name != "ubuntu.iso" || files[0].ModTime != d.CreatedAt {
+		t.Errorf("unnamed download: name %q, modtime %v", files[0].Name, files[0].ModTime)
+	}
+
+	d.Filename = "ubuntu.iso"
+	completed := updated.Add(-time.Minute)
+	d.CompletedAt = &completed
+	f := downloadFiles(d)[0]
+	if f.Path != "/data/downloads/ubuntu.iso" || f.Name != "ubuntu.iso" || f.Ext != "iso" || f.IsDir {
+		t.Errorf("row = %+v", f)
+	}
+	if f.URL != d.URL || f.Provider != "direct" || f.DownloadID != "d_1" || f.Source != model.IndexSourceDownload {
+		t.Errorf("download fields = %q, %q, %q, %q", f.URL, f.Provider, f.DownloadID, f.Source)
+	}
+	if !f.ModTime.Equal(completed) || !f.LastIndexedAt.Equal(updated) {
+		t.Errorf("times: modtime %v, indexed %v", f.ModTime, f.LastIndexedAt)
+	}
+}
+
+func TestDownloadFilesMulti(t *testing.T) {
+	fileTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	d := &model.Download{
+		ID:        "d_2",
+		URL:       "magnet:?xt=urn:btih:abc",
+		Filename:  "Show S01",
+		Dir:       "/data/downloads",
+		Size:      300,
+		CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Files: []model.DownloadFile{
+			{Name: "e01.mkv", Path: "Show S01/e01.mkv", Size: 100, ModTime: &fileTime},
+			{Path: "Show S01/Subs/e01.EN.srt", Size: 200},
+		},
+	}
+
+	files := downloadFiles(d)
+	if len(files) != 3 {
+		t.Fatalf("%d rows, want 3", len(files))
+	}
+	top := files[0]
+	if top.Path != "/data/downloads/Show S01" || !top.IsDir || top.Ext != "" || top.Size != 300 || top.URL != d.URL {
+		t.Errorf("top row = %+v", top)
+	}
+
+	want := []struct {
+		path, name, ext string
+		modTime         time.Time
+	}{
+		{"/data/downloads/Show S01/e01.mkv", "e01.mkv", "mkv", fileTime},
+		{"/data/downloads/Show S01/Subs/e01.EN.srt", "e01.EN.srt", "srt", d.CreatedAt},
+	}
+	for i, w := range want {
+		f := files[i+1]
+		if f.Path != w.path || f.Name != w.name || f.Ext != w.ext || !f.ModTime.Equal(w.modTime) {
+			t.Errorf("file %d = %q %q %q %v, want %+v", i, f.Path, f.Name, f.Ext, f.ModTime, w)
+		}
+		if f.DownloadID != d.ID || f.URL != "" || f.IsDir {
+			t.Errorf("file %d carries download fields: %+v", i, f)
+		}
+	}
+	if files[1].ID == files[2].ID {
+		t.Error("rows share an ID")
+	}
+}
+
+func TestIndexDownloadsIncremental(t *testing.T) {
+	ctx := context.Background()
+	db := newTestStore(t).GetDB()
+	downloads := store.NewDownloadRepo(db)
+	s := NewSearchService(store.NewSearchRepo(db), store.NewSettingsRepo(db), nil, nil, downloads, nil)
+
+	for _, d := range []*model.Download{
+		{ID: "d_keep", URL: "https://example.com/a.iso", Filename: "a.iso", Dir: "/dl", Status: model.StatusComplete},
+		{ID: "d_gone", URL: "https://example.com/b.iso", Filename: "b.iso", Dir: "/dl", Status: model.StatusComplete},
+	} {
+		if err := downloads.Create(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func() map[string]string {
+		var rows []model.IndexedFile
+		db.Table("indexed_files").Where("source = ?", model.IndexSourceDownload).Find(&rows)
+		byID := make(map[string]string)
+		for _, row := range rows {
+			byID[row.DownloadID] = row.Name
+		}
+		return byID
+	}
+
+	if err := s.indexDownloads(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); got["d_keep"] != "a.iso" || got["d_gone"] != "b.iso" {
+		t.Fatalf("first pass indexed %v", got)
+	}
+
+	// A row is only rebuilt once its download's update time moves
+	db.Model(&model.Download{}).Where("id = ?", "d_keep").UpdateColumn("filename", "renamed.iso")
+	if err := s.indexDownloads(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := names()["d_keep"]; got != "a.iso" {
+		t.Errorf("unchanged download was reindexed as %q", got)
+	}
+
+	d, err := downloads.Get(ctx, "d_keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := downloads.Update(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if err := downloads.Delete(ctx, "d_gone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.indexDownloads(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := names()
+	if got["d_keep"] != "renamed.iso" {
+		t.Errorf("updated download indexed as %q", got["d_keep"])
+	}
+	if _, ok := got["d_gone"]; ok {
+		t.Error("deleted download is still indexed")
+	}
+}
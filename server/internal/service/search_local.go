@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gravity/internal/engine"
+	"gravity/internal/model"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// How long a changed local path must go without further changes before it
+// is indexed, so a file being written is indexed once it is done
+const localIndexDelay = 2 * time.Second
+
+// Statuses of downloads whose files may still be written. Their paths are
+// indexed once the download leaves them.
+var localBusyStatuses = []string{
+	string(model.StatusWaiting),
+	string(model.StatusAllocating),
+	string(model.StatusActive),
+	string(model.StatusPaused),
+	string(model.StatusProcessing),
+}
+
+// localIndex keeps the index of a local root, such as the download dir, in
+// step with the disk using filesystem notifications
+type localIndex struct {
+	name    string
+	dir     string
+	watcher *fsnotify.Watcher
+	cancel  context.CancelFunc
+}
+
+// syncLocalRoots starts indexing and watching the local roots of the engine
+// and stops for roots that are gone or moved
+func (s *SearchService) syncLocalRoots() {
+	lister, ok := s.storageEngine.(engine.LocalRootLister)
+	if !ok {
+		return
+	}
+	roots := lister.LocalRoots()
+
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+
+	for name, l := range s.locals {
+		if roots[name] != l.dir {
+			l.cancel()
+			delete(s.locals, name)
+			s.logger.Info("stopped watching local root", zap.String("root", name), zap.String("dir", l.dir))
+		}
+	}
+	for name, dir := range roots {
+		if _, ok := s.locals[name]; ok {
+			continue
+		}
+		l, err := s.watchLocal(name, dir)
+		if err != nil {
+			s.logger.Warn("failed to watch local root", zap.String("root", name), zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+		s.locals[name] = l
+	}
+}
+
+// watchLocal watches dir before indexing it in full, so no change made during
+// the first run is missed
+func (s *SearchService) watchLocal(name, dir string) (*localIndex, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	l := &localIndex{name: name, dir: dir, watcher: watcher, cancel: cancel}
+	s.addWatches(l, dir)
+
+	go func() {
+		if err := s.IndexRemote(ctx, name); err != nil {
+			s.logger.Warn("initial local index failed", zap.String("root", name), zap.Error(err))
+		}
+	}()
+	go s.runLocal(ctx, l)
+
+	s.logger.Info("watching local root", zap.String("root", name), zap.String("dir", dir))
+	return l, nil
+}
+
+// runLocal collects changed paths with the time of their last change and
+// indexes the settled ones every localIndexDelay
+func (s *SearchService) runLocal(ctx context.Context, l *localIndex) {
+	defer l.watcher.Close()
+	ticker := time.NewTicker(localIndexDelay)
+	defer ticker.Stop()
+
+	pending := make(map[string]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-l.watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op != fsnotify.Chmod {
+				pending[ev.Name] = time.Now()
+			}
+		case err, ok := <-l.watcher.Errors:
+			if !ok {
+				return
+			}
+			// An overflow drops events; the next full run catches up
+			s.logger.Warn("local watch error", zap.String("root", l.name), zap.Error(err))
+		case now := <-ticker.C:
+			if len(pending) > 0 {
+				s.flushLocal(ctx, l, pending, now)
+			}
+		}
+	}
+}
+
+// flushLocal indexes the pending paths that have not changed for
+// localIndexDelay and do not belong to a download still in progress, then
+// checks saved searches against the files that were added. The other paths
+// stay pending, so saved searches never act on a half-written file.
+func (s *SearchService) flushLocal(ctx context.Context, l *localIndex, pending map[string]time.Time, now time.Time) {
+	busy := s.busyDownloadPaths(ctx)
+	since := time.Now()
+	indexed := 0
+	for p, changed := range pending {
+		if now.Sub(changed) < localIndexDelay || underAny(p, busy) {
+			continue
+		}
+		s.indexLocalPath(ctx, l, p)
+		delete(pending, p)
+		indexed++
+	}
+	if indexed > 0 {
+		s.savedSearches.Check(ctx, l.name, since)
+	}
+}
+
+// busyDownloadPaths returns the local paths of downloads still being
+// written
+func (s *SearchService) busyDownloadPaths(ctx context.Context) []string {
+	if s.downloadRepo == nil {
+		return nil
+	}
+	downloads, _, err := s.downloadRepo.List(ctx, localBusyStatuses, 1000, 0, false)
+	if err != nil {
+		s.logger.Warn("failed to list active downloads", zap.Error(err))
+		return nil
+	}
+	var paths []string
+	for _, d := range downloads {
+		// Without a name yet the file cannot be told apart from others
+		if d.Dir != "" && d.Filename != "" {
+			paths = append(paths, filepath.Join(d.Dir, d.Filename))
+		}
+	}
+	return paths
+}
+
+// underAny reports whether localPath is one of paths, below one, or a file
+// kept next to one while it downloads, such as "movie.mkv.aria2"
+func underAny(localPath string, paths []string) bool {
+	for _, p := range paths {
+		if localPath == p || strings.HasPrefix(localPath, p+string(filepath.Separator)) || strings.HasPrefix(localPath, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// indexLocalPath brings the rows of one changed path up to date. A new
+// folder is watched and indexed with everything in it, since it may have
+// been moved in whole.
+func (s *SearchService) indexLocalPath(ctx context.Context, l *localIndex, localPath string) {
+	virtualPath, ok := l.virtualPath(localPath)
+	if !ok || inTrash(virtualPath) {
+		return
+	}
+
+	fi, err := os.Lstat(localPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := s.repo.DeleteTree(ctx, l.name, virtualPath); err != nil {
+			s.logger.Warn("failed to remove local path from index", zap.String("path", localPath), zap.Error(err))
+		}
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	batch := []engine.FileInfo{localFileInfo(virtualPath, fi)}
+	if fi.IsDir() {
+		s.addWatches(l, localPath)
+		filepath.WalkDir(localPath, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == localPath {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if vp, ok := l.virtualPath(p); ok {
+				batch = append(batch, localFileInfo(vp, info))
+			}
+			return nil
+		})
+	}
+
+	files := indexedFiles(model.IndexSourceLocal, newIndexFilter(s.configFor(ctx, l.name)), batch)
+	if _, _, err := s.repo.ApplyFiles(ctx, l.name, files, time.Now()); err != nil {
+		s.logger.Warn("failed to index local path", zap.String("path", localPath), zap.Error(err))
+	}
+}
+
+// addWatches watches dir and every folder below it; fsnotify does not
+// recurse on its own
+func (s *SearchService) addWatches(l *localIndex, dir string) {
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name() == TrashDirName {
+			return filepath.SkipDir
+		}
+		if err := l.watcher.Add(p); err != nil {
+			// Usually the inotify watch limit; the rest is found by full runs
+			s.logger.Warn("failed to watch folder", zap.String("dir", p), zap.Error(err))
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// virtualPath maps a path in the root's folder to its path in GravityRoot
+func (l *localIndex) virtualPath(localPath string) (string, bool) {
+	rel, err := filepath.Rel(l.dir, localPath)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return path.Join("/", l.name, filepath.ToSlash(rel)), true
+}
+
+func localFileInfo(virtualPath string, fi fs.FileInfo) engine.FileInfo {
+	info := engine.FileInfo{
+		Path:    virtualPath,
+		Name:    fi.Name(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+	}
+	if !fi.IsDir() {
+		info.Size = fi.Size()
+	}
+	return info
+}
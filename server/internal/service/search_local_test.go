@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"gravity/internal/model"
+	"gravity/internal/store"
+)
+
+func TestLocalIndexVirtualPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "downloads")
+	l := &localIndex{name: "local", dir: dir}
+
+	tests := []struct {
+		localPath string
+		want      string
+		ok        bool
+	}{
+		{filepath.Join(dir, "movie.mkv"), "/local/movie.mkv", true},
+		{filepath.Join(dir, "show", "s01", "e01.mkv"), "/local/show/s01/e01.mkv", true},
+		{dir, "", false},
+		{filepath.Dir(dir), "", false},
+		{filepath.Join(dir, "..", "other", "a.mkv"), "", false},
+		{dir + "-old/a.mkv", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := l.virtualPath(tt.localPath)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("virtualPath(%q) = %q, %v, want %q, %v", tt.localPath, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// Trashed files map into the root but are never indexed
+	vp, ok := l.virtualPath(filepath.Join(dir, TrashDirName, "tr_a1b2c3d4", "movie.mkv"))
+	if !ok || !inTrash(vp) {
+		t.Errorf("trashed file mapped to %q, %v", vp, ok)
+	}
+	if vp, _ := l.virtualPath(filepath.Join(dir, "movie.mkv")); inTrash(vp) {
+		t.Errorf("%s counted as trash", vp)
+	}
+}
+
+func TestFlushLocalHoldsBusyPaths(t *testing.T) {
+	ctx := context.Background()
+	db := newTestStore(t).GetDB()
+	downloads := store.NewDownloadRepo(db)
+	s := NewSearchService(store.NewSearchRepo(db), store.NewSettingsRepo(db), nil, nil, downloads, nil)
+
+	dir := t.TempDir()
+	l := &localIndex{name: "local", dir: dir}
+	for _, name := range []string{"done.mkv", "part.mkv", "part.mkv.aria2", "fresh.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := &model.Download{ID: "d_1", URL: "https://example.com/part.mkv", Filename: "part.mkv", Dir: dir, Status: model.StatusActive}
+	if err := downloads.Create(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	indexed := func() []string {
+		var paths []string
+		db.Table("indexed_files").Where("remote = ?", "local").Order("path").Pluck("path", &paths)
+		return paths
+	}
+
+	now := time.Now()
+	settled := now.Add(-2 * localIndexDelay)
+	pending := map[string]time.Time{
+		filepath.Join(dir, "done.mkv"):       settled,
+		filepath.Join(dir, "part.mkv"):       settled,
+		filepath.Join(dir, "part.mkv.aria2"): settled,
+		filepath.Join(dir, "fresh.mkv"):      now, // Still being written
+	}
+	s.flushLocal(ctx, l, pending, now)
+	if got := indexed(); !slices.Equal(got, []string{"/local/done.mkv"}) {
+		t.Errorf("indexed %v, want only the settled file outside the download", got)
+	}
+	if len(pending) != 3 {
+		t.Errorf("%d paths left pending, want 3", len(pending))
+	}
+
+	// Once the download is done its file is indexed without a new change
+	db.Model(&model.Download{}).Where("id = ?", d.ID).UpdateColumn("status", model.StatusComplete)
+	os.Remove(filepath.Join(dir, "part.mkv.aria2"))
+	s.flushLocal(ctx, l, pending, now.Add(2*localIndexDelay))
+	want := []string{"/local/done.mkv", "/local/fresh.mkv", "/local/part.mkv"}
+	if got := indexed(); !slices.Equal(got, want) {
+		t.Errorf("indexed %v, want %v", got, want)
+	}
+	if len(pending) != 0 {
+		t.Errorf("%d paths left pending", len(pending))
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gravity/internal/engine"
+	"gravity/internal/model"
+	"gravity/internal/store"
+
+	"gorm.io/gorm"
+)
+
+func TestIndexFilterKeep(t *testing.T) {
+	filter := newIndexFilter(model.RemoteIndexConfig{
+		MinSizeBytes:       100,
+		IncludedExtensions: "mkv, .MP4",
+		ExcludedPatterns:   `/sample/`,
+	})
+
+	tests := []struct {
+		item engine.FileInfo
+		want bool
+	}{
+		{engine.FileInfo{Path: "/gdrive/movie.mkv", Name: "movie.mkv", Size: 500}, true},
+		{engine.FileInfo{Path: "/gdrive/clip.mp4", Name: "clip.mp4", Size: 500}, true},
+		{engine.FileInfo{Path: "/gdrive/small.mkv", Name: "small.mkv", Size: 10}, false},
+		{engine.FileInfo{Path: "/gdrive/notes.txt", Name: "notes.txt", Size: 500}, false},
+		{engine.FileInfo{Path: "/gdrive/sample/movie.mkv", Name: "movie.mkv", Size: 500}, false},
+		{engine.FileInfo{Path: "/gdrive/sample", Name: "sample", IsDir: true}, true},
+	}
+
+	for _, tt := range tests {
+		if got := filter.keep(&tt.item); got != tt.want {
+			t.Errorf("keep(%q) = %v, want %v", tt.item.Path, got, tt.want)
+		}
+	}
+}
+
+// listingStorage is a memStorage that records listed folders and can fail
+// to list one of them
+type listingStorage struct {
+	*memStorage
+	listed []string
+	fail   string
+}
+
+func (l *listingStorage) List(ctx context.Context, virtualPath string) ([]engine.FileInfo, error) {
+	if virtualPath == l.fail {
+		return nil, errors.New("connection reset")
+	}
+	l.listed = append(l.listed, virtualPath)
+	return l.memStorage.List(ctx, virtualPath)
+}
+
+func indexedPaths(db *gorm.DB, remote string) []string {
+	var paths []string
+	db.Table("indexed_files").Where("remote = ?", remote).Order("path").Pluck("path", &paths)
+	return paths
+}
+
+func TestApplyFiles(t *testing.T) {
+	ctx := context.Background()
+	db := newTestStore(t).GetDB()
+	repo := store.NewSearchRepo(db)
+	mod := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	file := func(p string, size int64) model.IndexedFile {
+		return model.IndexedFile{Source: model.IndexSourceRemote, Path: p, Name: p[strings.LastIndex(p, "/")+1:], Ext: "mkv", Size: size, ModTime: mod}
+	}
+	hashes := func() map[string]string {
+		var rows []model.IndexedFile
+		db.Table("indexed_files").Where("remote = ?", "gdrive").Find(&rows)
+		out := make(map[string]string, len(rows))
+		for _, r := range rows {
+			out[r.Path] = r.Hash
+		}
+		return out
+	}
+
+	first := time.Now().Add(-time.Hour)
+	added, updated, err := repo.ApplyFiles(ctx, "gdrive", []model.IndexedFile{
+		file("/gdrive/a.mkv", 1), file("/gdrive/b.mkv", 2), file("/gdrive/c.mkv", 3), file("/gdrive/a.mkv", 1),
+	}, first)
+	if err != nil || added != 3 || updated != 0 {
+		t.Fatalf("first apply: %d added, %d updated, %v", added, updated, err)
+	}
+	db.Table("indexed_files").Where("path IN ?", []string{"/gdrive/a.mkv", "/gdrive/b.mkv"}).Update("hash", "md5:x")
+
+	second := time.Now()
+	added, updated, err = repo.ApplyFiles(ctx, "gdrive", []model.IndexedFile{
+		file("/gdrive/a.mkv", 1), file("/gdrive/b.mkv", 20), file("/gdrive/d.mkv", 4),
+	}, second)
+	if err != nil || added != 1 || updated != 1 {
+		t.Fatalf("second apply: %d added, %d updated, %v", added, updated, err)
+	}
+	// A changed file must be hashed again, an unchanged one keeps its hash
+	if h := hashes(); h["/gdrive/a.mkv"] != "md5:x" || h["/gdrive/b.mkv"] != "" {
+		t.Errorf("hashes = %v", h)
+	}
+
+	removed, err := repo.DeleteStale(ctx, "gdrive", second)
+	if err != nil || removed != 1 {
+		t.Fatalf("DeleteStale: %d removed, %v", removed, err)
+	}
+	h := hashes()
+	if _, ok := h["/gdrive/c.mkv"]; ok || len(h) != 3 {
+		t.Errorf("rows left = %v", h)
+	}
+}
+
+func TestIndexRemoteChanges(t *testing.T) {
+	ctx := context.Background()
+	db := newTestStore(t).GetDB()
+	repo := store.NewSearchRepo(db)
+	storage := &listingStorage{memStorage: newMemStorage()}
+	storage.files["/gdrive/top.txt"] = []byte("t")
+	storage.files["/gdrive/movies/a.mkv"] = []byte("aaaa")
+	storage.files["/gdrive/shows/s1/e1.mkv"] = []byte("e1")
+	s := NewSearchService(repo, store.NewSettingsRepo(db), storage, nil, nil, nil)
+
+	run := func() *model.IndexRun {
+		job := &indexJob{
+			run:    &model.IndexRun{Remote: "gdrive", StartedAt: time.Now()},
+			source: model.IndexSourceRemote,
+			filter: newIndexFilter(model.RemoteIndexConfig{Remote: "gdrive"}),
+		}
+		if err := s.indexRun(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		return job.run
+	}
+
+	// top.txt, movies, movies/a.mkv, shows, shows/s1, shows/s1/e1.mkv
+	if r := run(); r.Added != 6 || r.Updated != 0 || r.Removed != 0 {
+		t.Errorf("first run: %d added, %d updated, %d removed", r.Added, r.Updated, r.Removed)
+	}
+
+	storage.files["/gdrive/movies/a.mkv"] = []byte("aaaaaaaa")
+	storage.files["/gdrive/movies/b.mkv"] = []byte("b")
+	delete(storage.files, "/gdrive/shows/s1/e1.mkv")
+	storage.dirs["/gdrive/shows"] = true
+
+	// shows/s1 is gone with its only file
+	if r := run(); r.Added != 1 || r.Updated != 1 || r.Removed != 2 {
+		t.Errorf("second run: %d added, %d updated, %d removed", r.Added, r.Updated, r.Removed)
+	}
+	want := []string{"/gdrive/movies", "/gdrive/movies/a.mkv", "/gdrive/movies/b.mkv", "/gdrive/shows", "/gdrive/top.txt"}
+	if got := indexedPaths(db, "gdrive"); !slices.Equal(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
+
+func TestIndexRemoteResumes(t *testing.T) {
+	ctx := context.Background()
+	db := newTestStore(t).GetDB()
+	repo := store.NewSearchRepo(db)
+	storage := &listingStorage{memStorage: newMemStorage(), fail: "/gdrive/shows"}
+	storage.files["/gdrive/movies/a.mkv"] = []byte("a")
+	storage.files["/gdrive/shows/e1.mkv"] = []byte("e1")
+	storage.files["/gdrive/zoo/old.mkv"] = []byte("z")
+	s := NewSearchService(repo, store.NewSettingsRepo(db), storage, nil, nil, nil)
+
+	// The row of a file deleted since an earlier index
+	if _, _, err := repo.ApplyFiles(ctx, "gdrive", []model.IndexedFile{
+		{Source: model.IndexSourceRemote, Path: "/gdrive/gone.mkv", Name: "gone.mkv"},
+	}, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.IndexRemote(ctx, "gdrive"); err == nil {
+		t.Fatal("index run survived a failed listing")
+	}
+	saved, err := repo.GetRun(ctx, "gdrive")
+	if err != nil || !slices.Equal(saved.Done, []string{"movies"}) {
+		t.Fatalf("interrupted run = %+v, %v", saved, err)
+	}
+	if got := indexedPaths(db, "gdrive"); !slices.Contains(got, "/gdrive/gone.mkv") {
+		t.Error("stale rows removed by an unfinished run")
+	}
+
+	storage.fail, storage.listed = "", nil
+	if err := s.IndexRemote(ctx, "gdrive"); err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(storage.listed, "/gdrive/movies") {
+		t.Errorf("resumed run listed a finished folder again: %v", storage.listed)
+	}
+	if _, err := repo.GetRun(ctx, "gdrive"); err == nil {
+		t.Error("finished run was kept")
+	}
+	want := []string{"/gdrive/movies", "/gdrive/movies/a.mkv", "/gdrive/shows", "/gdrive/shows/e1.mkv", "/gdrive/zoo", "/gdrive/zoo/old.mkv"}
+	if got := indexedPaths(db, "gdrive"); !slices.Equal(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
+
+func TestSearchFuzzyAndEmptyFiles(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewSearchRepo(newTestStore(t).GetDB())
+	var rows []model.IndexedFile
+	for name, size := range map[string]int64{
+		"Breaking.Bad.S01E01.mkv": 10,
+		"bad.mkv":                 10,
+		"Singing.in.the.rain.mkv": 10,
+		"empty.txt":               0,
+	} {
+		rows = append(rows, model.IndexedFile{Source: model.IndexSourceRemote, Path: "/gdrive/" + name, Name: name, Size: size})
+	}
+	if _, _, err := repo.ApplyFiles(ctx, "gdrive", rows, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	search := func(query string) []string {
+		t.Helper()
+		q, err := model.ParseSearchQuery(query, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := repo.Search(ctx, q, 10, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range res.Files {
+			names = append(names, f.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	// Sharing "bad" or "ing" with the query is not enough
+	if got := search("breakingbad fuzzy:on"); !slices.Equal(got, []string{"Breaking.Bad.S01E01.mkv"}) {
+		t.Errorf("fuzzy = %v", got)
+	}
+	if got := search("size:0"); !slices.Equal(got, []string{"empty.txt"}) {
+		t.Errorf("size:0 = %v", got)
+	}
+}
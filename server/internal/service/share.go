@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+	"gravity/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/rclone/rclone/fs"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// DefaultShareExpiry is the lifetime of a share link created without one
+const DefaultShareExpiry = "7d"
+
+// Expired links are kept this long so they can still be seen, then deleted
+const shareLinkRetention = 30 * 24 * time.Hour
+
+// How long after its last request a client's download of a shared file may
+// be continued with range requests without counting again
+const shareDownloadIdle = time.Hour
+
+// ShareService manages links that give people without an API key access to
+// a single file of the VFS
+type ShareService struct {
+	repo   *store.ShareLinkRepo
+	engine engine.UploadEngine
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	downloads map[shareDownloadKey]*shareDownload
+}
+
+// ShareRequest describes a request for a shared file
+type ShareRequest struct {
+	Client     string // Identifies the client, such as its address and user agent
+	Download   bool   // The body is sent; HEAD requests are never counted
+	Ranged     bool
+	RangeStart int64 // First byte asked for if Ranged, -1 for a suffix range
+}
+
+// shareDownload is a counted download of a link by one client, which range
+// requests may continue until the whole file has been served once
+type shareDownload struct {
+	served   int64
+	lastSeen time.Time
+}
+
+type shareDownloadKey struct {
+	linkID string
+	client string
+}
+
+type ShareOptions struct {
+	Path         string
+	ExpiresIn    string // Duration such as "24h" or "7d"
+	Password     string
+	MaxDownloads int
+	Native       bool // Use a link made by the backend instead of /s/{token}
+}
+
+func NewShareService(repo *store.ShareLinkRepo, eng engine.UploadEngine) *ShareService {
+	return &ShareService{
+		repo:   repo,
+		engine: eng,
+		logger: logger.Component("SHARE"),
+
+		downloads: make(map[shareDownloadKey]*shareDownload),
+	}
+}
+
+func (s *ShareService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if n, err := s.repo.DeleteExpired(ctx, now.Add(-shareLinkRetention)); err != nil {
+					s.logger.Error("failed to delete expired share links", zap.Error(err))
+				} else if n > 0 {
+					s.logger.Debug("deleted expired share links", zap.Int64("count", n))
+				}
+				s.expireDownloads(now)
+			}
+		}
+	}()
+}
+
+func (s *ShareService) List(ctx context.Context) ([]*model.ShareLink, error) {
+	links, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range links {
+		setShareURL(l)
+	}
+	return links, nil
+}
+
+func (s *ShareService) Create(ctx context.Context, opts ShareOptions) (*model.ShareLink, error) {
+	if opts.ExpiresIn == "" {
+		opts.ExpiresIn = DefaultShareExpiry
+	}
+	expiresIn, err := fs.ParseDuration(opts.ExpiresIn)
+	if err != nil || expiresIn <= 0 {
+		return nil, apperrors.New(apperrors.CodeValidationFailed, "invalid expiresIn: "+opts.ExpiresIn)
+	}
+	if opts.MaxDownloads < 0 {
+		return nil, apperrors.New(apperrors.CodeValidationFailed, "maxDownloads must not be negative")
+	}
+	// Backend links are served by the backend, which checks neither
+	if opts.Native && (opts.Password != "" || opts.MaxDownloads > 0) {
+		return nil, apperrors.New(apperrors.CodeValidationFailed, "native links cannot have a password or a download limit")
+	}
+
+	info, err := s.engine.Stat(ctx, opts.Path)
+	if err != nil {
+		return nil, apperrors.NewNotFound("file", opts.Path)
+	}
+	if info.IsDir {
+		return nil, apperrors.New(apperrors.CodeValidationFailed, "only files can be shared")
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	l := &model.ShareLink{
+		ID:           "sh_" + uuid.New().String()[:8],
+		Token:        token,
+		Path:         opts.Path,
+		ExpiresAt:    now.Add(expiresIn),
+		MaxDownloads: opts.MaxDownloads,
+		CreatedAt:    now,
+	}
+
+	if opts.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, apperrors.New(apperrors.CodeValidationFailed, "invalid password: "+err.Error())
+		}
+		l.PasswordHash = string(hash)
+		l.HasPassword = true
+	}
+
+	if opts.Native {
+		url, err := s.engine.PublicLink(ctx, opts.Path, expiresIn, false)
+		if err != nil {
+			return nil, apperrors.New(apperrors.CodeValidationFailed, "backend link failed: "+err.Error())
+		}
+		l.NativeURL = url
+	}
+
+	if err := s.repo.Create(ctx, l); err != nil {
+		return nil, err
+	}
+	s.logger.Info("share link created", zap.String("id", l.ID), zap.String("path", l.Path), zap.Time("expires", l.ExpiresAt))
+	setShareURL(l)
+	return l, nil
+}
+
+// Revoke deletes link id, removing its backend link too where there is one
+func (s *ShareService) Revoke(ctx context.Context, id string) error {
+	l, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.NewNotFound("share link", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if l.NativeURL != "" {
+		if _, err := s.engine.PublicLink(ctx, l.Path, 0, true); err != nil {
+			s.logger.Warn("failed to remove backend link", zap.String("id", id), zap.Error(err))
+		}
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Open checks that token may be used with password and returns its link.
+// A download counts against the limit when it asks for the whole file, or
+// for a range from the start that does not continue a download of the
+// client. Other range requests are refused unless they continue one, so a
+// file cannot be fetched in pieces without counting. Downloads of a file that
+// no longer exists are refused before they count.
+func (s *ShareService) Open(ctx context.Context, token, password string, req ShareRequest) (*model.ShareLink, error) {
+	l, err := s.repo.GetByToken(ctx, token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.New(apperrors.CodeNotFound, "share link not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if l.Expired(now) {
+		return nil, apperrors.New(apperrors.CodeGone, "share link has expired")
+	}
+	if l.Exhausted() {
+		return nil, apperrors.New(apperrors.CodeGone, "share link download limit reached")
+	}
+	if l.HasPassword {
+		if password == "" {
+			return nil, apperrors.New(apperrors.CodeUnauthorized, "password required")
+		}
+		if bcrypt.CompareHashAndPassword([]byte(l.PasswordHash), []byte(password)) != nil {
+			return nil, apperrors.New(apperrors.CodeUnauthorized, "wrong password")
+		}
+	}
+
+	if !req.Download || l.NativeURL != "" {
+		return l, nil
+	}
+
+	key := shareDownloadKey{linkID: l.ID, client: req.Client}
+	if req.Ranged && s.continues(key, now) {
+		return l, nil
+	}
+	if req.Ranged && req.RangeStart != 0 {
+		if l.MaxDownloads > 0 {
+			return nil, apperrors.New(apperrors.CodeInvalidOperation, "range does not continue a download; request the file from the start")
+		}
+		return l, nil
+	}
+
+	// A file that is gone must not use up a download
+	if _, err := s.engine.Stat(ctx, l.Path); err != nil {
+		return nil, apperrors.New(apperrors.CodeNotFound, "shared file no longer exists")
+	}
+	ok, err := s.repo.CountDownload(ctx, l.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, apperrors.New(apperrors.CodeGone, "share link download limit reached")
+	}
+	s.mu.Lock()
+	s.downloads[key] = &shareDownload{lastSeen: now}
+	s.mu.Unlock()
+	return l, nil
+}
+
+// Served records n bytes of a file of size bytes sent to client for link
+// id. Once a whole file's worth has been sent, the download is complete and
+// range requests have to start a new one.
+func (s *ShareService) Served(id, client string, n, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := shareDownloadKey{linkID: id, client: client}
+	d, ok := s.downloads[key]
+	if !ok {
+		return
+	}
+	d.served += n
+	d.lastSeen = time.Now()
+	if d.served >= size {
+		delete(s.downloads, key)
+	}
+}
+
+// continues reports whether key has a download in progress, keeping it alive
+func (s *ShareService) continues(key shareDownloadKey, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.downloads[key]
+	if !ok || now.Sub(d.lastSeen) > shareDownloadIdle {
+		return false
+	}
+	d.lastSeen = now
+	return true
+}
+
+func (s *ShareService) expireDownloads(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, d := range s.downloads {
+		if now.Sub(d.lastSeen) > shareDownloadIdle {
+			delete(s.downloads, key)
+		}
+	}
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func setShareURL(l *model.ShareLink) {
+	l.URL = "/s/" + l.Token
+}
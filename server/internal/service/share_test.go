@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gravity/internal/engine"
+	"gravity/internal/model"
+	"gravity/internal/store"
+)
+
+// storageEngine is an UploadEngine over a memStorage
+type storageEngine struct {
+	engine.UploadEngine
+	storage *memStorage
+}
+
+func (e storageEngine) Stat(ctx context.Context, virtualPath string) (*engine.FileInfo, error) {
+	return e.storage.Stat(ctx, virtualPath)
+}
+
+func TestShareCreateNative(t *testing.T) {
+	s := NewShareService(nil, nil)
+	for _, opts := range []ShareOptions{
+		{Path: "/gdrive/a.mkv", Native: true, Password: "secret"},
+		{Path: "/gdrive/a.mkv", Native: true, MaxDownloads: 3},
+	} {
+		if _, err := s.Create(context.Background(), opts); err == nil {
+			t.Errorf("Create(%+v) accepted a native link it cannot enforce", opts)
+		}
+	}
+}
+
+func TestShareDownloadCounting(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewShareLinkRepo(newTestStore(t).GetDB())
+	storage := newMemStorage()
+	storage.files["/gdrive/a.mkv"] = []byte("video")
+	s := NewShareService(repo, storageEngine{storage: storage})
+	link := &model.ShareLink{ID: "sh_1", Token: "tok", Path: "/gdrive/a.mkv", ExpiresAt: time.Now().Add(time.Hour), MaxDownloads: 2}
+	if err := repo.Create(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	const size = 100
+
+	downloads := func() int {
+		l, _ := repo.Get(ctx, link.ID)
+		return l.Downloads
+	}
+	open := func(client string, ranged bool, start int64) error {
+		req := ShareRequest{Client: client, Download: true, Ranged: ranged, RangeStart: start}
+		_, err := s.Open(ctx, "tok", "", req)
+		return err
+	}
+
+	if _, err := s.Open(ctx, "tok", "", ShareRequest{Client: "a"}); err != nil || downloads() != 0 {
+		t.Fatalf("HEAD: %v, %d downloads", err, downloads())
+	}
+	if err := open("a", true, 1); err == nil {
+		t.Fatal("range outside a download was served")
+	}
+
+	// The first byte counts; the rest continues that download
+	if err := open("a", true, 0); err != nil || downloads() != 1 {
+		t.Fatalf("first range: %v, %d downloads", err, downloads())
+	}
+	s.Served(link.ID, "a", 1, size)
+	if err := open("a", true, 1); err != nil || downloads() != 1 {
+		t.Fatalf("continued range: %v, %d downloads", err, downloads())
+	}
+	if err := open("b", true, 1); err == nil {
+		t.Error("another client continued the download")
+	}
+	s.Served(link.ID, "a", size-1, size)
+
+	// Once the whole file is sent, ranges have to start a new download
+	if err := open("a", true, 50); err == nil {
+		t.Error("range after a complete download was served")
+	}
+	if err := open("a", false, -1); err != nil || downloads() != 2 {
+		t.Fatalf("full download: %v, %d downloads", err, downloads())
+	}
+	if err := open("c", false, -1); err == nil {
+		t.Error("download past the limit was served")
+	}
+}
+
+func TestShareMissingFileNotCounted(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewShareLinkRepo(newTestStore(t).GetDB())
+	storage := newMemStorage()
+	s := NewShareService(repo, storageEngine{storage: storage})
+	link := &model.ShareLink{ID: "sh_1", Token: "tok", Path: "/gdrive/a.mkv", ExpiresAt: time.Now().Add(time.Hour), MaxDownloads: 1}
+	if err := repo.Create(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+
+	req := ShareRequest{Client: "a", Download: true}
+	if _, err := s.Open(ctx, "tok", "", req); err == nil {
+		t.Fatal("download of a missing file was served")
+	}
+	if l, _ := repo.Get(ctx, link.ID); l.Downloads != 0 {
+		t.Fatalf("missing file counted %d downloads", l.Downloads)
+	}
+
+	// Once the file is back, the link still has its download
+	storage.files["/gdrive/a.mkv"] = []byte("video")
+	if _, err := s.Open(ctx, "tok", "", req); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+	"gravity/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SiteProfileService manages per-site credentials and applies them to
+// outgoing requests. Profiles are cached in memory since they are consulted
+// for every resolve and download.
+type SiteProfileService struct {
+	repo   *store.SiteProfileRepo
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	profiles []*model.SiteProfile
+}
+
+func NewSiteProfileService(repo *store.SiteProfileRepo) *SiteProfileService {
+	return &SiteProfileService{
+		repo:   repo,
+		logger: logger.Component("PROFILES"),
+	}
+}
+
+// Load fills the cache from the database
+func (s *SiteProfileService) Load(ctx context.Context) error {
+	profiles, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.profiles = profiles
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *SiteProfileService) List(ctx context.Context) ([]*model.SiteProfile, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *SiteProfileService) Get(ctx context.Context, id string) (*model.SiteProfile, error) {
+	p, err := s.repo.Get(ctx, id)
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.NewNotFound("site profile", id)
+	}
+	return p, err
+}
+
+func (s *SiteProfileService) Create(ctx context.Context, p *model.SiteProfile) (*model.SiteProfile, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	p.ID = "sp_" + uuid.New().String()[:8]
+	if p.AuthType == "" {
+		p.AuthType = model.AuthTypeNone
+	}
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+
+	if err := s.repo.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, s.Load(ctx)
+}
+
+// Update replaces a profile. Secrets left empty or masked keep their stored
+// values, so a client can round-trip a redacted profile.
+func (s *SiteProfileService) Update(ctx context.Context, id string, p *model.SiteProfile) (*model.SiteProfile, error) {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	p.ID = existing.ID
+	p.CreatedAt = existing.CreatedAt
+	p.UpdatedAt = time.Now()
+	if p.AuthType == "" {
+		p.AuthType = model.AuthTypeNone
+	}
+	p.MergeSecrets(existing.Secrets())
+
+	if err := s.repo.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, s.Load(ctx)
+}
+
+func (s *SiteProfileService) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.Load(ctx)
+}
+
+// ImportCookies merges a Netscape cookies.txt export into the profile.
+// Cookies with the same domain, path and name are replaced.
+func (s *SiteProfileService) ImportCookies(ctx context.Context, id, cookiesTxt string) (*model.SiteProfile, error) {
+	p, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cookies, err := model.ParseNetscapeCookies(strings.NewReader(cookiesTxt))
+	if err != nil {
+		return nil, apperrors.New(apperrors.CodeValidationFailed, "invalid cookies file: "+err.Error())
+	}
+	if len(cookies) == 0 {
+		return nil, apperrors.New(apperrors.CodeValidationFailed, "cookies file contains no cookies")
+	}
+
+	key := func(c model.Cookie) string { return c.Domain + "\t" + c.Path + "\t" + c.Name }
+	index := make(map[string]int, len(p.Cookies))
+	for i, c := range p.Cookies {
+		index[key(c)] = i
+	}
+	for _, c := range cookies {
+		if i, ok := index[key(c)]; ok {
+			p.Cookies[i] = c
+			continue
+		}
+		index[key(c)] = len(p.Cookies)
+		p.Cookies = append(p.Cookies, c)
+	}
+
+	p.UpdatedAt = time.Now()
+	if err := s.repo.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	s.logger.Info("imported cookies", zap.String("profile", p.ID), zap.Int("count", len(cookies)))
+	return p, s.Load(ctx)
+}
+
+func (s *SiteProfileService) ClearCookies(ctx context.Context, id string) (*model.SiteProfile, error) {
+	p, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p.Cookies = nil
+	p.UpdatedAt = time.Now()
+	if err := s.repo.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, s.Load(ctx)
+}
+
+// Match returns the first enabled profile for rawURL's host, or nil
+func (s *SiteProfileService) Match(rawURL string) (*model.SiteProfile, *url.URL) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.profiles {
+		if p.Enabled && p.Matches(u.Hostname()) {
+			return p, u
+		}
+	}
+	return nil, u
+}
+
+// StoreURLCredentials moves the user and password of rawURL into the site
+// profile for its host, where they are sealed, and returns the URL without
+// them. URLs without a password are returned unchanged.
+func (s *SiteProfileService) StoreURLCredentials(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL, nil
+	}
+	pass, ok := u.User.Password()
+	if !ok {
+		return rawURL, nil
+	}
+	user := u.User.Username()
+	host := strings.ToLower(u.Hostname())
+
+	var id string
+	s.mu.RLock()
+	for _, p := range s.profiles {
+		if slices.ContainsFunc(p.Hosts, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), host) }) {
+			id = p.ID
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	var p *model.SiteProfile
+	if id != "" {
+		if p, err = s.repo.Get(ctx, id); err != nil {
+			return "", err
+		}
+	} else {
+		p = &model.SiteProfile{
+			ID:        "sp_" + uuid.New().String()[:8],
+			Name:      u.Scheme + "://" + host,
+			Hosts:     []string{host},
+			CreatedAt: time.Now(),
+		}
+	}
+	p.Enabled = true
+	p.AuthType = model.AuthTypeBasic
+	p.Username = user
+	p.Password = pass
+	p.UpdatedAt = time.Now()
+
+	if err := s.repo.Save(ctx, p); err != nil {
+		return "", err
+	}
+	s.logger.Info("stored URL credentials", zap.String("profile", p.ID), zap.String("host", host))
+
+	u.User = nil
+	return u.String(), s.Load(ctx)
+}
+
+// SourceLogin returns the basic auth login of the profile matching an
+// sftp/ftp source
+func (s *SiteProfileService) SourceLogin(rawURL string) (string, string, bool) {
+	p, _ := s.Match(rawURL)
+	if p == nil || p.AuthType != model.AuthTypeBasic || p.Password == "" {
+		return "", "", false
+	}
+	return p.Username, p.Password, true
+}
+
+// HeadersFor returns the auth, cookie and custom headers for rawURL
+func (s *SiteProfileService) HeadersFor(rawURL string) map[string]string {
+	p, u := s.Match(rawURL)
+	if p == nil {
+		return nil
+	}
+	return p.RequestHeaders(u)
+}
+
+// WriteCookieFile writes the profile cookies for rawURL to a private temp
+// file for tools such as yt-dlp
+func (s *SiteProfileService) WriteCookieFile(rawURL string) (string, func(), error) {
+	noop := func() {}
+	p, _ := s.Match(rawURL)
+	if p == nil || len(p.Cookies) == 0 {
+		return "", noop, nil
+	}
+
+	f, err := os.CreateTemp("", "gravity-cookies-*.txt")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	if err := model.WriteNetscapeCookies(f, p.Cookies); err != nil {
+		f.Close()
+		cleanup()
+		return "", noop, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return f.Name(), cleanup, nil
+}
+
+// ApplyOptions adds the matching profile to engine options. Explicit
+// per-download headers, referer and user agent win over the profile; the
+// profile's connection cap only ever lowers the configured values.
+func (s *SiteProfileService) ApplyOptions(rawURL string, opts *engine.DownloadOptions) {
+	p, u := s.Match(rawURL)
+	if p == nil {
+		return
+	}
+
+	headers := p.RequestHeaders(u)
+	if p.UserAgent != "" {
+		delete(headers, "User-Agent")
+		if opts.UserAgent == nil {
+			ua := p.UserAgent
+			opts.UserAgent = &ua
+		}
+	}
+	if p.Referer != "" {
+		delete(headers, "Referer")
+		if opts.Referer == nil {
+			ref := p.Referer
+			opts.Referer = &ref
+		}
+	}
+
+	merged := make(map[string]string, len(headers)+len(opts.Headers))
+	maps.Copy(merged, headers)
+	maps.Copy(merged, opts.Headers)
+	opts.Headers = merged
+
+	if p.MaxConnections > 0 {
+		opts.MaxConnectionPerServer = capInt(opts.MaxConnectionPerServer, p.MaxConnections)
+		opts.Split = capInt(opts.Split, p.MaxConnections)
+	}
+}
+
+func capInt(v *int, limit int) *int {
+	if v != nil && *v > 0 && *v <= limit {
+		return v
+	}
+	return &limit
+}
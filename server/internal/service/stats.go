@@ -108,6 +108,7 @@ type StatsService struct {
 	downloadRepo   *store.DownloadRepo
 	downloadEngine engine.DownloadEngine
 	uploadEngine   engine.UploadEngine
+	quota          *QuotaService
 	bus            *event.Bus
 	ctx            context.Context
 	logger         *zap.Logger
@@ -134,13 +135,14 @@ type StatsService struct {
 	trigger       chan struct{}
 }
 
-func NewStatsService(repo *store.StatsRepo, setr *store.SettingsRepo, dr *store.DownloadRepo, de engine.DownloadEngine, ue engine.UploadEngine, bus *event.Bus) *StatsService {
+func NewStatsService(repo *store.StatsRepo, setr *store.SettingsRepo, dr *store.DownloadRepo, de engine.DownloadEngine, ue engine.UploadEngine, bus *event.Bus, quota *QuotaService) *StatsService {
 	s := &StatsService{
 		repo:           repo,
 		settingsRepo:   setr,
 		downloadRepo:   dr,
 		downloadEngine: de,
 		uploadEngine:   ue,
+		quota:          quota,
 		bus:            bus,
 		logger:         logger.Component("STATS"),
 		pollingPaused:  true,
@@ -438,7 +440,8 @@ func (s *StatsService) GetCurrent(ctx context.Context) (*model.Stats, error) {
 	settings, _ := s.settingsRepo.Get(ctx)
 	downloadDir := ""
 	if settings != nil {
-		downloadDir = settings.Download.DownloadDir
+		// Templated dirs such as "/data/{category}" report their root
+		downloadDir = engine.TemplateRoot(settings.Download.DownloadDir)
 	}
 	if downloadDir == "" {
 		home, _ := os.UserHomeDir()
@@ -447,6 +450,11 @@ func (s *StatsService) GetCurrent(ctx context.Context) (*model.Stats, error) {
 
 	disk := s.getDiskStats(downloadDir)
 
+	remotes := []model.RemoteUsage{}
+	if s.quota != nil {
+		remotes = s.quota.All()
+	}
+
 	return &model.Stats{
 		Speeds: model.Speeds{
 			Download: downloadSpeed,
@@ -472,6 +480,7 @@ func (s *StatsService) GetCurrent(ctx context.Context) (*model.Stats, error) {
 			DiskUsage: disk.Usage,
 			Uptime:    int64(time.Since(s.startTime).Seconds()),
 		},
+		Remotes: remotes,
 	}, nil
 }
 
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/event"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+	"gravity/internal/store"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// How often sync jobs are checked for a due run
+const syncSchedulerInterval = 30 * time.Second
+
+// SyncService runs named copy, move and sync jobs between local folders and
+// remotes on a cron schedule or interval, and keeps a history of their runs
+type SyncService struct {
+	repo   *store.SyncJobRepo
+	engine engine.UploadEngine
+	bus    *event.Bus
+	ctx    context.Context
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	running map[string]string // Run ID by job ID
+}
+
+func NewSyncService(repo *store.SyncJobRepo, eng engine.UploadEngine, bus *event.Bus) *SyncService {
+	return &SyncService{
+		repo:    repo,
+		engine:  eng,
+		bus:     bus,
+		logger:  logger.Component("SYNC"),
+		running: make(map[string]string),
+	}
+}
+
+func (s *SyncService) Start(ctx context.Context) {
+	s.ctx = ctx
+
+	if n, err := s.repo.AbortRunning(ctx); err != nil {
+		s.logger.Error("failed to close interrupted sync runs", zap.Error(err))
+	} else if n > 0 {
+		s.logger.Info("closed sync runs interrupted by restart", zap.Int64("count", n))
+	}
+
+	go func() {
+		ticker := time.NewTicker(syncSchedulerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.runDue(now)
+			}
+		}
+	}()
+}
+
+func (s *SyncService) List(ctx context.Context) ([]*model.SyncJob, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *SyncService) Get(ctx context.Context, id string) (*model.SyncJob, error) {
+	j, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.NewNotFound("sync job", id)
+	}
+	return j, err
+}
+
+func (s *SyncService) Create(ctx context.Context, j *model.SyncJob) (*model.SyncJob, error) {
+	if err := j.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkName(ctx, j.Name, ""); err != nil {
+		return nil, err
+	}
+	j.ID = "sj_" + uuid.New().String()[:8]
+	j.LastRunAt = nil
+	j.LastStatus = ""
+	j.NextRunAt = j.NextRun(time.Now())
+
+	if err := s.repo.Create(ctx, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Update replaces the definition of a job, keeping its run state
+func (s *SyncService) Update(ctx context.Context, id string, j *model.SyncJob) (*model.SyncJob, error) {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkName(ctx, j.Name, id); err != nil {
+		return nil, err
+	}
+
+	j.ID = existing.ID
+	j.CreatedAt = existing.CreatedAt
+	j.LastRunAt = existing.LastRunAt
+	j.LastStatus = existing.LastStatus
+	j.NextRunAt = j.NextRun(time.Now())
+
+	if err := s.repo.Update(ctx, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Delete removes a job and its history, stopping a run in progress
+func (s *SyncService) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	s.Cancel(ctx, id)
+	return s.repo.Delete(ctx, id)
+}
+
+// Run starts job id now. dryRun forces a dry run even if the job is not
+// configured as one.
+func (s *SyncService) Run(ctx context.Context, id string, dryRun bool) (*model.SyncRun, error) {
+	j, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.start(j, "manual", dryRun)
+}
+
+// Cancel stops the running run of job id, if any
+func (s *SyncService) Cancel(ctx context.Context, id string) error {
+	s.mu.Lock()
+	runID, ok := s.running[id]
+	s.mu.Unlock()
+	if !ok {
+		return apperrors.New(apperrors.CodeInvalidOperation, "sync job is not running")
+	}
+	return s.engine.Cancel(ctx, runID)
+}
+
+// Runs returns the run history of job id, newest first
+func (s *SyncService) Runs(ctx context.Context, id string, limit, offset int) ([]*model.SyncRun, int, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListRuns(ctx, id, limit, offset)
+}
+
+func (s *SyncService) runDue(now time.Time) {
+	ctx := s.context()
+	jobs, err := s.repo.Due(ctx, now)
+	if err != nil {
+		s.logger.Error("failed to list due sync jobs", zap.Error(err))
+		return
+	}
+	for _, j := range jobs {
+		// Still busy with the previous run; this one starts once it ends
+		s.mu.Lock()
+		_, busy := s.running[j.ID]
+		s.mu.Unlock()
+		if busy {
+			continue
+		}
+		if _, err := s.start(j, "schedule", false); err != nil {
+			s.logger.Warn("skipping scheduled sync", zap.String("job", j.Name), zap.Error(err))
+		}
+	}
+}
+
+// start records a new run of j and executes it in the background. A job
+// runs at most once at a time.
+func (s *SyncService) start(j *model.SyncJob, trigger string, dryRun bool) (*model.SyncRun, error) {
+	ctx := s.context()
+	run := &model.SyncRun{
+		ID:        "sr_" + uuid.New().String()[:8],
+		JobID:     j.ID,
+		Status:    model.SyncRunRunning,
+		Trigger:   trigger,
+		DryRun:    j.DryRun || dryRun,
+		StartedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	if _, busy := s.running[j.ID]; busy {
+		s.mu.Unlock()
+		return nil, apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("sync job %s is already running", j.Name))
+	}
+	s.running[j.ID] = run.ID
+	s.mu.Unlock()
+
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		s.release(j.ID)
+		return nil, err
+	}
+
+	j.LastRunAt = &run.StartedAt
+	j.LastStatus = model.SyncRunRunning
+	j.NextRunAt = j.NextRun(run.StartedAt)
+	if err := s.repo.Update(ctx, j); err != nil {
+		s.logger.Error("failed to save sync job", zap.String("job", j.ID), zap.Error(err))
+	}
+
+	s.logger.Info("sync started",
+		zap.String("job", j.Name),
+		zap.String("mode", string(j.Mode)),
+		zap.String("trigger", trigger),
+		zap.Bool("dry_run", run.DryRun))
+	s.bus.PublishLifecycle(event.LifecycleEvent{
+		Type:      event.SyncStarted,
+		ID:        j.ID,
+		Timestamp: run.StartedAt,
+		Data:      run,
+	})
+
+	go s.execute(j, run)
+	return run, nil
+}
+
+func (s *SyncService) execute(j *model.SyncJob, run *model.SyncRun) {
+	defer s.release(j.ID)
+	ctx := s.context()
+
+	stats, err := s.engine.Sync(ctx, engine.SyncOptions{
+		TrackingID: run.ID,
+		Mode:       string(j.Mode),
+		Src:        j.Source,
+		Dst:        j.Destination,
+		DryRun:     run.DryRun,
+		Include:    j.Filters.Include,
+		Exclude:    j.Filters.Exclude,
+		MinSize:    j.Filters.MinSize,
+		MaxSize:    j.Filters.MaxSize,
+		MinAge:     j.Filters.MinAge,
+		MaxAge:     j.Filters.MaxAge,
+		OnProgress: func(p engine.UploadProgress) {
+			s.bus.PublishProgress(event.ProgressEvent{
+				ID:       j.ID,
+				Type:     "sync",
+				Uploaded: p.Uploaded,
+				Size:     p.Size,
+				Speed:    p.Speed,
+				ETA:      event.ETAUnknown,
+			})
+		},
+	})
+
+	now := time.Now()
+	run.CompletedAt = &now
+	if stats != nil {
+		run.Bytes = stats.Bytes
+		run.Transfers = stats.Transfers
+		run.Checks = stats.Checks
+		run.Deletes = stats.Deletes
+		run.Errors = stats.Errors
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		run.Status = model.SyncRunCancelled
+		run.Error = "cancelled"
+	case err != nil:
+		run.Status = model.SyncRunError
+		run.Error = err.Error()
+	default:
+		run.Status = model.SyncRunComplete
+	}
+	if err := s.repo.UpdateRun(ctx, run); err != nil {
+		s.logger.Error("failed to save sync run", zap.String("run", run.ID), zap.Error(err))
+	}
+
+	// The job may have been edited or deleted while it ran
+	if current, err := s.repo.Get(ctx, j.ID); err == nil {
+		current.LastStatus = run.Status
+		if err := s.repo.Update(ctx, current); err != nil {
+			s.logger.Error("failed to save sync job", zap.String("job", j.ID), zap.Error(err))
+		}
+	}
+
+	evType := event.SyncCompleted
+	if run.Status != model.SyncRunComplete {
+		evType = event.SyncError
+		s.logger.Warn("sync failed", zap.String("job", j.Name), zap.String("error", run.Error))
+	} else {
+		s.logger.Info("sync complete",
+			zap.String("job", j.Name),
+			zap.Int64("bytes", run.Bytes),
+			zap.Int64("transfers", run.Transfers),
+			zap.Int64("deletes", run.Deletes))
+	}
+	s.bus.PublishLifecycle(event.LifecycleEvent{
+		Type:      evType,
+		ID:        j.ID,
+		Timestamp: now,
+		Error:     run.Error,
+		Data:      run,
+	})
+}
+
+func (s *SyncService) release(jobID string) {
+	s.mu.Lock()
+	delete(s.running, jobID)
+	s.mu.Unlock()
+}
+
+func (s *SyncService) checkName(ctx context.Context, name, id string) error {
+	existing, err := s.repo.GetByName(ctx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != id {
+		return apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("a sync job named %q already exists", name))
+	}
+	return nil
+}
+
+func (s *SyncService) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
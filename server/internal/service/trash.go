@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
+	"gravity/internal/logger"
+	"gravity/internal/model"
+	"gravity/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/rclone/rclone/fs"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TrashDirName is the folder deleted items are moved to, at the top of each
+// remote and of the download dir
+const TrashDirName = ".gravity-trash"
+
+// TrashService moves deleted files into a trash folder on the same remote or
+// disk, so they can be restored, and purges them by age and total size.
+// Each item gets its own folder named after its ID inside the trash.
+type TrashService struct {
+	repo         *store.TrashRepo
+	storage      engine.StorageEngine
+	settingsRepo *store.SettingsRepo
+	logger       *zap.Logger
+}
+
+func NewTrashService(repo *store.TrashRepo, storage engine.StorageEngine, settingsRepo *store.SettingsRepo) *TrashService {
+	return &TrashService{
+		repo:         repo,
+		storage:      storage,
+		settingsRepo: settingsRepo,
+		logger:       logger.Component("TRASH"),
+	}
+}
+
+func (s *TrashService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.autoPurge(ctx, now)
+			}
+		}
+	}()
+}
+
+// Delete moves virtualPath to the trash of its remote, or deletes it when
+// the trash is off or bypassed for the remote. Items already in a trash are
+// deleted for good.
+func (s *TrashService) Delete(ctx context.Context, virtualPath string) error {
+	remote, rest := splitTop(virtualPath)
+	if rest == "" {
+		return apperrors.New(apperrors.CodeValidationFailed, "cannot delete the root of a remote")
+	}
+	if inTrash(virtualPath) {
+		return s.storage.Delete(ctx, virtualPath)
+	}
+
+	settings := s.settings(ctx)
+	if settings.Bypass(remote) {
+		return s.storage.Delete(ctx, virtualPath)
+	}
+
+	info, err := s.storage.Stat(ctx, virtualPath)
+	if err != nil {
+		return apperrors.NewNotFound("file", virtualPath)
+	}
+
+	item := &model.TrashItem{
+		ID:           "tr_" + uuid.New().String()[:8],
+		Name:         info.Name,
+		OriginalPath: virtualPath,
+		Remote:       remote,
+		Size:         info.Size,
+		IsDir:        info.IsDir,
+		TrashedAt:    time.Now(),
+	}
+	item.TrashPath = path.Join("/", remote, TrashDirName, item.ID, info.Name)
+	if info.IsDir {
+		item.Size = s.treeSize(ctx, virtualPath)
+	}
+
+	if err := s.storage.MovePath(ctx, virtualPath, item.TrashPath); err != nil {
+		return fmt.Errorf("failed to move to trash (add %s to the trash bypass list to delete permanently): %w", remote, err)
+	}
+	if err := s.repo.Create(ctx, item); err != nil {
+		return err
+	}
+	s.logger.Info("moved to trash", zap.String("path", virtualPath), zap.String("id", item.ID))
+	return nil
+}
+
+// DeleteLocal moves the file or folder of a deleted download to the trash
+// in the download dir, or next to it if that is on another disk. Missing
+// paths are ignored.
+func (s *TrashService) DeleteLocal(ctx context.Context, localPath, downloadID string) error {
+	fi, err := os.Lstat(localPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	all := s.allSettings(ctx)
+	if all.Trash.Bypass(model.LocalRootDownloads) {
+		return os.RemoveAll(localPath)
+	}
+
+	item := &model.TrashItem{
+		ID:           "tr_" + uuid.New().String()[:8],
+		Name:         fi.Name(),
+		OriginalPath: localPath,
+		Local:        true,
+		Remote:       model.LocalRootDownloads,
+		Size:         fi.Size(),
+		IsDir:        fi.IsDir(),
+		DownloadID:   downloadID,
+		TrashedAt:    time.Now(),
+	}
+	if fi.IsDir() {
+		item.Size = localTreeSize(localPath)
+	}
+
+	var candidates []string
+	if root := engine.TemplateRoot(all.Download.DownloadDir); root != "" {
+		candidates = append(candidates, filepath.Join(root, TrashDirName, item.ID))
+	}
+	candidates = append(candidates, filepath.Join(filepath.Dir(localPath), TrashDirName, item.ID))
+
+	for _, dir := range candidates {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			continue
+		}
+		target := filepath.Join(dir, item.Name)
+		// A rename never copies, so a trash on another disk fails here
+		if err = os.Rename(localPath, target); err == nil {
+			item.TrashPath = target
+			break
+		}
+		os.Remove(dir)
+	}
+	if item.TrashPath == "" {
+		return fmt.Errorf("failed to move %s to trash: %w", localPath, err)
+	}
+
+	if err := s.repo.Create(ctx, item); err != nil {
+		return err
+	}
+	s.logger.Info("moved to trash", zap.String("path", localPath), zap.String("id", item.ID))
+	return nil
+}
+
+func (s *TrashService) List(ctx context.Context, limit, offset int) ([]*model.TrashItem, int, error) {
+	return s.repo.List(ctx, limit, offset)
+}
+
+// Restore moves item id back to where it was deleted from
+func (s *TrashService) Restore(ctx context.Context, id string) (*model.TrashItem, error) {
+	item, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Local {
+		if _, err := os.Lstat(item.OriginalPath); err == nil {
+			return nil, apperrors.New(apperrors.CodeInvalidOperation, item.OriginalPath+" already exists")
+		}
+		if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(item.TrashPath, item.OriginalPath); err != nil {
+			return nil, err
+		}
+		os.Remove(filepath.Dir(item.TrashPath))
+	} else {
+		if _, err := s.storage.Stat(ctx, item.OriginalPath); err == nil {
+			return nil, apperrors.New(apperrors.CodeInvalidOperation, item.OriginalPath+" already exists")
+		}
+		if err := s.storage.MovePath(ctx, item.TrashPath, item.OriginalPath); err != nil {
+			return nil, err
+		}
+		if err := s.storage.Delete(ctx, path.Dir(item.TrashPath)); err != nil {
+			s.logger.Debug("failed to remove trash folder", zap.String("id", id), zap.Error(err))
+		}
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	s.logger.Info("restored from trash", zap.String("path", item.OriginalPath), zap.String("id", id))
+	return item, nil
+}
+
+// Purge deletes item id for good
+func (s *TrashService) Purge(ctx context.Context, id string) error {
+	item, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.purge(ctx, item)
+}
+
+// Empty purges every item and returns how many were purged
+func (s *TrashService) Empty(ctx context.Context) (int, error) {
+	items, err := s.repo.Oldest(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, item := range items {
+		if err := s.purge(ctx, item); err != nil {
+			return i, err
+		}
+	}
+	return len(items), nil
+}
+
+// purge deletes the folder holding item. Data that is already gone, for
+// example removed by hand, only drops the record.
+func (s *TrashService) purge(ctx context.Context, item *model.TrashItem) error {
+	if item.Local {
+		if err := os.RemoveAll(filepath.Dir(item.TrashPath)); err != nil {
+			return err
+		}
+	} else {
+		dir := path.Dir(item.TrashPath)
+		if _, err := s.storage.Stat(ctx, dir); err == nil {
+			if err := s.removeTree(ctx, dir); err != nil {
+				return err
+			}
+		}
+	}
+	return s.repo.Delete(ctx, item.ID)
+}
+
+// autoPurge removes items older than the configured age, then the oldest
+// items until the trash fits the configured size
+func (s *TrashService) autoPurge(ctx context.Context, now time.Time) {
+	settings := s.settings(ctx)
+
+	if settings.MaxAgeDays > 0 {
+		items, err := s.repo.TrashedBefore(ctx, now.AddDate(0, 0, -settings.MaxAgeDays))
+		if err != nil {
+			s.logger.Error("failed to list expired trash", zap.Error(err))
+			return
+		}
+		for _, item := range items {
+			if err := s.purge(ctx, item); err != nil {
+				s.logger.Warn("failed to purge trash item", zap.String("id", item.ID), zap.Error(err))
+			}
+		}
+	}
+
+	if settings.MaxSize == "" || settings.MaxSize == "0" {
+		return
+	}
+	var maxSize fs.SizeSuffix
+	if err := maxSize.Set(settings.MaxSize); err != nil {
+		return
+	}
+	total, err := s.repo.TotalSize(ctx)
+	if err != nil || total <= int64(maxSize) {
+		return
+	}
+	items, err := s.repo.Oldest(ctx)
+	if err != nil {
+		return
+	}
+	for _, item := range items {
+		if total <= int64(maxSize) {
+			break
+		}
+		if err := s.purge(ctx, item); err != nil {
+			s.logger.Warn("failed to purge trash item", zap.String("id", item.ID), zap.Error(err))
+			continue
+		}
+		total -= item.Size
+	}
+}
+
+// removeTree deletes the trash folder of an item with its contents. The
+// public Delete of an engine refuses folders that are not empty.
+func (s *TrashService) removeTree(ctx context.Context, virtualPath string) error {
+	if r, ok := s.storage.(engine.TreeRemover); ok {
+		return r.RemoveAll(ctx, virtualPath)
+	}
+	return s.storage.Delete(ctx, virtualPath)
+}
+
+func (s *TrashService) get(ctx context.Context, id string) (*model.TrashItem, error) {
+	item, err := s.repo.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.NewNotFound("trash item", id)
+	}
+	return item, err
+}
+
+func (s *TrashService) allSettings(ctx context.Context) *model.Settings {
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil || settings == nil {
+		return model.DefaultSettings()
+	}
+	return settings
+}
+
+func (s *TrashService) settings(ctx context.Context) *model.TrashSettings {
+	return &s.allSettings(ctx).Trash
+}
+
+// treeSize adds up the sizes of the files below virtualPath
+func (s *TrashService) treeSize(ctx context.Context, virtualPath string) int64 {
+	entries, err := s.storage.List(ctx, virtualPath)
+	if err != nil {
+		return 0
+	}
+	var size int64
+	for _, e := range entries {
+		if e.IsDir {
+			size += s.treeSize(ctx, e.Path)
+		} else {
+			size += e.Size
+		}
+	}
+	return size
+}
+
+func localTreeSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// inTrash reports whether virtualPath is the trash of its remote or is in it
+func inTrash(virtualPath string) bool {
+	_, rest := splitTop(virtualPath)
+	return rest == TrashDirName || strings.HasPrefix(rest, TrashDirName+"/")
+}
+
+// splitTop splits a virtual path into its remote and the path on it
+func splitTop(virtualPath string) (string, string) {
+	remote, rest, _ := strings.Cut(strings.Trim(virtualPath, "/"), "/")
+	return remote, rest
+}
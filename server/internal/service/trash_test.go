@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gravity/internal/model"
+	"gravity/internal/store"
+)
+
+func newTestTrash(t *testing.T, configure func(*model.TrashSettings)) (*TrashService, *memStorage, *store.TrashRepo) {
+	t.Helper()
+	db := newTestStore(t).GetDB()
+	if configure != nil {
+		settingsRepo := store.NewSettingsRepo(db)
+		settings, _ := settingsRepo.Get(context.Background())
+		configure(&settings.Trash)
+		if err := settingsRepo.Save(context.Background(), settings); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storage := newMemStorage()
+	repo := store.NewTrashRepo(db)
+	return NewTrashService(repo, storage, store.NewSettingsRepo(db)), storage, repo
+}
+
+func TestTrashMoveAndRestore(t *testing.T) {
+	ctx := context.Background()
+	s, storage, _ := newTestTrash(t, nil)
+	storage.files["/gdrive/movies/a.mkv"] = []byte("12345")
+	storage.files["/gdrive/show/s01/e01.mkv"] = []byte("123")
+	storage.files["/gdrive/show/s01/e02.mkv"] = []byte("1234")
+
+	for _, p := range []string{"/gdrive/movies/a.mkv", "/gdrive/show"} {
+		if err := s.Delete(ctx, p); err != nil {
+			t.Fatalf("Delete(%s): %v", p, err)
+		}
+	}
+	if _, ok := storage.get("/gdrive/movies/a.mkv"); ok {
+		t.Error("file is still in place")
+	}
+
+	items, total, err := s.List(ctx, 10, 0)
+	if err != nil || total != 2 {
+		t.Fatalf("List: %d items, %v", total, err)
+	}
+	byName := make(map[string]*model.TrashItem)
+	for _, item := range items {
+		byName[item.Name] = item
+		if !strings.HasPrefix(item.TrashPath, "/gdrive/"+TrashDirName+"/"+item.ID+"/") {
+			t.Errorf("%s trashed to %s", item.Name, item.TrashPath)
+		}
+	}
+	if byName["a.mkv"].Size != 5 || byName["show"].Size != 7 || !byName["show"].IsDir {
+		t.Errorf("sizes: file %d, folder %d", byName["a.mkv"].Size, byName["show"].Size)
+	}
+	if _, ok := storage.get(byName["show"].TrashPath + "/s01/e02.mkv"); !ok {
+		t.Error("folder contents were not moved to the trash")
+	}
+
+	if _, err := s.Restore(ctx, byName["show"].ID); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := storage.get("/gdrive/show/s01/e01.mkv"); !ok || data != "123" {
+		t.Error("folder was not restored")
+	}
+
+	// A restore never overwrites what took the old place
+	storage.files["/gdrive/movies/a.mkv"] = []byte("new")
+	if _, err := s.Restore(ctx, byName["a.mkv"].ID); err == nil {
+		t.Error("restore overwrote an existing file")
+	}
+	if _, total, _ := s.List(ctx, 10, 0); total != 1 {
+		t.Errorf("%d items left, want 1", total)
+	}
+}
+
+func TestTrashPurge(t *testing.T) {
+	ctx := context.Background()
+	s, storage, _ := newTestTrash(t, nil)
+	storage.files["/gdrive/show/s01/e01.mkv"] = []byte("123")
+	storage.files["/gdrive/b.txt"] = []byte("b")
+	s.Delete(ctx, "/gdrive/show")
+	s.Delete(ctx, "/gdrive/b.txt")
+
+	items, _, _ := s.List(ctx, 10, 0)
+	if err := s.Purge(ctx, items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	n, err := s.Empty(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("Empty: %d, %v", n, err)
+	}
+	for p := range storage.files {
+		t.Errorf("%s survived the purge", p)
+	}
+	if err := s.Purge(ctx, items[0].ID); err == nil {
+		t.Error("purged an item twice")
+	}
+}
+
+func TestTrashAutoPurge(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, storage, repo := newTestTrash(t, func(ts *model.TrashSettings) {
+		ts.MaxAgeDays = 30
+		ts.MaxSize = "1K"
+	})
+
+	add := func(id string, size int, age time.Duration) {
+		item := &model.TrashItem{
+			ID:        id,
+			Name:      id + ".bin",
+			Remote:    "gdrive",
+			Size:      int64(size),
+			TrashPath: "/gdrive/" + TrashDirName + "/" + id + "/" + id + ".bin",
+			TrashedAt: now.Add(-age),
+		}
+		storage.files[item.TrashPath] = make([]byte, size)
+		if err := repo.Create(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("tr_expired", 10, 31*24*time.Hour)
+	add("tr_old", 600, 3*24*time.Hour)
+	add("tr_mid", 300, 2*24*time.Hour)
+	add("tr_new", 300, time.Hour)
+
+	s.autoPurge(ctx, now)
+
+	items, _, _ := s.List(ctx, 10, 0)
+	var left []string
+	for _, item := range items {
+		left = append(left, item.ID)
+	}
+	// The expired item goes by age, then the oldest until 1K fits
+	if strings.Join(left, ",") != "tr_new,tr_mid" {
+		t.Errorf("left %v, want [tr_new tr_mid]", left)
+	}
+	if len(storage.files) != 2 {
+		t.Errorf("%d files left, want 2", len(storage.files))
+	}
+}
+
+func TestTrashBypass(t *testing.T) {
+	ctx := context.Background()
+	s, storage, _ := newTestTrash(t, func(ts *model.TrashSettings) {
+		ts.BypassRemotes = []string{"s3"}
+	})
+	storage.files["/s3/a.txt"] = []byte("a")
+	storage.files["/gdrive/"+TrashDirName+"/tr_x/b.txt"] = []byte("b")
+
+	for _, p := range []string{"/s3/a.txt", "/gdrive/" + TrashDirName + "/tr_x/b.txt"} {
+		if err := s.Delete(ctx, p); err != nil {
+			t.Fatalf("Delete(%s): %v", p, err)
+		}
+		if _, ok := storage.get(p); ok {
+			t.Errorf("%s was not deleted", p)
+		}
+	}
+	if _, total, _ := s.List(ctx, 10, 0); total != 0 {
+		t.Errorf("%d items trashed, want none", total)
+	}
+	if err := s.Delete(ctx, "/gdrive"); err == nil {
+		t.Error("deleted the root of a remote")
+	}
+
+	off, storage, _ := newTestTrash(t, func(ts *model.TrashSettings) { ts.Enabled = false })
+	storage.files["/gdrive/c.txt"] = []byte("c")
+	if err := off.Delete(ctx, "/gdrive/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := off.List(ctx, 10, 0); total != 0 || len(storage.files) != 0 {
+		t.Error("disabled trash kept the file")
+	}
+}
@@ -2,36 +2,73 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"gravity/internal/engine"
+	apperrors "gravity/internal/errors"
 	"gravity/internal/event"
 	"gravity/internal/logger"
 	"gravity/internal/model"
 	"gravity/internal/store"
 
+	"github.com/google/uuid"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fspath"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// How often the upload queue is checked for retries that fell due
+const uploadQueueInterval = 5 * time.Second
+
+// How long a job that does not fit its remote waits before it is checked
+// again. Checks only read the cached usage, which is refreshed every
+// quotaTTL.
+const spaceRetryDelay = time.Minute
+
+// How many times a job is checked for space before it is refused, so the
+// download can settle without it
+const maxSpaceChecks = 60
+
+// UploadService runs the persistent upload queue. Each destination of a
+// download is an UploadJob; at most UploadSettings.ConcurrentUploads run at
+// once and failed ones are retried with backoff up to MaxRetryAttempts.
 type UploadService struct {
 	repo         *store.DownloadRepo
+	jobs         *store.UploadJobRepo
 	settingsRepo *store.SettingsRepo
 	engine       engine.UploadEngine
+	quota        *QuotaService
 	bus          *event.Bus
 	ctx          context.Context
 	logger       *zap.Logger
+
+	// Serializes read-modify-write of Download.Uploads, which the jobs of
+	// one download update concurrently, and guards running
+	mu      sync.Mutex
+	running map[string]*model.UploadJob // by job ID
+	wake    chan struct{}
 }
 
-func NewUploadService(repo *store.DownloadRepo, settingsRepo *store.SettingsRepo, eng engine.UploadEngine, bus *event.Bus) *UploadService {
+func NewUploadService(repo *store.DownloadRepo, jobs *store.UploadJobRepo, settingsRepo *store.SettingsRepo, eng engine.UploadEngine, bus *event.Bus, quota *QuotaService) *UploadService {
 	s := &UploadService{
 		repo:         repo,
+		jobs:         jobs,
 		settingsRepo: settingsRepo,
 		engine:       eng,
+		quota:        quota,
 		bus:          bus,
 		logger:       logger.Component("UPLOAD"),
+		running:      make(map[string]*model.UploadJob),
+		wake:         make(chan struct{}, 1),
 	}
 
 	// Wire up engine events
@@ -64,7 +101,12 @@ func (s *UploadService) Start(ctx context.Context) {
 			case <-s.ctx.Done():
 				return
 			case ev := <-lifecycleEvents:
-				if ev.Type != event.DownloadCompleted {
+				switch ev.Type {
+				case event.DownloadDeleted:
+					s.dropDownload(ev.ID)
+					continue
+				case event.DownloadCompleted:
+				default:
 					continue
 				}
 
@@ -76,25 +118,43 @@ func (s *UploadService) Start(ctx context.Context) {
 					continue
 				}
 
+				// Already written to its destination by the download engine
+				if d.WritesToDestination() {
+					continue
+				}
+
 				settings, err := s.settingsRepo.Get(s.ctx)
 				if err != nil || settings == nil {
 					continue
 				}
 
-				if d.Destination == "" && settings.Upload.AutoUpload {
-					d.Destination = settings.Upload.DefaultRemote
+				dests := d.AllDestinations()
+				if len(dests) == 0 && settings.Upload.AutoUpload {
+					dests = settings.Upload.DefaultDestinations()
 				}
 
-				if d.Destination != "" {
+				if len(dests) > 0 {
+					d.SetDestinations(dests)
 					s.TriggerUpload(s.ctx, d)
 				}
 			}
 		}
 	}()
+
+	// 3. Queue scheduler
+	go s.scheduler()
+	s.signal()
 }
 
 func (s *UploadService) Sync(ctx context.Context) error {
-	// Find all downloads stuck in "uploading" state
+	// Jobs interrupted by a restart start over; completed ones stay done
+	if n, err := s.jobs.Requeue(ctx); err != nil {
+		return err
+	} else if n > 0 {
+		s.logger.Info("requeued interrupted uploads", zap.Int64("count", n))
+	}
+
+	// Find all downloads stuck in "uploading" state without queued jobs
 	uploads, _, err := s.repo.List(ctx, []string{string(model.StatusUploading)}, 1000, 0, false)
 	if err != nil {
 		return err
@@ -107,64 +167,92 @@ func (s *UploadService) Sync(ctx context.Context) error {
 	}
 
 	for _, d := range uploads {
+		jobs, err := s.jobs.ListByDownload(ctx, d.ID)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(jobs, func(j *model.UploadJob) bool { return j.Status.Pending() }) {
+			continue
+		}
+
 		s.logger.Info("resetting stale upload", zap.String("id", d.ID))
-		_ = d.TransitionTo(model.StatusComplete)
 		d.UploadStatus = ""
-		d.UploadJobID = ""
-
-		target := d.Destination
-		if target == "" && autoUpload {
-			target = settings.Upload.DefaultRemote
+		for i := range d.Uploads {
+			if d.Uploads[i].Status.Pending() {
+				d.Uploads[i].Status = model.UploadStatusIdle
+				d.Uploads[i].Speed = 0
+			}
 		}
 
-		s.repo.Update(ctx, d)
+		targets := d.AllDestinations()
+		if len(targets) == 0 && autoUpload && settings != nil {
+			targets = settings.Upload.DefaultDestinations()
+		}
 
-		if target != "" {
-			s.logger.Debug("re-triggering stale upload", zap.String("id", d.ID), zap.String("dest", target))
-			d.Destination = target
-			go s.TriggerUpload(s.ctx, d)
+		if len(targets) > 0 {
+			s.logger.Debug("re-triggering stale upload", zap.String("id", d.ID), zap.Strings("dest", targets))
+			d.SetDestinations(targets)
+			if err := s.TriggerUpload(ctx, d); err != nil {
+				s.logger.Error("failed to re-trigger upload", zap.String("id", d.ID), zap.Error(err))
+			}
 		} else {
 			s.logger.Debug("skipping stale upload resume: no destination and auto-upload disabled", zap.String("id", d.ID))
+			_ = d.TransitionTo(model.StatusComplete)
+			s.repo.Update(ctx, d)
 		}
 	}
 
 	return nil
 }
 
+// TriggerUpload queues one upload job per destination of d. Destinations
+// already uploaded, or still queued, are not uploaded again.
 func (s *UploadService) TriggerUpload(ctx context.Context, d *model.Download) error {
+	// Destinations may be templates such as "gdrive:/Media/{category}"
+	settings, _ := s.settingsRepo.Get(ctx)
+	resolver := engine.NewOptionResolver(settings)
+	dests := d.AllDestinations()
+	for i, dst := range dests {
+		dests[i] = resolver.ExpandPath(dst, d)
+	}
 	s.logger.Info("triggering upload",
 		zap.String("id", d.ID),
-		zap.String("destination", d.Destination))
+		zap.Strings("destinations", dests))
 
-	// Generate job ID upfront so we can save it before starting the upload
-	// This avoids race condition with handleProgress overwriting it
-	jobID := time.Now().UnixNano()
-	jobIDStr := fmt.Sprintf("%d", jobID)
+	if len(dests) == 0 {
+		return apperrors.New(apperrors.CodeValidationFailed, "download has no destination")
+	}
 
-	if err := d.TransitionTo(model.StatusUploading); err != nil {
-		s.logger.Error("failed to transition to uploading", zap.Error(err))
-		// Should we return error? Yes.
-		return err
+	// Downloads that completed with a destination are already uploading
+	if d.Status != model.StatusUploading {
+		if err := d.TransitionTo(model.StatusUploading); err != nil {
+			s.logger.Error("failed to transition to uploading", zap.Error(err))
+			return err
+		}
 	}
-	d.UploadStatus = "running"
-	d.UploadJobID = jobIDStr // Save job ID BEFORE starting upload
-	s.repo.Update(ctx, d)
 
-	// Trigger in engine
-	// Use Dir (absolute) if available, otherwise Filename (relative/fallback)
-	srcPath := d.Dir
-	if srcPath == "" {
-		srcPath = d.Filename
+	s.mu.Lock()
+	d.Uploads = planUploads(d.Uploads, dests)
+	var queued []*model.UploadJob
+	for i := range d.Uploads {
+		u := &d.Uploads[i]
+		if u.Status == model.UploadStatusComplete || (u.Status.Pending() && u.JobID != "") {
+			continue
+		}
+		j := newUploadJob(d, i)
+		if err := s.jobs.Create(ctx, j); err != nil {
+			s.mu.Unlock()
+			s.deleteJobs(ctx, queued)
+			return err
+		}
+		queued = append(queued, j)
+		queueUpload(u, j.ID)
 	}
-	_, err := s.engine.Upload(ctx, srcPath, d.Destination, engine.UploadOptions{
-		TrackingID: d.ID,  // Pass download ID for progress tracking callbacks
-		JobID:      jobID, // Pass the pre-generated job ID
-	})
+	d.UploadStatus = model.UploadStatusRunning
+	err := s.repo.Update(ctx, d)
+	s.mu.Unlock()
 	if err != nil {
-		_ = d.TransitionTo(model.StatusError)
-		d.Error = "Upload failed: " + err.Error()
-		d.UploadJobID = "" // Clear job ID on failure
-		s.repo.Update(ctx, d)
+		s.deleteJobs(ctx, queued)
 		return err
 	}
 
@@ -172,66 +260,728 @@ func (s *UploadService) TriggerUpload(ctx context.Context, d *model.Download) er
 		Type:      event.UploadStarted,
 		ID:        d.ID,
 		Timestamp: time.Now(),
-		Data:      map[string]string{"id": d.ID, "destination": d.Destination},
+		Data:      map[string]string{"id": d.ID, "destination": strings.Join(dests, ",")},
 	})
 
+	if len(queued) == 0 {
+		s.settle(d.ID, nil)
+	}
+	s.signal()
+	return nil
+}
+
+// RetryUpload queues the upload of download id to a single destination
+// again, with a fresh retry budget
+func (s *UploadService) RetryUpload(ctx context.Context, id string, index int) error {
+	s.mu.Lock()
+	d, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.mu.Unlock()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NewNotFound("download", id)
+		}
+		return err
+	}
+	if index < 0 || index >= len(d.Uploads) {
+		s.mu.Unlock()
+		return apperrors.NewNotFound("upload", fmt.Sprint(index))
+	}
+	if st := d.Uploads[index].Status; st.Pending() || st == model.UploadStatusComplete {
+		s.mu.Unlock()
+		return apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("upload to %s is already %s", d.Uploads[index].Destination, st))
+	}
+
+	// A download that already met its quorum stays complete
+	if d.Status != model.StatusComplete && d.Status != model.StatusUploading {
+		if err := d.TransitionTo(model.StatusUploading); err != nil {
+			s.mu.Unlock()
+			return apperrors.New(apperrors.CodeInvalidOperation, err.Error())
+		}
+		d.Error = ""
+	}
+
+	j := newUploadJob(d, index)
+	if err := s.jobs.Create(ctx, j); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	queueUpload(&d.Uploads[index], j.ID)
+	d.Uploads[index].RetryCount++
+	d.UploadStatus = model.UploadStatusRunning
+	err = s.repo.Update(ctx, d)
+	s.mu.Unlock()
+	if err != nil {
+		s.deleteJobs(ctx, []*model.UploadJob{j})
+		return err
+	}
+
+	s.logger.Info("retrying upload",
+		zap.String("id", d.ID),
+		zap.String("destination", j.Destination))
+	s.signal()
 	return nil
 }
 
-func (s *UploadService) handleProgress(downloadID string, p engine.UploadProgress) {
-	ctx := s.ctx
-	if ctx == nil {
-		ctx = context.Background()
+// List returns queued, running and finished upload jobs, newest first
+func (s *UploadService) List(ctx context.Context, status []string, limit, offset int) ([]*model.UploadJob, int, error) {
+	jobs, total, err := s.jobs.List(ctx, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
 	}
-	d, err := s.repo.Get(ctx, downloadID)
+	s.mergeLive(jobs...)
+	return jobs, total, nil
+}
+
+func (s *UploadService) Get(ctx context.Context, id string) (*model.UploadJob, error) {
+	j, err := s.getJob(ctx, id)
 	if err != nil {
-		// Download may have been deleted - this is expected, silently ignore
+		return nil, err
+	}
+	s.mergeLive(j)
+	return j, nil
+}
+
+// Pause stops a queued or running upload until it is resumed. A running
+// upload starts over when resumed.
+func (s *UploadService) Pause(ctx context.Context, id string) error {
+	return s.interrupt(ctx, id, model.UploadStatusPaused, "")
+}
+
+// Cancel stops an upload for good. The destination then counts as failed
+// towards the download's quorum.
+func (s *UploadService) Cancel(ctx context.Context, id string) error {
+	return s.interrupt(ctx, id, model.UploadStatusCancelled, "cancelled")
+}
+
+func (s *UploadService) Resume(ctx context.Context, id string) error {
+	j, err := s.getJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	if j.Status != model.UploadStatusPaused {
+		return apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("cannot resume upload in status %s", j.Status))
+	}
+
+	j.Status = model.UploadStatusQueued
+	j.NextRetryAt = nil
+	if err := s.jobs.Update(ctx, j); err != nil {
+		return err
+	}
+	s.updateUpload(j, func(u *model.UploadTarget) {
+		u.Status = model.UploadStatusQueued
+	})
+	s.signal()
+	return nil
+}
+
+// Retry queues a failed, mismatched or cancelled upload again
+func (s *UploadService) Retry(ctx context.Context, id string) error {
+	j, err := s.getJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !j.Status.Failed() {
+		return apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("cannot retry upload in status %s", j.Status))
+	}
+	return s.RetryUpload(ctx, j.DownloadID, j.Index)
+}
+
+func (s *UploadService) interrupt(ctx context.Context, id string, status model.UploadStatus, reason string) error {
+	s.mu.Lock()
+	j, running := s.running[id]
+	if !running {
+		var err error
+		if j, err = s.getJob(ctx, id); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	if !j.Status.Pending() || j.Status == status {
+		s.mu.Unlock()
+		return apperrors.New(apperrors.CodeInvalidOperation, fmt.Sprintf("upload is already %s", j.Status))
+	}
+	// Set before cancelling so handleError knows this was on purpose
+	j.Status = status
+	j.Error = reason
+	j.Speed = 0
+	err := s.jobs.Update(ctx, j)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if running {
+		s.engine.Cancel(ctx, id)
+	}
+	s.logger.Info("upload "+string(status), zap.String("job", id), zap.String("destination", j.Destination))
+	s.updateUpload(j, func(u *model.UploadTarget) {
+		u.Status = status
+		u.Error = reason
+		u.Speed = 0
+	})
+	s.signal()
+	return nil
+}
+
+// dropDownload cancels and forgets the uploads of a deleted download
+func (s *UploadService) dropDownload(downloadID string) {
+	s.mu.Lock()
+	var cancel []string
+	for id, j := range s.running {
+		if j.DownloadID == downloadID {
+			j.Status = model.UploadStatusCancelled
+			cancel = append(cancel, id)
+		}
+	}
+	s.mu.Unlock()
+
+	ctx := s.context()
+	for _, id := range cancel {
+		s.engine.Cancel(ctx, id)
+	}
+	if err := s.jobs.DeleteByDownload(ctx, downloadID); err != nil {
+		s.logger.Error("failed to delete upload jobs", zap.String("id", downloadID), zap.Error(err))
+	}
+}
+
+func (s *UploadService) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default: // Already signaled
+	}
+}
+
+// scheduler starts queued uploads whenever a slot frees up or a retry
+// falls due
+func (s *UploadService) scheduler() {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("panic in upload scheduler", zap.Any("panic", r))
+		}
+	}()
+
+	ticker := time.NewTicker(uploadQueueInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+		s.processQueue()
+	}
+}
+
+func (s *UploadService) processQueue() {
+	ctx := s.context()
+	limit := 1
+	if settings, _ := s.settingsRepo.Get(ctx); settings != nil && settings.Upload.ConcurrentUploads > 0 {
+		limit = settings.Upload.ConcurrentUploads
+	}
+
+	s.mu.Lock()
+	free := limit - len(s.running)
+	s.mu.Unlock()
+	if free <= 0 {
+		return
+	}
+
+	jobs, err := s.jobs.NextQueued(ctx, time.Now(), free)
+	if err != nil {
+		s.logger.Error("failed to read upload queue", zap.Error(err))
+		return
+	}
+	for _, j := range jobs {
+		s.startJob(ctx, j)
+	}
+}
+
+func (s *UploadService) startJob(ctx context.Context, j *model.UploadJob) {
+	if msg, ok := s.checkSpace(ctx, j); !ok {
+		s.waitForSpace(ctx, j, msg)
+		return
+	}
+
+	now := time.Now()
+	j.Status = model.UploadStatusRunning
+	j.Attempts++
+	j.StartedAt = &now
+	j.NextRetryAt = nil
+	j.Uploaded = 0
+	if err := s.jobs.Update(ctx, j); err != nil {
+		s.logger.Error("failed to start upload", zap.String("job", j.ID), zap.Error(err))
+		return
+	}
+
+	s.mu.Lock()
+	s.running[j.ID] = j
+	s.mu.Unlock()
+
+	d := s.updateUpload(j, func(u *model.UploadTarget) {
+		u.Status = model.UploadStatusRunning
+		u.Uploaded = 0
+		u.Speed = 0
+		u.StartedAt = &now
+	})
+	if d == nil {
+		// The download or this destination is gone
+		s.release(j.ID)
+		j.Status = model.UploadStatusCancelled
+		j.Error = "download no longer exists"
+		s.jobs.Update(ctx, j)
+		return
+	}
+
+	s.logger.Debug("starting upload",
+		zap.String("job", j.ID),
+		zap.String("id", j.DownloadID),
+		zap.String("destination", j.Destination),
+		zap.Int("attempt", j.Attempts))
+
+	// Jobs outlive the request that queued them
+	_, err := s.engine.Upload(s.context(), j.Source, j.Destination, engine.UploadOptions{
+		TrackingID: j.ID, // Routes progress callbacks back to this job
+		Force:      j.Force,
+	})
+	if err != nil {
+		s.handleError(j.ID, err)
+	}
+}
+
+// checkSpace compares the size of job j, plus what running uploads to the
+// same remote have left to send, with the free space of the remote. It
+// returns false and the reason if the job must not start. Remotes without a
+// quota always pass.
+func (s *UploadService) checkSpace(ctx context.Context, j *model.UploadJob) (string, bool) {
+	remote := destinationRemote(j.Destination)
+	if s.quota == nil || j.Size <= 0 || remote == "" {
+		return "", true
+	}
+	mode := model.QuotaCheckRefuse
+	if settings, _ := s.settingsRepo.Get(ctx); settings != nil && settings.Upload.QuotaCheck != "" {
+		mode = settings.Upload.QuotaCheck
+	}
+	if mode == model.QuotaCheckOff {
+		return "", true
+	}
+	free, ok := s.quota.Usage(remote).FreeSpace()
+	if !ok {
+		return "", true
+	}
+
+	need := j.Size
+	s.mu.Lock()
+	for _, r := range s.running {
+		if destinationRemote(r.Destination) == remote {
+			need += max(r.Size-r.Uploaded, 0)
+		}
+	}
+	s.mu.Unlock()
+	if need <= free {
+		return "", true
+	}
+
+	msg := fmt.Sprintf("Not enough space on %s: uploads need %s, %s free", remote, fs.SizeSuffix(need), fs.SizeSuffix(free))
+	if mode == model.QuotaCheckWarn {
+		s.logger.Warn("upload may not fit its destination", zap.String("job", j.ID), zap.String("reason", msg))
+		return "", true
+	}
+	return msg, false
+}
+
+// waitForSpace leaves job j queued for another spaceRetryDelay, since its
+// remote has too little space for it now. Waiting does not count as an
+// attempt. After maxSpaceChecks the job is refused, which lets the download
+// settle on its other destinations.
+func (s *UploadService) waitForSpace(ctx context.Context, j *model.UploadJob, msg string) {
+	j.SpaceChecks++
+	if j.SpaceChecks >= maxSpaceChecks {
+		s.logger.Warn("upload refused, not enough space",
+			zap.String("job", j.ID),
+			zap.String("destination", j.Destination),
+			zap.Int("checks", j.SpaceChecks),
+			zap.String("reason", msg))
+		s.finishFailed(j, model.UploadStatusError, "Refused: "+msg)
+		return
+	}
+
+	next := time.Now().Add(spaceRetryDelay)
+	j.Error = msg
+	j.NextRetryAt = &next
+	if err := s.jobs.Update(ctx, j); err != nil {
+		s.logger.Error("failed to save upload job", zap.String("job", j.ID), zap.Error(err))
+	}
+	if j.SpaceChecks == 1 {
+		s.logger.Warn("upload waiting for space",
+			zap.String("job", j.ID),
+			zap.String("destination", j.Destination),
+			zap.Duration("refused_after", spaceRetryDelay*(maxSpaceChecks-1)),
+			zap.String("reason", msg))
+	}
+	s.updateUpload(j, func(u *model.UploadTarget) {
+		u.Error = msg
+	})
+}
+
+// release removes a job from the running set, returning nil if it was not
+// running
+func (s *UploadService) release(jobID string) *model.UploadJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.running[jobID]
+	delete(s.running, jobID)
+	return j
+}
+
+func (s *UploadService) handleProgress(jobID string, p engine.UploadProgress) {
+	s.mu.Lock()
+	j := s.running[jobID]
+	if j != nil {
+		j.Uploaded = p.Uploaded
+		j.Size = p.Size
+		j.Speed = p.Speed
+	}
+	s.mu.Unlock()
+	if j == nil {
 		return
 	}
 
-	// Update DB with latest progress
-	d.UploadProgress = 0
-	if p.Size > 0 {
-		d.UploadProgress = int((p.Uploaded * 100) / p.Size)
+	d := s.updateUpload(j, func(u *model.UploadTarget) {
+		if u.Status != model.UploadStatusRunning {
+			return
+		}
+		u.Uploaded = p.Uploaded
+		u.Size = p.Size
+		u.Speed = p.Speed
+	})
+	if d == nil {
+		// Download may have been deleted - this is expected, silently ignore
+		return
 	}
-	d.UploadSpeed = p.Speed
-	// Note: We don't save upload progress to DB every time to avoid overhead
-	// But we publish it to the bus
 
+	// Publish the combined progress of all destinations
+	var uploaded, size, speed int64
+	for _, u := range d.Uploads {
+		uploaded += u.Uploaded
+		size += u.Size
+		speed += u.Speed
+	}
 	s.bus.PublishProgress(event.ProgressEvent{
 		ID:       d.ID,
 		Type:     "upload",
-		Uploaded: p.Uploaded,
-		Size:     p.Size,
-		Speed:    p.Speed,
+		Uploaded: uploaded,
+		Size:     size,
+		Speed:    speed,
 	})
 }
 
-func (s *UploadService) handleComplete(downloadID string) {
-	ctx := s.ctx
-	if ctx == nil {
-		ctx = context.Background()
+func (s *UploadService) handleComplete(jobID string) {
+	if settings, _ := s.settingsRepo.Get(s.context()); settings != nil && settings.Upload.VerifyUploads {
+		if s.beginVerify(jobID) {
+			return
+		}
 	}
-	d, err := s.repo.Get(ctx, downloadID)
-	if err != nil {
+
+	j := s.release(jobID)
+	if j == nil {
+		return
+	}
+	defer s.signal()
+	s.complete(j, "")
+}
+
+func (s *UploadService) handleError(jobID string, err error) {
+	j := s.release(jobID)
+	if j == nil {
+		return
+	}
+	defer s.signal()
+
+	// Paused or cancelled on purpose; the engine reports that as an error
+	if j.Status != model.UploadStatusRunning {
+		return
+	}
+	s.fail(j, err)
+}
+
+// beginVerify moves running job jobID to verifying and compares the remote
+// copy with the local one in the background. The job keeps its slot in the
+// running set until the comparison is done.
+func (s *UploadService) beginVerify(jobID string) bool {
+	s.mu.Lock()
+	j := s.running[jobID]
+	if j == nil || j.Status != model.UploadStatusRunning {
+		s.mu.Unlock()
+		return false
+	}
+	j.Status = model.UploadStatusVerifying
+	j.Speed = 0
+	if j.Size > 0 {
+		j.Uploaded = j.Size
+	}
+	s.mu.Unlock()
+
+	if err := s.jobs.Update(s.context(), j); err != nil {
+		s.logger.Error("failed to save upload job", zap.String("job", j.ID), zap.Error(err))
+	}
+	s.updateUpload(j, func(u *model.UploadTarget) {
+		u.Status = model.UploadStatusVerifying
+		u.Speed = 0
+		if u.Size > 0 {
+			u.Uploaded = u.Size
+		}
+	})
+
+	go s.verify(j)
+	return true
+}
+
+// verify compares the uploaded copy of job j with the local data. Only the
+// download's own file or folder is checked when it is still on disk, as that
+// is what removeLocal deletes.
+func (s *UploadService) verify(j *model.UploadJob) {
+	ctx := s.context()
+	src, dst := j.Source, j.Destination
+	if d, err := s.repo.Get(ctx, j.DownloadID); err == nil && d.Dir != "" && d.Filename != "" {
+		local := filepath.Join(d.Dir, d.Filename)
+		if _, err := os.Stat(local); err == nil && local != filepath.Clean(j.Source) {
+			src, dst = local, fspath.JoinRootPath(j.Destination, d.Filename)
+		}
+	}
+
+	s.logger.Debug("verifying upload", zap.String("job", j.ID), zap.String("src", src), zap.String("dst", dst))
+	result, err := s.engine.Verify(ctx, src, dst)
+
+	// Paused, cancelled or dropped while verifying
+	s.mu.Lock()
+	current := s.running[j.ID] == j && j.Status == model.UploadStatusVerifying
+	if current {
+		delete(s.running, j.ID)
+	}
+	s.mu.Unlock()
+	if !current {
+		return
+	}
+	defer s.signal()
+
+	switch {
+	case err != nil:
+		s.fail(j, fmt.Errorf("verification failed: %w", err))
+	case !result.OK():
+		s.mismatch(j, result)
+	default:
+		s.complete(j, result.Method)
+	}
+}
+
+func (s *UploadService) complete(j *model.UploadJob, verifiedBy string) {
+	now := time.Now()
+	j.Status = model.UploadStatusComplete
+	j.Error = ""
+	j.Speed = 0
+	j.VerifiedBy = verifiedBy
+	if j.Size > 0 {
+		j.Uploaded = j.Size
+	}
+	j.CompletedAt = &now
+	if err := s.jobs.Update(s.context(), j); err != nil {
+		s.logger.Error("failed to save upload job", zap.String("job", j.ID), zap.Error(err))
+	}
+	if s.quota != nil {
+		s.quota.Invalidate(destinationRemote(j.Destination))
+	}
+	s.logger.Info("upload complete",
+		zap.String("id", j.DownloadID),
+		zap.String("destination", j.Destination),
+		zap.String("verified_by", verifiedBy))
+
+	s.updateUpload(j, func(u *model.UploadTarget) {
+		u.Status = model.UploadStatusComplete
+		u.Error = ""
+		u.Speed = 0
+		u.VerifiedBy = verifiedBy
+		if u.Size > 0 {
+			u.Uploaded = u.Size
+		}
+		u.CompletedAt = &now
+	})
+}
+
+// fail retries job j with backoff if err is transient and attempts remain,
+// and marks it failed otherwise
+func (s *UploadService) fail(j *model.UploadJob, err error) {
+	ctx := s.context()
+	maxRetries := 0
+	if settings, _ := s.settingsRepo.Get(ctx); settings != nil {
+		maxRetries = settings.Upload.MaxRetryAttempts
+	}
+
+	msg := "Upload error: " + err.Error()
+	j.Error = msg
+	j.Speed = 0
+
+	if isRetryableUploadError(err) && j.Attempts <= maxRetries {
+		next := time.Now().Add(calculateBackoff(j.Attempts - 1))
+		j.Status = model.UploadStatusQueued
+		j.NextRetryAt = &next
+		if err := s.jobs.Update(ctx, j); err != nil {
+			s.logger.Error("failed to save upload job", zap.String("job", j.ID), zap.Error(err))
+		}
+		s.logger.Warn("upload failed, retrying",
+			zap.String("id", j.DownloadID),
+			zap.String("destination", j.Destination),
+			zap.Int("attempt", j.Attempts),
+			zap.Time("next_retry", next),
+			zap.Error(err))
+		s.updateUpload(j, func(u *model.UploadTarget) {
+			u.Status = model.UploadStatusQueued
+			u.Error = msg
+			u.Speed = 0
+			u.RetryCount++
+		})
 		return
 	}
 
-	_ = d.TransitionTo(model.StatusComplete)
-	d.UploadStatus = "complete"
-	d.UploadProgress = 100
-	s.repo.Update(ctx, d)
+	s.finishFailed(j, model.UploadStatusError, msg)
+}
+
+// mismatch records that the upload of job j finished but the remote copy
+// differs from the local one. The local copy is kept so it can be sent
+// again with RetryUpload.
+func (s *UploadService) mismatch(j *model.UploadJob, result *engine.VerifyResult) {
+	bad := append(slices.Clone(result.Missing), result.Mismatched...)
+	msg := fmt.Sprintf("Verification failed: %d of %d files missing or different on %s (first: %s)",
+		len(bad), result.Files+len(result.Missing), j.Destination, bad[0])
+	s.logger.Warn("upload verification failed",
+		zap.String("id", j.DownloadID),
+		zap.String("destination", j.Destination),
+		zap.Strings("missing", result.Missing),
+		zap.Strings("mismatched", result.Mismatched))
+
+	s.finishFailed(j, model.UploadStatusMismatch, msg)
+}
+
+func (s *UploadService) finishFailed(j *model.UploadJob, status model.UploadStatus, msg string) {
+	j.Status = status
+	j.Error = msg
+	j.Speed = 0
+	if err := s.jobs.Update(s.context(), j); err != nil {
+		s.logger.Error("failed to save upload job", zap.String("job", j.ID), zap.Error(err))
+	}
+	d := s.updateUpload(j, func(u *model.UploadTarget) {
+		u.Status = status
+		u.Error = msg
+		u.Speed = 0
+	})
+	if d == nil {
+		// Download may have been deleted - this is expected, silently ignore
+		return
+	}
 
 	s.bus.PublishLifecycle(event.LifecycleEvent{
-		Type:      event.UploadCompleted,
+		Type:      event.UploadError,
 		ID:        d.ID,
 		Timestamp: time.Now(),
-		Data:      d,
+		Error:     msg,
+		Data:      map[string]string{"id": d.ID, "destination": j.Destination, "status": string(status), "error": msg},
+	})
+}
+
+// updateUpload applies fn to the upload target of job j and settles the
+// download. It returns nil if the download is gone or the target has been
+// handed to another job.
+func (s *UploadService) updateUpload(j *model.UploadJob, fn func(u *model.UploadTarget)) *model.Download {
+	return s.settle(j.DownloadID, func(d *model.Download) bool {
+		if j.Index >= len(d.Uploads) || d.Uploads[j.Index].JobID != j.ID {
+			return false
+		}
+		fn(&d.Uploads[j.Index])
+		return true
 	})
+}
+
+// settle applies fn to download id, then completes it once the quorum is
+// reached or fails it once every target failed. It returns nil if the
+// download no longer exists or fn rejects it.
+func (s *UploadService) settle(id string, fn func(d *model.Download) bool) *model.Download {
+	ctx := s.context()
+	settings, _ := s.settingsRepo.Get(ctx)
+
+	s.mu.Lock()
+	d, err := s.repo.Get(ctx, id)
+	if err != nil || (fn != nil && !fn(d)) {
+		s.mu.Unlock()
+		return nil
+	}
+
+	quorum := 0
+	if settings != nil {
+		quorum = settings.Upload.Quorum
+	}
+	if d.UploadQuorum != nil {
+		quorum = *d.UploadQuorum
+	}
+	outcome := settleUploads(d, quorum)
+	if err := s.repo.Update(ctx, d); err != nil {
+		s.mu.Unlock()
+		s.logger.Error("failed to save upload state", zap.String("id", id), zap.Error(err))
+		return nil
+	}
+	s.mu.Unlock()
+
+	switch outcome {
+	case uploadsComplete:
+		s.bus.PublishLifecycle(event.LifecycleEvent{
+			Type:      event.UploadCompleted,
+			ID:        d.ID,
+			Timestamp: time.Now(),
+			Data:      d,
+		})
+	case uploadsFailed:
+		s.logger.Warn("upload failed", zap.String("id", d.ID), zap.String("error", d.Error))
+	}
+
+	// The local copy is only needed while a destination may still use it,
+	// including one whose copy failed verification
+	if d.UploadQuorumMet(quorum) && !d.UploadsPending() && !d.UploadsMismatched() {
+		s.removeLocal(settings, d)
+	}
+	return d
+}
+
+// mergeLive copies the progress of running jobs into jobs
+func (s *UploadService) mergeLive(jobs ...*model.UploadJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range jobs {
+		if r, ok := s.running[j.ID]; ok {
+			j.Uploaded = r.Uploaded
+			j.Size = r.Size
+			j.Speed = r.Speed
+		}
+	}
+}
 
-	settings, err := s.settingsRepo.Get(ctx)
+func (s *UploadService) getJob(ctx context.Context, id string) (*model.UploadJob, error) {
+	j, err := s.jobs.Get(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperrors.NewNotFound("upload", id)
+	}
+	return j, err
+}
+
+func (s *UploadService) deleteJobs(ctx context.Context, jobs []*model.UploadJob) {
+	for _, j := range jobs {
+		if err := s.jobs.Delete(ctx, j.ID); err != nil {
+			s.logger.Error("failed to delete upload job", zap.String("job", j.ID), zap.Error(err))
+		}
+	}
+}
+
+func (s *UploadService) removeLocal(settings *model.Settings, d *model.Download) {
 	shouldDelete := true
-	if err == nil && settings != nil {
+	if settings != nil {
 		shouldDelete = settings.Upload.RemoveLocal
 	}
 	if d.RemoveLocal != nil {
@@ -240,6 +990,9 @@ func (s *UploadService) handleComplete(downloadID string) {
 
 	if shouldDelete && d.Dir != "" {
 		filePath := filepath.Join(d.Dir, d.Filename)
+		if _, err := os.Lstat(filePath); err != nil {
+			return
+		}
 		s.logger.Debug("deleting local copy after upload", zap.String("path", filePath))
 		if err := os.RemoveAll(filePath); err != nil {
 			s.logger.Error("failed to delete local file", zap.String("path", filePath), zap.Error(err))
@@ -249,27 +1002,117 @@ func (s *UploadService) handleComplete(downloadID string) {
 	}
 }
 
-func (s *UploadService) handleError(downloadID string, err error) {
-	ctx := s.ctx
-	if ctx == nil {
-		ctx = context.Background()
+func (s *UploadService) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
 	}
-	d, repoErr := s.repo.Get(ctx, downloadID)
-	if repoErr != nil {
-		// Download may have been deleted - this is expected, silently ignore
-		return
+	return s.ctx
+}
+
+type uploadOutcome int
+
+const (
+	uploadsPending uploadOutcome = iota
+	uploadsComplete
+	uploadsFailed
+)
+
+// settleUploads moves an uploading download to complete once quorum
+// destinations succeeded, or to error once none are pending and the quorum
+// can no longer be met
+func settleUploads(d *model.Download, quorum int) uploadOutcome {
+	if d.Status != model.StatusUploading {
+		return uploadsPending
+	}
+	if d.UploadQuorumMet(quorum) {
+		_ = d.TransitionTo(model.StatusComplete)
+		d.UploadStatus = model.UploadStatusComplete
+		d.UploadProgress = 100
+		return uploadsComplete
+	}
+	if d.UploadsPending() {
+		return uploadsPending
 	}
 
+	var errs []string
+	for _, u := range d.Uploads {
+		if u.Status.Failed() {
+			errs = append(errs, u.Destination+": "+u.Error)
+		}
+	}
 	_ = d.TransitionTo(model.StatusError)
-	d.Error = "Upload error: " + err.Error()
-	d.UploadStatus = "error"
-	s.repo.Update(ctx, d)
+	d.Error = "Upload error: " + strings.Join(errs, "; ")
+	d.UploadStatus = model.UploadStatusError
+	return uploadsFailed
+}
 
-	s.bus.PublishLifecycle(event.LifecycleEvent{
-		Type:      event.UploadError,
-		ID:        d.ID,
-		Timestamp: time.Now(),
-		Error:     d.Error,
-		Data:      map[string]string{"id": d.ID, "error": d.Error},
-	})
+// destinationRemote returns the name of the remote an upload destination
+// is on, or "" for a local path or an on-the-fly backend such as ":s3,..."
+func destinationRemote(dst string) string {
+	parsed, err := fspath.Parse(dst)
+	if err != nil || strings.HasPrefix(parsed.Name, ":") {
+		return ""
+	}
+	return parsed.Name
+}
+
+// planUploads returns one target per destination, keeping the state of
+// targets from an earlier attempt whose destination is unchanged
+func planUploads(prev []model.UploadTarget, dests []string) []model.UploadTarget {
+	targets := make([]model.UploadTarget, len(dests))
+	for i, dst := range dests {
+		targets[i] = model.UploadTarget{Destination: dst, Status: model.UploadStatusIdle}
+		for _, p := range prev {
+			if p.Destination == dst {
+				targets[i] = p
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// queueUpload hands target u to a freshly queued job
+func queueUpload(u *model.UploadTarget, jobID string) {
+	u.Status = model.UploadStatusQueued
+	u.JobID = jobID
+	u.Uploaded = 0
+	u.Speed = 0
+	u.Error = ""
+	u.StartedAt = nil
+	u.CompletedAt = nil
+}
+
+func newUploadJob(d *model.Download, index int) *model.UploadJob {
+	// Use Dir (absolute) if available, otherwise Filename (relative/fallback)
+	src := d.Dir
+	if src == "" {
+		src = d.Filename
+	}
+	return &model.UploadJob{
+		ID:          "u_" + uuid.New().String()[:8],
+		DownloadID:  d.ID,
+		Index:       index,
+		Source:      src,
+		Destination: d.Uploads[index].Destination,
+		Status:      model.UploadStatusQueued,
+		Size:        d.Size,
+		// A copy that failed verification may look up to date to rclone
+		Force: d.Uploads[index].Status == model.UploadStatusMismatch,
+	}
+}
+
+// isRetryableUploadError reports whether an upload failure is worth
+// retrying: network and rate limit errors are, missing files or rejected
+// credentials are not
+func isRetryableUploadError(err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case fserrors.IsFatalError(err), fserrors.IsNoRetryError(err):
+		return false
+	case fserrors.IsRetryError(err), fserrors.ShouldRetry(err):
+		return true
+	}
+	return isRetryableError(err)
 }
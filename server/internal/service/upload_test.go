@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gravity/internal/event"
+	"gravity/internal/model"
+	"gravity/internal/store"
+)
+
+func TestUploadWaitsForSpace(t *testing.T) {
+	ctx := context.Background()
+	db := newTestStore(t).GetDB()
+	downloads := store.NewDownloadRepo(db)
+	jobs := store.NewUploadJobRepo(db)
+	e := &usageEngine{free: 10}
+	q := NewQuotaService(e)
+	q.refresh(ctx, "gdrive")
+	s := NewUploadService(downloads, jobs, store.NewSettingsRepo(db), e, event.NewBus(), q)
+
+	d := &model.Download{
+		ID:       "d_1",
+		URL:      "https://example.com/a.iso",
+		Filename: "a.iso",
+		Dir:      t.TempDir(),
+		Size:     100,
+		Status:   model.StatusUploading,
+		Uploads:  []model.UploadTarget{{Destination: "gdrive:isos"}},
+	}
+	j := newUploadJob(d, 0)
+	queueUpload(&d.Uploads[0], j.ID)
+	if err := downloads.Create(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if err := jobs.Create(ctx, j); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	s.startJob(ctx, j)
+
+	got, err := jobs.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.UploadStatusQueued || got.Attempts != 0 || !strings.Contains(got.Error, "Not enough space") {
+		t.Errorf("job = %s, %d attempts, error %q", got.Status, got.Attempts, got.Error)
+	}
+	if got.NextRetryAt == nil || got.NextRetryAt.Before(start.Add(spaceRetryDelay)) {
+		t.Errorf("next check at %v, want %v after %v", got.NextRetryAt, spaceRetryDelay, start)
+	}
+	if len(s.running) != 0 {
+		t.Error("job started without space")
+	}
+	dl, _ := downloads.Get(ctx, d.ID)
+	if u := dl.Uploads[0]; u.Status != model.UploadStatusQueued || u.Error != got.Error {
+		t.Errorf("target = %s, error %q", u.Status, u.Error)
+	}
+	if dl.Status == model.StatusError {
+		t.Error("download failed while its upload waits for space")
+	}
+	// Until it has waited long enough, then it is refused and the
+	// download settles without it
+	for got.Status == model.UploadStatusQueued && got.SpaceChecks < maxSpaceChecks {
+		s.startJob(ctx, got)
+		got, _ = jobs.Get(ctx, j.ID)
+	}
+	if got.Status != model.UploadStatusError || got.SpaceChecks != maxSpaceChecks || !strings.HasPrefix(got.Error, "Refused: Not enough space") {
+		t.Errorf("job = %s after %d checks, error %q", got.Status, got.SpaceChecks, got.Error)
+	}
+	if dl, _ := downloads.Get(ctx, d.ID); dl.Status != model.StatusError || dl.Uploads[0].Status != model.UploadStatusError {
+		t.Errorf("download = %s, target %s", dl.Status, dl.Uploads[0].Status)
+	}
+}
@@ -79,6 +79,9 @@ func New(cfg *config.Config) (*Store, error) {
 	l := logger.Component("DATABASE")
 
 	l.Debug("Connection established. Running AutoMigrate...")
+	// Checked before the column is added, so existing installs get the trash
+	// defaults instead of the zero value
+	hadTrash := db.Migrator().HasColumn(&model.Settings{}, "Trash")
 	// RUN AUTOMIGRATE
 	if err := db.AutoMigrate(
 		&model.Download{},
@@ -88,10 +91,24 @@ func New(cfg *config.Config) (*Store, error) {
 		&StatsKV{},
 		&model.IndexedFile{},
 		&model.RemoteIndexConfig{},
+		&model.IndexRun{},
+		&model.SavedSearch{},
+		&model.SiteProfile{},
+		&model.UploadJob{},
+		&model.SyncJob{},
+		&model.SyncRun{},
+		&model.ShareLink{},
+		&model.TrashItem{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to auto-migrate: %w", err)
 	}
 
+	if !hadTrash {
+		if err := NewSettingsRepo(db).setTrashDefaults(); err != nil {
+			l.Warn("failed to set trash defaults", zap.Error(err))
+		}
+	}
+
 	l.Debug("AutoMigrate complete. Configuring connection pool...")
 	sqlDB, err := db.DB()
 	if err == nil {
@@ -117,8 +134,25 @@ func New(cfg *config.Config) (*Store, error) {
 	// PostgreSQL-specific FTS index
 	if cfg.Database.Type == "postgres" {
 		l.Debug("Creating Postgres FTS index...")
-		if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_indexed_files_fts ON indexed_files USING GIN (to_tsvector('english', filename || ' ' || path));`).Error; err != nil {
-			l.Warn("Postgres FTS index creation failed", zap.Error(err))
+		// Search splits names on punctuation itself, which the english
+		// parser of the old index did not
+		stmts := []string{
+			`DROP INDEX IF EXISTS idx_indexed_files_fts;`,
+			`DROP INDEX IF EXISTS idx_indexed_files_tokens;`,
+			`CREATE INDEX IF NOT EXISTS idx_indexed_files_search ON indexed_files USING GIN (` + pgSearchTokens + `);`,
+		}
+		for _, stmt := range stmts {
+			if err := db.Exec(stmt).Error; err != nil {
+				l.Warn("Postgres FTS index creation failed", zap.Error(err))
+				break
+			}
+		}
+
+		// Fuzzy search needs pg_trgm, which may need a superuser to install
+		if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm;`).Error; err != nil {
+			l.Warn("pg_trgm is not available, fuzzy search is disabled", zap.Error(err))
+		} else if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_indexed_files_trgm ON indexed_files USING GIN (filename gin_trgm_ops);`).Error; err != nil {
+			l.Warn("Postgres trigram index creation failed", zap.Error(err))
 		}
 	}
 
@@ -141,20 +175,64 @@ func (s *Store) GetDB() *gorm.DB {
 func (s *Store) setupSQLiteFTS() error {
 	// Custom SQL for FTS5 (AutoMigrate doesn't support virtual tables)
 	stmts := []string{
-		`CREATE VIRTUAL TABLE IF NOT EXISTS files_search USING fts5(filename, path, remote, content='indexed_files', content_rowid='rowid');`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS files_search USING fts5(filename, path, remote, url, provider, content='indexed_files', content_rowid='rowid');`,
 		`DROP TRIGGER IF EXISTS indexed_files_ai;`,
 		`CREATE TRIGGER indexed_files_ai AFTER INSERT ON indexed_files BEGIN
-			INSERT INTO files_search(rowid, filename, path, remote) VALUES (new.rowid, new.filename, new.path, new.remote);
+			INSERT INTO files_search(rowid, filename, path, remote, url, provider) VALUES (new.rowid, new.filename, new.path, new.remote, new.url, new.provider);
 		END;`,
 		`DROP TRIGGER IF EXISTS indexed_files_ad;`,
 		`CREATE TRIGGER indexed_files_ad AFTER DELETE ON indexed_files BEGIN
-			INSERT INTO files_search(files_search, rowid, filename, path, remote) VALUES('delete', old.rowid, old.filename, old.path, old.remote);
+			INSERT INTO files_search(files_search, rowid, filename, path, remote, url, provider) VALUES('delete', old.rowid, old.filename, old.path, old.remote, old.url, old.provider);
 		END;`,
+		// Only renames touch the FTS table; indexing stamps every unchanged row
 		`DROP TRIGGER IF EXISTS indexed_files_au;`,
-		`CREATE TRIGGER indexed_files_au AFTER UPDATE ON indexed_files BEGIN
-			INSERT INTO files_search(files_search, rowid, filename, path, remote) VALUES('delete', old.rowid, old.filename, old.path, old.remote);
-			INSERT INTO files_search(rowid, filename, path, remote) VALUES (new.rowid, new.filename, new.path, new.remote);
+		`CREATE TRIGGER indexed_files_au AFTER UPDATE OF filename, path, remote, url, provider ON indexed_files BEGIN
+			INSERT INTO files_search(files_search, rowid, filename, path, remote, url, provider) VALUES('delete', old.rowid, old.filename, old.path, old.remote, old.url, old.provider);
+			INSERT INTO files_search(rowid, filename, path, remote, url, provider) VALUES (new.rowid, new.filename, new.path, new.remote, new.url, new.provider);
+		END;`,
+	}
+
+	// Tables from before downloads were indexed lack the url and provider
+	// columns; they are rebuilt with them
+	var schema string
+	s.db.Raw("SELECT sql FROM sqlite_master WHERE type='table' AND name='files_search'").Scan(&schema)
+	if schema != "" && !strings.Contains(schema, "provider") {
+		stmts = append([]string{`DROP TABLE files_search;`}, stmts...)
+		stmts = append(stmts, `INSERT INTO files_search(files_search) VALUES('rebuild');`)
+	}
+
+	for _, stmt := range stmts {
+		if err := s.db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return s.setupSQLiteTrigram()
+}
+
+// setupSQLiteTrigram adds the trigram index of file names used by fuzzy
+// search. It is filled from indexed_files when first created.
+func (s *Store) setupSQLiteTrigram() error {
+	var existing string
+	s.db.Raw("SELECT name FROM sqlite_master WHERE type='table' AND name='files_trigram'").Scan(&existing)
+
+	stmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS files_trigram USING fts5(filename, content='indexed_files', content_rowid='rowid', tokenize='trigram');`,
+		`DROP TRIGGER IF EXISTS indexed_files_trigram_ai;`,
+		`CREATE TRIGGER indexed_files_trigram_ai AFTER INSERT ON indexed_files BEGIN
+			INSERT INTO files_trigram(rowid, filename) VALUES (new.rowid, new.filename);
 		END;`,
+		`DROP TRIGGER IF EXISTS indexed_files_trigram_ad;`,
+		`CREATE TRIGGER indexed_files_trigram_ad AFTER DELETE ON indexed_files BEGIN
+			INSERT INTO files_trigram(files_trigram, rowid, filename) VALUES('delete', old.rowid, old.filename);
+		END;`,
+		`DROP TRIGGER IF EXISTS indexed_files_trigram_au;`,
+		`CREATE TRIGGER indexed_files_trigram_au AFTER UPDATE OF filename ON indexed_files BEGIN
+			INSERT INTO files_trigram(files_trigram, rowid, filename) VALUES('delete', old.rowid, old.filename);
+			INSERT INTO files_trigram(rowid, filename) VALUES (new.rowid, new.filename);
+		END;`,
+	}
+	if existing == "" {
+		stmts = append(stmts, `INSERT INTO files_trigram(files_trigram) VALUES('rebuild');`)
 	}
 	for _, stmt := range stmts {
 		if err := s.db.Exec(stmt).Error; err != nil {
@@ -79,12 +79,6 @@ func (r *DownloadRepo) GetByEngineID(ctx context.Context, engineID string) (*mod
 	return &d, err
 }
 
-func (r *DownloadRepo) GetByUploadJobID(ctx context.Context, jobID string) (*model.Download, error) {
-	var d model.Download
-	err := r.db.WithContext(ctx).First(&d, "upload_job_id = ?", jobID).Error
-	return &d, err
-}
-
 func (r *DownloadRepo) List(ctx context.Context, status []string, limit, offset int, sortAsc bool) ([]*model.Download, int, error) {
 	var downloads []*model.Download
 	var total int64
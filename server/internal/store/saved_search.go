@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gravity/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SavedSearchRepo struct {
+	db *gorm.DB
+}
+
+func NewSavedSearchRepo(db *gorm.DB) *SavedSearchRepo {
+	return &SavedSearchRepo{db: db}
+}
+
+func (r *SavedSearchRepo) Create(ctx context.Context, s *model.SavedSearch) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *SavedSearchRepo) Update(ctx context.Context, s *model.SavedSearch) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+func (r *SavedSearchRepo) Get(ctx context.Context, id string) (*model.SavedSearch, error) {
+	var s model.SavedSearch
+	if err := r.db.WithContext(ctx).First(&s, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SavedSearchRepo) GetByName(ctx context.Context, name string) (*model.SavedSearch, error) {
+	var s model.SavedSearch
+	if err := r.db.WithContext(ctx).First(&s, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SavedSearchRepo) List(ctx context.Context) ([]*model.SavedSearch, error) {
+	var searches []*model.SavedSearch
+	err := r.db.WithContext(ctx).Order("name asc").Find(&searches).Error
+	return searches, err
+}
+
+func (r *SavedSearchRepo) ListEnabled(ctx context.Context) ([]*model.SavedSearch, error) {
+	var searches []*model.SavedSearch
+	err := r.db.WithContext(ctx).Where("enabled = ?", true).Order("name asc").Find(&searches).Error
+	return searches, err
+}
+
+// RecordMatch adds count new matches found at t to search id
+func (r *SavedSearchRepo) RecordMatch(ctx context.Context, id string, count int, t time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.SavedSearch{}).Where("id = ?", id).Updates(map[string]any{
+		"match_count":   gorm.Expr("match_count + ?", count),
+		"last_match_at": t,
+	}).Error
+}
+
+func (r *SavedSearchRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&model.SavedSearch{}, "id = ?", id).Error
+}
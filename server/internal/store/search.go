@@ -2,12 +2,14 @@ package store
 
 import (
 	"context"
+	"math"
 	"strings"
 	"time"
 
 	"gravity/internal/logger"
 	"gravity/internal/model"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -21,91 +23,522 @@ func NewSearchRepo(db *gorm.DB) *SearchRepo {
 	return &SearchRepo{db: db}
 }
 
-func (r *SearchRepo) SaveFiles(ctx context.Context, remote string, files []model.IndexedFile) error {
+// indexBatchSize bounds the paths looked up in one query
+const indexBatchSize = 500
+
+// The share of a fuzzy query's trigrams a name must hold to match
+const minTrigramShare = 0.5
+
+// ApplyFiles diffs files, one listing batch of remote, against the indexed
+// rows by path, size and modtime. New rows are inserted, changed ones
+// updated and all of them stamped with seenAt, which new rows also keep as
+// their first index time. Each batch is its own
+// transaction so search keeps working during a run.
+func (r *SearchRepo) ApplyFiles(ctx context.Context, remote string, files []model.IndexedFile, seenAt time.Time) (added, updated int, err error) {
+	for start := 0; start < len(files); start += indexBatchSize {
+		end := min(start+indexBatchSize, len(files))
+		a, u, err := r.applyBatch(ctx, remote, files[start:end], seenAt)
+		added += a
+		updated += u
+		if err != nil {
+			return added, updated, err
+		}
+	}
+	return added, updated, nil
+}
+
+func (r *SearchRepo) applyBatch(ctx context.Context, remote string, files []model.IndexedFile, seenAt time.Time) (int, int, error) {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+
+	var existing []model.IndexedFile
+	if err := r.db.WithContext(ctx).Table("indexed_files").
+		Where("remote = ? AND path IN ?", remote, paths).
+		Find(&existing).Error; err != nil {
+		return 0, 0, err
+	}
+	byPath := make(map[string]*model.IndexedFile, len(existing))
+	for i := range existing {
+		byPath[existing[i].Path] = &existing[i]
+	}
+
+	var created []model.IndexedFile
+	var changed []*model.IndexedFile
+	var seen []string
+	for i := range files {
+		f := &files[i]
+		f.Remote = remote
+		f.LastIndexedAt = seenAt
+		old, ok := byPath[f.Path]
+		switch {
+		case !ok:
+			f.ID = uuid.New().String()
+			f.FirstIndexedAt = seenAt
+			created = append(created, *f)
+			// A listing may repeat a path; index it once
+			byPath[f.Path] = f
+		case f.Unchanged(old):
+			seen = append(seen, old.ID)
+		default:
+			f.ID = old.ID
+			changed = append(changed, f)
+		}
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(created) > 0 {
+			if err := tx.Table("indexed_files").Create(&created).Error; err != nil {
+				return err
+			}
+		}
+		for _, f := range changed {
+			if err := tx.Table("indexed_files").Where("id = ?", f.ID).Updates(map[string]any{
+				"source":          f.Source,
+				"filename":        f.Name,
+				"ext":             f.Ext,
+				"size":            f.Size,
+				"mod_time":        f.ModTime,
+				"is_dir":          f.IsDir,
+				"last_indexed_at": seenAt,
+				"hash":            "",
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if len(seen) > 0 {
+			return tx.Table("indexed_files").Where("id IN ?", seen).
+				Update("last_indexed_at", seenAt).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(created), len(changed), nil
+}
+
+// DeleteStale removes the rows of remote that the run started at runStart
+// did not see, and returns how many were removed
+func (r *SearchRepo) DeleteStale(ctx context.Context, remote string, runStart time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Table("indexed_files").
+		Where("remote = ? AND (last_indexed_at < ? OR last_indexed_at IS NULL)", remote, runStart).
+		Delete(nil)
+	return res.RowsAffected, res.Error
+}
+
+// DeleteTree removes the row of virtualPath on remote and the rows below it
+func (r *SearchRepo) DeleteTree(ctx context.Context, remote, virtualPath string) error {
+	return r.db.WithContext(ctx).Table("indexed_files").
+		Where(`remote = ? AND (path = ? OR path LIKE ? ESCAPE '\')`, remote, virtualPath, escapeLike(virtualPath)+"/%").
+		Delete(nil).Error
+}
+
+// SaveDownload replaces the rows of download id with files
+func (r *SearchRepo) SaveDownload(ctx context.Context, id string, files []model.IndexedFile) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Table("indexed_files").Where("remote = ?", remote).Delete(nil).Error; err != nil {
+		if err := tx.Table("indexed_files").Where("source = ? AND download_id = ?", model.IndexSourceDownload, id).Delete(nil).Error; err != nil {
 			return err
 		}
-
 		if len(files) == 0 {
 			return nil
 		}
-
-		// Batch insert
 		return tx.Table("indexed_files").Create(&files).Error
 	})
 }
 
-func (r *SearchRepo) Search(ctx context.Context, query string, limit, offset int) ([]model.IndexedFile, int, error) {
-	dbType := r.db.Dialector.Name()
-	var results []model.IndexedFile
+func (r *SearchRepo) DeleteDownload(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Table("indexed_files").
+		Where("source = ? AND download_id = ?", model.IndexSourceDownload, id).
+		Delete(nil).Error
+}
+
+// PruneDownloads removes the rows of downloads that no longer exist
+func (r *SearchRepo) PruneDownloads(ctx context.Context) error {
+	return r.db.WithContext(ctx).Table("indexed_files").
+		Where("source = ? AND download_id NOT IN (SELECT id FROM downloads)", model.IndexSourceDownload).
+		Delete(nil).Error
+}
+
+// DownloadIndexTimes returns the update time of each indexed download, which
+// all rows of a download are stamped with
+func (r *SearchRepo) DownloadIndexTimes(ctx context.Context) (map[string]time.Time, error) {
+	var rows []struct {
+		DownloadID    string
+		LastIndexedAt time.Time
+	}
+	if err := r.db.WithContext(ctx).Table("indexed_files").
+		Distinct("download_id", "last_indexed_at").
+		Where("source = ?", model.IndexSourceDownload).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	times := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		times[row.DownloadID] = row.LastIndexedAt
+	}
+	return times, nil
+}
+
+func (r *SearchRepo) GetRun(ctx context.Context, remote string) (*model.IndexRun, error) {
+	var run model.IndexRun
+	if err := r.db.WithContext(ctx).First(&run, "remote = ?", remote).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns returns the runs that were interrupted before they finished
+func (r *SearchRepo) ListRuns(ctx context.Context) ([]*model.IndexRun, error) {
+	var runs []*model.IndexRun
+	err := r.db.WithContext(ctx).Find(&runs).Error
+	return runs, err
+}
+
+func (r *SearchRepo) SaveRun(ctx context.Context, run *model.IndexRun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+func (r *SearchRepo) DeleteRun(ctx context.Context, remote string) error {
+	return r.db.WithContext(ctx).Delete(&model.IndexRun{}, "remote = ?", remote).Error
+}
+
+// facetLimit caps the extensions counted in facets
+const facetLimit = 20
+
+// pgSearchTokens is the tsvector of a row on postgres. It is the expression
+// of idx_indexed_files_search, so queries must use it as is.
+const pgSearchTokens = `to_tsvector('simple', regexp_replace(lower(filename || ' ' || path || ' ' || coalesce(url, '') || ' ' || coalesce(provider, '')), '[^[:alnum:]]+', ' ', 'g'))`
+
+// textMatch restricts a query to the rows matching the text of a search
+type textMatch struct {
+	apply func(*gorm.DB) *gorm.DB
+	rank  any // Order of the best matches first
+}
+
+// Search returns a page of the files matching q and, with facets, how many
+// match on each remote and with each extension. Text matches whole tokens,
+// the last one as a prefix; with q.Fuzzy, or when that finds nothing, it
+// matches on trigrams instead.
+func (r *SearchRepo) Search(ctx context.Context, q *model.SearchQuery, limit, offset int, facets bool) (*model.SearchResult, error) {
+	var exact *model.SearchResult
+	if !q.Fuzzy {
+		res, err := r.search(ctx, q, false, limit, offset, facets)
+		if err != nil || res.Total > 0 || len(q.Terms) == 0 {
+			return res, err
+		}
+		exact = res
+	}
+
+	res, err := r.search(ctx, q, true, limit, offset, facets)
+	if err != nil && exact != nil {
+		logger.L.Warn("fuzzy search failed", zap.Error(err))
+		return exact, nil
+	}
+	if res != nil {
+		res.Fuzzy = true
+	}
+	return res, err
+}
+
+func (r *SearchRepo) search(ctx context.Context, q *model.SearchQuery, fuzzy bool, limit, offset int, facets bool) (*model.SearchResult, error) {
+	res := &model.SearchResult{Files: []model.IndexedFile{}}
+	if facets {
+		res.Facets = &model.SearchFacets{Sources: []model.FacetCount{}, Remotes: []model.FacetCount{}, Exts: []model.FacetCount{}}
+	}
+
+	match, ok := r.textMatch(q, fuzzy)
+	if !ok {
+		return res, nil
+	}
+	query := func() *gorm.DB {
+		return match.apply(r.filterSearch(r.db.WithContext(ctx).Table("indexed_files"), q))
+	}
+
 	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, err
+	}
+	res.Total = int(total)
+	if total == 0 {
+		return res, nil
+	}
 
-	if dbType == "sqlite" {
-		// Check if FTS table exists
-		var tableName string
-		r.db.Raw("SELECT name FROM sqlite_master WHERE type='table' AND name='files_search'").Scan(&tableName)
-
-		if tableName == "files_search" {
-			ftsQuery := strings.ReplaceAll(query, "\"", "") + "*"
-
-			countErr := r.db.WithContext(ctx).Table("indexed_files").
-				Joins("JOIN files_search ON files_search.rowid = indexed_files.rowid").
-				Where("files_search MATCH ?", ftsQuery).
-				Count(&total).Error
-			if countErr == nil {
-				err := r.db.WithContext(ctx).Table("indexed_files").
-					Select("indexed_files.* ").
-					Joins("JOIN files_search ON files_search.rowid = indexed_files.rowid").
-					Where("files_search MATCH ?", ftsQuery).
-					Order("rank").
-					Limit(limit).Offset(offset).
-					Find(&results).Error
-
-				return results, int(total), err
+	find := query().Select("indexed_files.*")
+	if q.Sort == model.SearchSortRelevance || q.Sort == "" {
+		if match.rank != nil {
+			find = find.Order(match.rank)
+		}
+	} else {
+		column := map[string]string{
+			model.SearchSortName:     "indexed_files.filename",
+			model.SearchSortSize:     "indexed_files.size",
+			model.SearchSortModified: "indexed_files.mod_time",
+			model.SearchSortPath:     "indexed_files.path",
+		}[q.Sort]
+		find = find.Order(clause.OrderByColumn{Column: clause.Column{Name: column, Raw: true}, Desc: q.Desc})
+	}
+	if err := find.Order("indexed_files.path").Limit(limit).Offset(offset).Find(&res.Files).Error; err != nil {
+		return nil, err
+	}
+
+	if facets {
+		if err := query().Select("indexed_files.source AS value, COUNT(*) AS count").
+			Group("indexed_files.source").Order("count DESC").
+			Scan(&res.Facets.Sources).Error; err != nil {
+			return nil, err
+		}
+		if err := query().Where("indexed_files.remote <> ''").
+			Select("indexed_files.remote AS value, COUNT(*) AS count").
+			Group("indexed_files.remote").Order("count DESC").
+			Scan(&res.Facets.Remotes).Error; err != nil {
+			return nil, err
+		}
+		if err := query().Where("indexed_files.ext <> ''").
+			Select("indexed_files.ext AS value, COUNT(*) AS count").
+			Group("indexed_files.ext").Order("count DESC").Limit(facetLimit).
+			Scan(&res.Facets.Exts).Error; err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// NewMatches returns up to limit files of remote first indexed at or after
+// since that match q, and how many match in all. Matching is fuzzy only if
+// q asks for it.
+func (r *SearchRepo) NewMatches(ctx context.Context, q *model.SearchQuery, remote string, since time.Time, limit int) ([]model.IndexedFile, int, error) {
+	match, ok := r.textMatch(q, q.Fuzzy)
+	if !ok {
+		return nil, 0, nil
+	}
+	query := func() *gorm.DB {
+		return match.apply(r.filterSearch(r.db.WithContext(ctx).Table("indexed_files"), q)).
+			Where("indexed_files.remote = ? AND indexed_files.first_indexed_at >= ?", remote, since)
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil || total == 0 {
+		return nil, 0, err
+	}
+	var files []model.IndexedFile
+	err := query().Select("indexed_files.*").Order("indexed_files.path").Limit(limit).Find(&files).Error
+	return files, int(total), err
+}
+
+// textMatch returns how the terms of q are matched on this database. It
+// reports false when nothing can match, such as a fuzzy search with only
+// words too short for a trigram.
+func (r *SearchRepo) textMatch(q *model.SearchQuery, fuzzy bool) (textMatch, bool) {
+	none := textMatch{apply: func(db *gorm.DB) *gorm.DB { return db }}
+	if len(q.Terms) == 0 {
+		return none, true
+	}
+
+	switch r.db.Dialector.Name() {
+	case "sqlite":
+		table, expr := "files_search", ftsQuery(q.Terms)
+		var trigrams []string
+		if fuzzy {
+			trigrams = queryTrigrams(q.Terms)
+			if len(trigrams) == 0 {
+				return none, false
 			}
-			logger.L.Warn("SQLite FTS query failed, falling back to LIKE", zap.Error(countErr))
+			table, expr = "files_trigram", `"`+strings.Join(trigrams, `" OR "`)+`"`
 		}
+		if !r.hasTable(table) {
+			break
+		}
+		return textMatch{
+			apply: func(db *gorm.DB) *gorm.DB {
+				db = db.Joins("JOIN "+table+" ON "+table+".rowid = indexed_files.rowid").
+					Where(table+" MATCH ?", expr)
+				if len(trigrams) > 0 {
+					db = sharesTrigrams(db, trigrams)
+				}
+				return db
+			},
+			rank: table + ".rank",
+		}, true
+
+	case "postgres":
+		if fuzzy {
+			text := strings.Join(searchTokens(q.Terms), " ")
+			return textMatch{
+				apply: func(db *gorm.DB) *gorm.DB { return db.Where("? <% filename", text) },
+				rank:  gorm.Expr("word_similarity(?, filename) DESC", text),
+			}, true
+		}
+		expr := tsQuery(q.Terms)
+		return textMatch{
+			apply: func(db *gorm.DB) *gorm.DB { return db.Where(pgSearchTokens+" @@ to_tsquery('simple', ?)", expr) },
+			rank:  gorm.Expr("ts_rank("+pgSearchTokens+", to_tsquery('simple', ?)) DESC", expr),
+		}, true
 	}
 
-	if dbType == "postgres" {
-		// PostgreSQL Full Text Search
-		// websearch_to_tsquery provides a Google-like search syntax (quotes for phrases, - for exclusion)
-		tsQuery := "websearch_to_tsquery('english', ?)"
-		ftsCondition := "to_tsvector('english', filename || ' ' || path) @@ " + tsQuery
+	// Fallback for others using LIKE
+	return textMatch{apply: func(db *gorm.DB) *gorm.DB {
+		for _, token := range searchTokens(q.Terms) {
+			like := "%" + escapeLike(token) + "%"
+			db = db.Where(`(LOWER(indexed_files.filename) LIKE ? ESCAPE '\' OR LOWER(indexed_files.path) LIKE ? ESCAPE '\')`, like, like)
+		}
+		return db
+	}}, true
+}
 
-		countErr := r.db.WithContext(ctx).Table("indexed_files").
-			Where(ftsCondition, query).
-			Count(&total).Error
-		if countErr != nil {
-			return nil, 0, countErr
+// filterSearch applies the filters of q other than its text
+func (r *SearchRepo) filterSearch(db *gorm.DB, q *model.SearchQuery) *gorm.DB {
+	db = r.excludeWords(db, q.Exclude)
+	if len(q.Sources) > 0 {
+		db = db.Where("indexed_files.source IN ?", q.Sources)
+	}
+	if len(q.Remotes) > 0 {
+		db = db.Where("indexed_files.remote IN ?", q.Remotes)
+	}
+	if len(q.Exts) > 0 {
+		db = db.Where("indexed_files.ext IN ?", q.Exts)
+	}
+	if q.MinSize > 0 {
+		db = db.Where("indexed_files.size >= ?", q.MinSize)
+	}
+	if q.MaxSize != nil {
+		db = db.Where("indexed_files.size <= ?", *q.MaxSize)
+	}
+	if q.After != nil {
+		db = db.Where("indexed_files.mod_time >= ?", *q.After)
+	}
+	if q.Before != nil {
+		db = db.Where("indexed_files.mod_time < ?", *q.Before)
+	}
+	if q.PathPrefix != "" && q.PathPrefix != "/" {
+		db = db.Where(`(indexed_files.path = ? OR indexed_files.path LIKE ? ESCAPE '\')`,
+			q.PathPrefix, escapeLike(q.PathPrefix)+"/%")
+	}
+	if q.IsDir != nil {
+		db = db.Where("indexed_files.is_dir = ?", *q.IsDir)
+	}
+	return db
+}
+
+// excludeWords drops the rows any of words matches. Like search terms, a
+// word matches whole tokens of the name or path, so -cam keeps "camera".
+func (r *SearchRepo) excludeWords(db *gorm.DB, words []string) *gorm.DB {
+	var phrases [][]string
+	for _, word := range words {
+		if tokens := model.SearchTokens(word); len(tokens) > 0 {
+			phrases = append(phrases, tokens)
 		}
+	}
+	if len(phrases) == 0 {
+		return db
+	}
 
-		err := r.db.WithContext(ctx).Table("indexed_files").
-			Where(ftsCondition, query).
-			Order(gorm.Expr("ts_rank(to_tsvector('english', filename || ' ' || path), "+tsQuery+") DESC", query)).
-			Limit(limit).Offset(offset).
-			Find(&results).Error
+	switch r.db.Dialector.Name() {
+	case "sqlite":
+		if !r.hasTable("files_search") {
+			break
+		}
+		parts := make([]string, len(phrases))
+		for i, tokens := range phrases {
+			parts[i] = `"` + strings.Join(tokens, " ") + `"`
+		}
+		return db.Where("indexed_files.rowid NOT IN (SELECT rowid FROM files_search WHERE files_search MATCH ?)",
+			strings.Join(parts, " OR "))
 
-		return results, int(total), err
+	case "postgres":
+		parts := make([]string, len(phrases))
+		for i, tokens := range phrases {
+			parts[i] = "(" + strings.Join(tokens, " <-> ") + ")"
+		}
+		return db.Where("NOT ("+pgSearchTokens+" @@ to_tsquery('simple', ?))", strings.Join(parts, " | "))
 	}
 
-	// Fallback for others using LIKE
-	likeQuery := "%" + query + "%"
+	// Fallback for others using LIKE, which also matches inside tokens
+	for _, tokens := range phrases {
+		var conds []string
+		var args []any
+		for _, token := range tokens {
+			like := "%" + escapeLike(token) + "%"
+			conds = append(conds, `(LOWER(indexed_files.filename) LIKE ? ESCAPE '\' OR LOWER(indexed_files.path) LIKE ? ESCAPE '\')`)
+			args = append(args, like, like)
+		}
+		db = db.Where("NOT ("+strings.Join(conds, " AND ")+")", args...)
+	}
+	return db
+}
 
-	countErr := r.db.WithContext(ctx).Table("indexed_files").
-		Where("filename LIKE ? OR path LIKE ?", likeQuery, likeQuery).
-		Count(&total).Error
-	if countErr != nil {
-		return nil, 0, countErr
+func (r *SearchRepo) hasTable(name string) bool {
+	var tableName string
+	r.db.Raw("SELECT name FROM sqlite_master WHERE type='table' AND name=?", name).Scan(&tableName)
+	return tableName == name
+}
+
+// ftsQuery builds an FTS5 query needing every term, each as a phrase of its
+// tokens with the last one a prefix
+func ftsQuery(terms []string) string {
+	var parts []string
+	for _, term := range terms {
+		if tokens := model.SearchTokens(term); len(tokens) > 0 {
+			parts = append(parts, `"`+strings.Join(tokens, " ")+`"*`)
+		}
 	}
+	return strings.Join(parts, " AND ")
+}
 
-	err := r.db.WithContext(ctx).Table("indexed_files").
-		Where("filename LIKE ? OR path LIKE ?", likeQuery, likeQuery).
-		Limit(limit).Offset(offset).
-		Find(&results).Error
+// queryTrigrams returns the distinct trigrams of the tokens of terms
+func queryTrigrams(terms []string) []string {
+	seen := make(map[string]bool)
+	var trigrams []string
+	for _, token := range searchTokens(terms) {
+		runes := []rune(token)
+		for i := 0; i+3 <= len(runes); i++ {
+			tri := string(runes[i : i+3])
+			if !seen[tri] {
+				seen[tri] = true
+				trigrams = append(trigrams, tri)
+			}
+		}
+	}
+	return trigrams
+}
+
+// sharesTrigrams keeps the rows whose name holds at least minTrigramShare of
+// trigrams. The trigram index finds rows sharing any of them, so one common
+// sequence such as "ing" would otherwise match.
+func sharesTrigrams(db *gorm.DB, trigrams []string) *gorm.DB {
+	parts := make([]string, len(trigrams))
+	args := make([]any, 0, len(trigrams)+1)
+	for i, tri := range trigrams {
+		parts[i] = "(instr(LOWER(indexed_files.filename), ?) > 0)"
+		args = append(args, tri)
+	}
+	need := int(math.Ceil(float64(len(trigrams)) * minTrigramShare))
+	return db.Where(strings.Join(parts, " + ")+" >= ?", append(args, need)...)
+}
+
+// tsQuery builds a postgres tsquery needing every term, each as a phrase of
+// its tokens with the last one a prefix
+func tsQuery(terms []string) string {
+	var parts []string
+	for _, term := range terms {
+		if tokens := model.SearchTokens(term); len(tokens) > 0 {
+			parts = append(parts, "("+strings.Join(tokens, " <-> ")+":*)")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+func searchTokens(terms []string) []string {
+	var tokens []string
+	for _, term := range terms {
+		tokens = append(tokens, model.SearchTokens(term)...)
+	}
+	return tokens
+}
 
-	return results, int(total), err
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 func (r *SearchRepo) GetConfigs(ctx context.Context) ([]model.RemoteIndexConfig, error) {
@@ -135,3 +568,64 @@ func (r *SearchRepo) UpdateLastIndexed(ctx context.Context, remote string) error
 		"error_msg":       "",
 	}).Error
 }
+
+// DuplicateCandidate is a name and size shared by more than one file
+type DuplicateCandidate struct {
+	Name  string
+	Size  int64
+	Count int
+}
+
+// duplicateRows are the rows the duplicate finder looks at: files that
+// exist on a remote or local disk, not download history
+func (r *SearchRepo) duplicateRows(ctx context.Context, minSize int64) *gorm.DB {
+	return r.db.WithContext(ctx).Table("indexed_files").
+		Where("is_dir = ? AND source <> ? AND size >= ?", false, model.IndexSourceDownload, minSize)
+}
+
+// DuplicateCandidates returns the names and sizes shared by several files of
+// at least minSize bytes, the most space wasted first
+func (r *SearchRepo) DuplicateCandidates(ctx context.Context, minSize int64, limit, offset int) ([]DuplicateCandidate, int, error) {
+	groups := r.duplicateRows(ctx, minSize).
+		Select("filename AS name, size, COUNT(*) AS count").
+		Group("filename, size").
+		Having("COUNT(*) > 1")
+
+	var total int64
+	if err := r.db.WithContext(ctx).Table("(?) AS g", groups).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var candidates []DuplicateCandidate
+	err := groups.Order("size * (COUNT(*) - 1) DESC").Order("filename").
+		Limit(limit).Offset(offset).
+		Scan(&candidates).Error
+	return candidates, int(total), err
+}
+
+// DuplicateFiles returns the files named name of size bytes
+func (r *SearchRepo) DuplicateFiles(ctx context.Context, name string, size int64) ([]model.IndexedFile, error) {
+	var files []model.IndexedFile
+	err := r.duplicateRows(ctx, size).
+		Where("filename = ? AND size = ?", name, size).
+		Order("path").
+		Find(&files).Error
+	return files, err
+}
+
+// DuplicateFile returns the file at path on remote the duplicate finder
+// looks at
+func (r *SearchRepo) DuplicateFile(ctx context.Context, remote, path string) (*model.IndexedFile, error) {
+	var f model.IndexedFile
+	err := r.duplicateRows(ctx, 0).
+		Where("remote = ? AND path = ?", remote, path).
+		First(&f).Error
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *SearchRepo) SetHash(ctx context.Context, id, hash string) error {
+	return r.db.WithContext(ctx).Table("indexed_files").Where("id = ?", id).Update("hash", hash).Error
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gravity/internal/config"
+)
+
+const secretKeyFile = "secret.key"
+
+// SecretBox encrypts credentials before they are written to the database
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox uses cfg.SecretKey when set, else a random key kept in
+// DataDir/secret.key that is created on first use
+func NewSecretBox(cfg *config.Config) (*SecretBox, error) {
+	var key []byte
+	if cfg.SecretKey != "" {
+		sum := sha256.Sum256([]byte(cfg.SecretKey))
+		key = sum[:]
+	} else {
+		var err error
+		if key, err = loadOrCreateKey(filepath.Join(cfg.DataDir, secretKeyFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+func loadOrCreateKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("secret key %s: expected 32 bytes, got %d", path, len(key))
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write secret key: %w", err)
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext into a base64 string of nonce and ciphertext
+func (b *SecretBox) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Open reverses Seal
+func (b *SecretBox) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	n := b.aead.NonceSize()
+	if len(data) < n {
+		return nil, errors.New("sealed value too short")
+	}
+	plaintext, err := b.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt secret: wrong key or corrupted value")
+	}
+	return plaintext, nil
+}
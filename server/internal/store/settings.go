@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"gravity/internal/model"
@@ -35,4 +36,15 @@ func (r *SettingsRepo) Save(ctx context.Context, s *model.Settings) error {
 
 func (r *SettingsRepo) DeleteAll(ctx context.Context) error {
 	return r.db.WithContext(ctx).Delete(&model.Settings{}, 1).Error
-}
\ No newline at end of file
+}
+
+// setTrashDefaults turns the trash on for settings saved before it existed
+func (r *SettingsRepo) setTrashDefaults() error {
+	data, err := json.Marshal(model.DefaultSettings().Trash)
+	if err != nil {
+		return err
+	}
+	return r.db.Model(&model.Settings{}).
+		Where("id = ?", 1).
+		Update("trash", string(data)).Error
+}
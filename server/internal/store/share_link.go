@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gravity/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ShareLinkRepo struct {
+	db *gorm.DB
+}
+
+func NewShareLinkRepo(db *gorm.DB) *ShareLinkRepo {
+	return &ShareLinkRepo{db: db}
+}
+
+func (r *ShareLinkRepo) Create(ctx context.Context, l *model.ShareLink) error {
+	return r.db.WithContext(ctx).Create(l).Error
+}
+
+func (r *ShareLinkRepo) Get(ctx context.Context, id string) (*model.ShareLink, error) {
+	var l model.ShareLink
+	if err := r.db.WithContext(ctx).First(&l, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *ShareLinkRepo) GetByToken(ctx context.Context, token string) (*model.ShareLink, error) {
+	var l model.ShareLink
+	if err := r.db.WithContext(ctx).First(&l, "token = ?", token).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *ShareLinkRepo) List(ctx context.Context) ([]*model.ShareLink, error) {
+	var links []*model.ShareLink
+	err := r.db.WithContext(ctx).Order("created_at desc").Find(&links).Error
+	return links, err
+}
+
+func (r *ShareLinkRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&model.ShareLink{}, "id = ?", id).Error
+}
+
+// CountDownload adds a download to link id unless its limit is reached, in
+// one statement so concurrent downloads cannot go past the limit. It reports
+// whether the download was counted.
+func (r *ShareLinkRepo) CountDownload(ctx context.Context, id string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ShareLink{}).
+		Where("id = ? AND (max_downloads = 0 OR downloads < max_downloads)", id).
+		Updates(map[string]any{"downloads": gorm.Expr("downloads + 1"), "last_used_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteExpired removes links that expired before t
+func (r *ShareLinkRepo) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", t).Delete(&model.ShareLink{})
+	return result.RowsAffected, result.Error
+}
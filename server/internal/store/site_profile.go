@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gravity/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SiteProfileRepo stores site profiles with their secrets sealed by a
+// SecretBox
+type SiteProfileRepo struct {
+	db  *gorm.DB
+	box *SecretBox
+}
+
+func NewSiteProfileRepo(db *gorm.DB, box *SecretBox) *SiteProfileRepo {
+	return &SiteProfileRepo{db: db, box: box}
+}
+
+func (r *SiteProfileRepo) Save(ctx context.Context, p *model.SiteProfile) error {
+	raw, err := json.Marshal(p.Secrets())
+	if err != nil {
+		return err
+	}
+	if p.SealedSecrets, err = r.box.Seal(raw); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		UpdateAll: true,
+	}).Create(p).Error
+}
+
+func (r *SiteProfileRepo) Get(ctx context.Context, id string) (*model.SiteProfile, error) {
+	var p model.SiteProfile
+	if err := r.db.WithContext(ctx).First(&p, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if err := r.open(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *SiteProfileRepo) List(ctx context.Context) ([]*model.SiteProfile, error) {
+	var profiles []*model.SiteProfile
+	if err := r.db.WithContext(ctx).Order("created_at asc").Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if err := r.open(p); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+func (r *SiteProfileRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&model.SiteProfile{}, "id = ?", id).Error
+}
+
+func (r *SiteProfileRepo) open(p *model.SiteProfile) error {
+	if p.SealedSecrets == "" {
+		return nil
+	}
+	raw, err := r.box.Open(p.SealedSecrets)
+	if err != nil {
+		return fmt.Errorf("site profile %s: %w", p.ID, err)
+	}
+	var s model.ProfileSecrets
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("site profile %s: %w", p.ID, err)
+	}
+	p.SetSecrets(s)
+	return nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gravity/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// SyncJobRepo stores sync jobs and their run history
+type SyncJobRepo struct {
+	db *gorm.DB
+}
+
+func NewSyncJobRepo(db *gorm.DB) *SyncJobRepo {
+	return &SyncJobRepo{db: db}
+}
+
+func (r *SyncJobRepo) Create(ctx context.Context, j *model.SyncJob) error {
+	return r.db.WithContext(ctx).Create(j).Error
+}
+
+func (r *SyncJobRepo) Update(ctx context.Context, j *model.SyncJob) error {
+	return r.db.WithContext(ctx).Save(j).Error
+}
+
+func (r *SyncJobRepo) Get(ctx context.Context, id string) (*model.SyncJob, error) {
+	var j model.SyncJob
+	if err := r.db.WithContext(ctx).First(&j, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *SyncJobRepo) GetByName(ctx context.Context, name string) (*model.SyncJob, error) {
+	var j model.SyncJob
+	if err := r.db.WithContext(ctx).First(&j, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *SyncJobRepo) List(ctx context.Context) ([]*model.SyncJob, error) {
+	var jobs []*model.SyncJob
+	err := r.db.WithContext(ctx).Order("name asc").Find(&jobs).Error
+	return jobs, err
+}
+
+// Due returns enabled jobs whose next run is at or before now
+func (r *SyncJobRepo) Due(ctx context.Context, now time.Time) ([]*model.SyncJob, error) {
+	var jobs []*model.SyncJob
+	err := r.db.WithContext(ctx).
+		Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at asc").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Delete removes a job and its run history
+func (r *SyncJobRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.SyncRun{}, "job_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.SyncJob{}, "id = ?", id).Error
+	})
+}
+
+func (r *SyncJobRepo) CreateRun(ctx context.Context, run *model.SyncRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *SyncJobRepo) UpdateRun(ctx context.Context, run *model.SyncRun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+// ListRuns returns the runs of job jobID, newest first
+func (r *SyncJobRepo) ListRuns(ctx context.Context, jobID string, limit, offset int) ([]*model.SyncRun, int, error) {
+	var runs []*model.SyncRun
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.SyncRun{}).Where("job_id = ?", jobID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("started_at desc").Limit(limit).Offset(offset).Find(&runs).Error
+	return runs, int(total), err
+}
+
+// AbortRunning marks runs left running by a previous process as failed, and
+// the jobs they belong to with them
+func (r *SyncJobRepo) AbortRunning(ctx context.Context) (int64, error) {
+	now := time.Now()
+	var affected int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.SyncRun{}).
+			Where("status = ?", model.SyncRunRunning).
+			Updates(map[string]any{"status": model.SyncRunError, "error": "interrupted by restart", "completed_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		return tx.Model(&model.SyncJob{}).
+			Where("last_status = ?", model.SyncRunRunning).
+			Update("last_status", model.SyncRunError).Error
+	})
+	return affected, err
+}
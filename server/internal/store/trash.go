@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gravity/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type TrashRepo struct {
+	db *gorm.DB
+}
+
+func NewTrashRepo(db *gorm.DB) *TrashRepo {
+	return &TrashRepo{db: db}
+}
+
+func (r *TrashRepo) Create(ctx context.Context, item *model.TrashItem) error {
+	return r.db.WithContext(ctx).Create(item).Error
+}
+
+func (r *TrashRepo) Get(ctx context.Context, id string) (*model.TrashItem, error) {
+	var item model.TrashItem
+	if err := r.db.WithContext(ctx).First(&item, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// List returns trashed items, most recently deleted first
+func (r *TrashRepo) List(ctx context.Context, limit, offset int) ([]*model.TrashItem, int, error) {
+	var items []*model.TrashItem
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.TrashItem{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("trashed_at desc").Limit(limit).Offset(offset).Find(&items).Error
+	return items, int(total), err
+}
+
+// Oldest returns all items, oldest first
+func (r *TrashRepo) Oldest(ctx context.Context) ([]*model.TrashItem, error) {
+	var items []*model.TrashItem
+	err := r.db.WithContext(ctx).Order("trashed_at asc").Find(&items).Error
+	return items, err
+}
+
+// TrashedBefore returns items deleted before t
+func (r *TrashRepo) TrashedBefore(ctx context.Context, t time.Time) ([]*model.TrashItem, error) {
+	var items []*model.TrashItem
+	err := r.db.WithContext(ctx).Where("trashed_at < ?", t).Find(&items).Error
+	return items, err
+}
+
+func (r *TrashRepo) TotalSize(ctx context.Context) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&model.TrashItem{}).Select("COALESCE(SUM(size), 0)").Scan(&total).Error
+	return total, err
+}
+
+func (r *TrashRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&model.TrashItem{}, "id = ?", id).Error
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gravity/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// UploadJobRepo stores the upload queue
+type UploadJobRepo struct {
+	db *gorm.DB
+}
+
+func NewUploadJobRepo(db *gorm.DB) *UploadJobRepo {
+	return &UploadJobRepo{db: db}
+}
+
+func (r *UploadJobRepo) Create(ctx context.Context, j *model.UploadJob) error {
+	return r.db.WithContext(ctx).Create(j).Error
+}
+
+func (r *UploadJobRepo) Update(ctx context.Context, j *model.UploadJob) error {
+	return r.db.WithContext(ctx).Save(j).Error
+}
+
+func (r *UploadJobRepo) Get(ctx context.Context, id string) (*model.UploadJob, error) {
+	var j model.UploadJob
+	if err := r.db.WithContext(ctx).First(&j, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *UploadJobRepo) List(ctx context.Context, status []string, limit, offset int) ([]*model.UploadJob, int, error) {
+	var jobs []*model.UploadJob
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.UploadJob{})
+	if len(status) > 0 {
+		query = query.Where("status IN ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&jobs).Error
+	return jobs, int(total), err
+}
+
+func (r *UploadJobRepo) ListByDownload(ctx context.Context, downloadID string) ([]*model.UploadJob, error) {
+	var jobs []*model.UploadJob
+	err := r.db.WithContext(ctx).Where("download_id = ?", downloadID).Order("created_at asc").Find(&jobs).Error
+	return jobs, err
+}
+
+// NextQueued returns up to limit queued jobs that are due, oldest first
+func (r *UploadJobRepo) NextQueued(ctx context.Context, now time.Time, limit int) ([]*model.UploadJob, error) {
+	var jobs []*model.UploadJob
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND (next_retry_at IS NULL OR next_retry_at <= ?)", model.UploadStatusQueued, now).
+		Order("created_at asc").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// Requeue puts jobs left running or verifying by a previous process back in
+// the queue
+func (r *UploadJobRepo) Requeue(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.UploadJob{}).
+		Where("status IN ?", []model.UploadStatus{model.UploadStatusRunning, model.UploadStatusVerifying}).
+		Updates(map[string]any{"status": model.UploadStatusQueued, "next_retry_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+func (r *UploadJobRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&model.UploadJob{}, "id = ?", id).Error
+}
+
+func (r *UploadJobRepo) DeleteByDownload(ctx context.Context, downloadID string) error {
+	return r.db.WithContext(ctx).Delete(&model.UploadJob{}, "download_id = ?", downloadID).Error
+}